@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -18,6 +22,15 @@ const (
 	iconFilename = "janken_choki.png"
 )
 
+var (
+	errPermission            = errors.New("the user doesn't have the permission")
+	errNotEnoughParticipants = errors.New("not enough participants")
+	errGameFull              = errors.New("the game is full")
+	errNotInvited            = errors.New("the user is not invited")
+	errNotFull               = errors.New("the game is not full")
+	errNotFinished           = errors.New("the game is not finished")
+)
+
 var (
 	handsRegisteredMessage = &i18n.Message{
 		ID:    "HandsRegisteredMessage",
@@ -29,7 +42,7 @@ var (
 	}
 	resultNotEnoughParticipantsErrorMessage = &i18n.Message{
 		ID:    "ResultNotEnoughParticipantsErrorMessage",
-		Other: "Failed to show the result of the janken game. Least 2 pariticipants are required.",
+		Other: "Failed to show the result of the janken game. At least {{.Min}} participants are required.",
 	}
 	resultTableRankLabel = &i18n.Message{
 		ID:    "ResultTableRankLabel",
@@ -43,16 +56,54 @@ var (
 		ID:    "ResultTableHandsLabel",
 		Other: "Hands",
 	}
+	resultTableNoteLabel = &i18n.Message{
+		ID:    "ResultTableNoteLabel",
+		Other: "Note",
+	}
 	resultTableTitle = &i18n.Message{
 		ID: "ResultTableTitle",
 		Other: `**Janken game ({{.ID}})**
 Result
+`,
+	}
+	resultTableCustomTitle = &i18n.Message{
+		ID: "ResultTableCustomTitle",
+		Other: `**{{.Title}} ({{.ID}})**
+Result
 `,
 	}
 	configPermissionErrorMessage = &i18n.Message{
 		ID:    "ConfigPermissionErrorMessage",
 		Other: "Failed to open the configration dialog. The creator of this game or the administrator can configure the game.",
 	}
+	configInvalidTitleErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidTitleErrorMessage",
+		Other: "Enter a title of at most {{.Max}} characters.",
+	}
+	configInvalidPurposeErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidPurposeErrorMessage",
+		Other: "Enter a purpose of at most {{.Max}} characters.",
+	}
+	configInvalidOutcomesErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidOutcomesErrorMessage",
+		Other: `Enter up to {{.Max}} outcomes of at most {{.MaxLength}} characters, one per line like "1: picks lunch place" or "last: buys coffee".`,
+	}
+	configInvalidNumWinnersErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidNumWinnersErrorMessage",
+		Other: "Enter a number of 1 or more.",
+	}
+	configInvalidMaxParticipantsErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidMaxParticipantsErrorMessage",
+		Other: "Enter 0 for no limit, or a number of 2 or more that is not less than the current number of participants ({{.Count}}).",
+	}
+	configInvalidMinParticipantsErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidMinParticipantsErrorMessage",
+		Other: "Enter a number of 2 or more that is not more than the max participants.",
+	}
+	configInvalidDeadlineErrorMessage = &i18n.Message{
+		ID:    "ConfigInvalidDeadlineErrorMessage",
+		Other: "Enter a duration such as 15m, a time such as 17:00 or a future date and time such as 2020-12-24 17:00.",
+	}
 	jankenGameDestroyedMessage = &i18n.Message{
 		ID:    "gameDestroyedMessage",
 		Other: "This janken game was destroyed by @{{.Username}}.",
@@ -61,6 +112,66 @@ Result
 		ID:    "FailedToGetStoredGameErrorMessage",
 		Other: "Failed to get stored game data. Try to create another game.",
 	}
+	joinFullErrorMessage = &i18n.Message{
+		ID:    "JoinFullErrorMessage",
+		Other: "Failed to join the janken game. Up to {{.Max}} users can join this game.",
+	}
+	joinNotInvitedErrorMessage = &i18n.Message{
+		ID:    "JoinNotInvitedErrorMessage",
+		Other: "Failed to join the janken game. Only the invited users can join this game.",
+	}
+	autoResultMessage = &i18n.Message{
+		ID:    "AutoResultMessage",
+		Other: "{{.Max}} users have joined.",
+	}
+	joinedMessage = &i18n.Message{
+		ID:    "JoinedMessage",
+		Other: "You joined janken game ({{.ID}}). Choose your hand each round after the game starts.",
+	}
+	liveAlreadyStartedErrorMessage = &i18n.Message{
+		ID:    "LiveAlreadyStartedErrorMessage",
+		Other: "This janken game has already started.",
+	}
+	liveNotPlayingErrorMessage = &i18n.Message{
+		ID:    "LiveNotPlayingErrorMessage",
+		Other: "This janken game is not being played.",
+	}
+	liveNotYourTurnErrorMessage = &i18n.Message{
+		ID:    "LiveNotYourTurnErrorMessage",
+		Other: "You are not playing in this round. Wait for your turn.",
+	}
+	liveAlreadyPlayedErrorMessage = &i18n.Message{
+		ID:    "LiveAlreadyPlayedErrorMessage",
+		Other: "You have already played a hand in this round.",
+	}
+	liveHandPlayedMessage = &i18n.Message{
+		ID:    "LiveHandPlayedMessage",
+		Other: "You played {{.Hand}}.",
+	}
+	roundEventMessage = &i18n.Message{
+		ID:    "RoundEventMessage",
+		Other: "Round {{.Round}}: {{.Hands}} → {{.Winners}} beat {{.Losers}}",
+	}
+	roundEventDrawMessage = &i18n.Message{
+		ID:    "RoundEventDrawMessage",
+		Other: "Round {{.Round}}: {{.Hands}} → draw",
+	}
+	replayTitle = &i18n.Message{
+		ID:    "ReplayTitle",
+		Other: "Round-by-round replay",
+	}
+	handsCommittedMessage = &i18n.Message{
+		ID:    "HandsCommittedMessage",
+		Other: "@{{.Username}} registered hands. Commitment: `{{.Commitment}}`",
+	}
+	handsCommittedAnonymousMessage = &i18n.Message{
+		ID:    "HandsCommittedAnonymousMessage",
+		Other: "A participant registered hands. Commitment: `{{.Commitment}}`",
+	}
+	revealMessage = &i18n.Message{
+		ID:    "RevealMessage",
+		Other: "The seed for the hands that were not registered is `{{.Seed}}` (commitment `{{.SeedCommitment}}`).\nThe salts and the registered hands of all participants are below. Save them to a file and run `plugin verify <file>` with the plugin executable to recompute the result.",
+	}
 )
 
 func (p *Plugin) initAPI() *mux.Router {
@@ -74,6 +185,9 @@ func (p *Plugin) initAPI() *mux.Router {
 	schedulesRouter.HandleFunc("/result", p.handleResult).Methods(http.MethodPost)
 	schedulesRouter.HandleFunc("/config", p.handleConfig).Methods(http.MethodPost)
 	schedulesRouter.HandleFunc("/config/submit", p.handleConfigSubmit).Methods(http.MethodPost)
+	schedulesRouter.HandleFunc("/live/start", p.handleLiveStart).Methods(http.MethodPost)
+	schedulesRouter.HandleFunc("/live/hand", p.handleLiveHand).Methods(http.MethodPost)
+	schedulesRouter.HandleFunc("/autocomplete/games", p.handleAutocompleteGames).Methods(http.MethodGet)
 
 	return r
 }
@@ -99,6 +213,38 @@ func (p *Plugin) handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ライブ対戦の開始後は参加できない
+	if checkLiveWaiting(game) != nil {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, liveAlreadyStartedErrorMessage, nil)
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+		response := &model.PostActionIntegrationResponse{}
+		writePostActionIntegrationResponse(response, w, r)
+		return
+	}
+
+	// 招待制のゲームには招待されたユーザーしか参加できない
+	if !game.isInvited(userID) {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, joinNotInvitedErrorMessage, nil)
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+		response := &model.PostActionIntegrationResponse{}
+		writePostActionIntegrationResponse(response, w, r)
+		return
+	}
+
+	// 最大参加人数に達した後は参加済みのユーザーしか開けない
+	if !game.canJoin(userID) {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, joinFullErrorMessage, map[string]interface{}{
+			"Max": game.MaxParticipants,
+		})
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+		response := &model.PostActionIntegrationResponse{}
+		writePostActionIntegrationResponse(response, w, r)
+		return
+	}
+
 	d := newJoinDialog(p.API, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, p)
 	d.Open(req.TriggerId, postID, userID, game)
 
@@ -118,21 +264,13 @@ func (p *Plugin) handleJoinSubmit(w http.ResponseWriter, r *http.Request) {
 	userID := req.UserId
 	postID := req.CallbackId
 	post, _ := p.API.GetPost(postID)
-
-	// get stored data
 	gameID := req.State
-	game, err := p.store.jankenStore.Get(gameID)
-	if err != nil {
-		p.API.LogError(err.Error())
-		l := p.getLocalizer(p.configuration.DefaultLanguage)
-		message := Localize(l, failedToGetStoredGameErrorMessage, nil)
-		p.sendEphemeralPost(req.ChannelId, userID, message)
-		return
-	}
 
 	// submitされたデータの取得
 	var cancel bool
 	var hands []string
+	team, _ := req.Submission["team"].(string)
+	team = strings.TrimSpace(team)
 	handsTmp := make([][]string, 0)
 	for k, v := range req.Submission {
 		if k == "cancel" {
@@ -145,10 +283,7 @@ func (p *Plugin) handleJoinSubmit(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if cancel {
-		// Participantを削除
-		game.RemoveParticipant(userID)
-	} else {
+	if !cancel {
 		// handsTmpをキーでソート
 		sort.Slice(handsTmp, func(i, j int) bool {
 			return handsTmp[i][0] < handsTmp[j][0]
@@ -157,24 +292,110 @@ func (p *Plugin) handleJoinSubmit(w http.ResponseWriter, r *http.Request) {
 		for _, v := range handsTmp {
 			hands = append(hands, v[1])
 		}
+	}
+	p.API.LogDebug("JoinSubmission", "cancel", cancel, "hands", hands, "team", team, "userID", userID)
+
+	// 最新のゲームに反映して保存する
+	var commitment string
+	var autoResult bool
+	var rejected *game
+	game, err := p.store.jankenStore.Update(gameID, func(game *game) error {
+		// ライブ対戦の開始後は参加も取り消しもできない
+		if err := checkLiveWaiting(game); err != nil {
+			rejected = game
+			return err
+		}
+		if cancel {
+			// Participantを削除
+			game.RemoveParticipant(userID)
+			return nil
+		}
+		// 招待されていない場合や最大参加人数に達している場合は新しく参加できない
+		if !game.isInvited(userID) {
+			rejected = game
+			return errNotInvited
+		}
+		if !game.canJoin(userID) {
+			rejected = game
+			return errGameFull
+		}
+		// 最大参加人数に達したのがこの参加の場合だけ結果を表示する
+		joined := game.GetParticipant(userID) == nil
 		// Handsを更新
 		game.UpdateHands(userID, hands)
+		// チームを更新
+		game.UpdateTeam(userID, team)
+		// 登録した手のコミットメントを作る．ライブ対戦では手を登録しない
+		if _, ok := game.Impl.(*gameImplLive); !ok {
+			commitment = game.commitHands(userID)
+		}
+		autoResult = joined && game.shouldAutoResult()
+		return nil
+	})
+	if err == errLiveAlreadyStarted {
+		l := p.getLocalizer(rejected.Language)
+		p.sendEphemeralPost(req.ChannelId, userID, Localize(l, liveAlreadyStartedErrorMessage, nil))
+		return
+	}
+	if err == errNotInvited {
+		l := p.getLocalizer(rejected.Language)
+		p.sendEphemeralPost(req.ChannelId, userID, Localize(l, joinNotInvitedErrorMessage, nil))
+		return
+	}
+	if err == errGameFull {
+		l := p.getLocalizer(rejected.Language)
+		message := Localize(l, joinFullErrorMessage, map[string]interface{}{
+			"Max": rejected.MaxParticipants,
+		})
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+		return
+	}
+	if err != nil {
+		p.API.LogError(err.Error())
+		l := p.getLocalizer(p.configuration.DefaultLanguage)
+		message := Localize(l, failedToGetStoredGameErrorMessage, nil)
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+		return
 	}
-	p.API.LogDebug("JoinSubmission", "cancel", cancel, "hands", hands, "userID", userID)
 
-	// save data to store
-	p.store.jankenStore.Save(game)
+	// 登録した手のコミットメントを公開する
+	if commitment != "" {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, handsCommittedMessage, map[string]interface{}{
+			"Username":   p.getUsername(userID),
+			"Commitment": commitment,
+		})
+		// 匿名のゲームは誰が登録したかを表示しない
+		if game.Anonymous {
+			message = Localize(l, handsCommittedAnonymousMessage, map[string]interface{}{
+				"Commitment": commitment,
+			})
+		}
+		p.replyToPost(post, message)
+	}
 
-	// update post
-	p.attachGameToPost(post, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, game)
-	p.API.UpdatePost(post)
+	// update post．他の操作が先に結果を表示した場合はpostを更新しない
+	if !autoResult {
+		p.attachGameToPost(post, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, game)
+		p.API.UpdatePost(post)
+	} else if p.publishAutoResult(game, post) {
+		p.API.UpdatePost(post)
+	}
 
-	if !cancel {
+	if _, ok := game.Impl.(*gameImplLive); ok && !cancel {
+		// ライブ対戦では手は対戦中に選ぶ
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, joinedMessage, map[string]interface{}{
+			"ID": game.getShortID(),
+		})
+		p.sendEphemeralPost(post.ChannelId, userID, message)
+	} else if !cancel {
 		// show registered hands
 		participant := game.GetParticipant(userID)
+		hs := game.getHandSet()
 		handsEmoji := make([]string, game.MaxRounds)
 		for i := 0; i < game.MaxRounds; i++ {
-			handsEmoji[i] = handIcons[participant.getHand(i)]
+			handsEmoji[i] = hs.icon(game.fillHand(participant, i))
 		}
 		handsStr := strings.Join(handsEmoji, " ")
 		id := game.getShortID()
@@ -210,31 +431,229 @@ func (p *Plugin) handleResult(w http.ResponseWriter, r *http.Request) {
 	// localizer
 	l := p.getLocalizer(game.Language)
 
-	// 権限チェック
-	permission, _ := p.HasPermission(game, userID)
-	if !permission {
-		message := Localize(l, resultPermissionErrorMessage, nil)
+	// 権限と参加人数のチェック
+	if message := p.validateResult(l, game, userID); message != "" {
 		p.sendEphemeralPost(post.ChannelId, userID, message)
 		return
 	}
 
-	// 最低人数2人を満たしているかチェック
-	if len(game.Participants) < 2 {
-		message := Localize(l, resultNotEnoughParticipantsErrorMessage, nil)
-		p.sendEphemeralPost(post.ChannelId, req.UserId, message)
+	// 他の操作が先に結果を表示した場合は何もしない
+	if game = p.claimGame(gameID, checkEnoughParticipants); game == nil {
 		return
 	}
+	p.finishGame(game, post)
 
-	// データ削除
-	p.store.jankenStore.Delete(gameID)
+	response := &model.PostActionIntegrationResponse{}
+	response.Update = post
+	writePostActionIntegrationResponse(response, w, r)
+}
 
-	// Attachmentを削除
-	model.ParseSlackAttachment(post, nil)
+/*
+publishAutoResult は参加人数が最大参加人数に達したゲームの結果をpostに追加する．
+他の操作が先に結果を表示した場合や最大参加人数に達していない場合はpostを変更しない
+Returns:
+    bool: 結果を表示した場合はtrue
+*/
+func (p *Plugin) publishAutoResult(game *game, post *model.Post) bool {
+	if game = p.claimGame(game.ID, checkAutoResult); game == nil {
+		return false
+	}
+	l := p.getLocalizer(game.Language)
+	appendMessage(post, Localize(l, autoResultMessage, map[string]interface{}{
+		"Max": game.MaxParticipants,
+	}))
+	p.finishGame(game, post)
+	return true
+}
 
+/*
+validateResult はユーザーがゲームの結果を表示できるかを確認する．
+Returns:
+    string: 結果を表示できない理由のメッセージ．表示できる場合は空文字
+*/
+func (p *Plugin) validateResult(l *i18n.Localizer, game *game, userID string) string {
+	if permission, _ := p.HasPermission(game, userID); !permission {
+		return Localize(l, resultPermissionErrorMessage, nil)
+	}
+	if !game.hasEnoughParticipants() {
+		return Localize(l, resultNotEnoughParticipantsErrorMessage, map[string]interface{}{
+			"Min": game.minParticipants(),
+		})
+	}
+	return ""
+}
+
+// checkEnoughParticipants は結果を表示するのに必要な人数が参加しているかを確認する
+func checkEnoughParticipants(game *game) error {
+	if !game.hasEnoughParticipants() {
+		return errNotEnoughParticipants
+	}
+	return nil
+}
+
+// checkAutoResult は最大参加人数に達して自動で結果を表示するゲームかを確認する
+func checkAutoResult(game *game) error {
+	if !game.shouldAutoResult() {
+		return errNotFull
+	}
+	return nil
+}
+
+// checkLiveWaiting はライブ対戦が開始前かを確認する．ライブ対戦でないゲームは常に開始前とみなす
+func checkLiveWaiting(game *game) error {
+	if live, ok := game.Impl.(*gameImplLive); ok && live.State != liveStateWaiting {
+		return errLiveAlreadyStarted
+	}
+	return nil
+}
+
+/*
+getLiveSettingChanges はライブ対戦の開始後に変更できない設定のうち変更しようとしている設定を返す．
+グループの状態はゲームの種類，手の組み合わせ，最大対戦回数に依存する
+Args:
+    game: 変更する前のゲーム
+    gameType: 変更後のゲームの種類
+    handSet: 変更後の手の組み合わせ
+    maxRounds: 変更後の最大対戦回数
+Returns:
+    []string: 変更しようとしている設定のダイアログの要素名．ライブ対戦の開始前の場合は空
+*/
+func getLiveSettingChanges(game *game, gameType, handSet string, maxRounds int) []string {
+	changes := []string{}
+	if checkLiveWaiting(game) == nil {
+		return changes
+	}
+	if newGameFuncMapping[gameType] != nil && gameType != game.GameType {
+		changes = append(changes, "game_type")
+	}
+	if isValidHandSet(handSet) && handSet != game.getHandSet().Name {
+		changes = append(changes, "hand_set")
+	}
+	if maxRounds != game.MaxRounds {
+		changes = append(changes, "max_rounds")
+	}
+	return changes
+}
+
+// checkLiveFinished はライブ対戦の全員の順位が決まったかを確認する
+func checkLiveFinished(game *game) error {
+	if live, ok := game.Impl.(*gameImplLive); !ok || !live.isFinished() {
+		return errNotFinished
+	}
+	return nil
+}
+
+/*
+claimGame は結果を表示するゲームをKVストアから取り出す．
+同じゲームの結果を同時に表示しようとした場合は1つの呼び出しだけがゲームを受け取る
+Args:
+    id: ゲームのID
+    check: 取り出す直前の最新のゲームを確認する．nilの場合は確認しない
+Returns:
+    *game: 取り出した最新のゲーム．他の呼び出しが先に取り出した場合や確認に失敗した場合はnil
+*/
+func (p *Plugin) claimGame(id string, check func(*game) error) *game {
+	game, err := p.store.jankenStore.Claim(id, check)
+	if err != nil {
+		p.API.LogDebug("The game was not claimed", "id", id, "error", err.Error())
+		return nil
+	}
+	return game
+}
+
+/*
+finishGame はclaimGameで取り出したゲームの結果を表示し，履歴に保存する．
+結果とジャンケンの経過のAttachmentは結果の表示方法に従ってpostに追加するか新しく投稿する．
+経過と検証のための情報はスレッドにも返信する
+*/
+func (p *Plugin) finishGame(game *game, post *model.Post) {
 	// 結果取得
 	result := game.getResult()
 	p.API.LogDebug("Result", "game", fmt.Sprintf("%#v", game), "result", fmt.Sprintf("%#v", result))
 
+	// 履歴と成績に保存
+	ratings := p.saveGameRecord(game, result)
+
+	// 結果と経過を表示する
+	l := p.getLocalizer(game.Language)
+	replay := p.getReplayMessage(game)
+	var replayAttachments []*model.SlackAttachment
+	if replay != "" {
+		replayAttachments = []*model.SlackAttachment{{
+			Title: Localize(l, replayTitle, nil),
+			Text:  replay,
+		}}
+	}
+	resultPostID := p.publishResult(game, post, p.getResultMessage(game, result, ratings), replayAttachments)
+
+	// 参加者それぞれに順位と手をダイレクトメッセージで送る
+	p.notifyParticipants(game, result, resultPostID)
+
+	// 賞品や罰ゲームが割り当てられた参加者にメンションで知らせる
+	if message := p.getOutcomeNotification(game, result); message != "" {
+		p.replyToPost(post, message)
+	}
+
+	if replay != "" {
+		p.replyToPost(post, fmt.Sprintf("**%s**\n%s", Localize(l, replayTitle, nil), replay))
+	}
+
+	// 結果を検証するための情報を公開する
+	b, err := json.MarshalIndent(game.reveal(), "", "  ")
+	if err != nil {
+		p.API.LogError("failed to marshal the revealed game", "error", err.Error())
+		return
+	}
+	message := Localize(l, revealMessage, map[string]interface{}{
+		"Seed":           game.Seed,
+		"SeedCommitment": game.SeedCommitment,
+	})
+	p.replyToPost(post, fmt.Sprintf("%s\n```json\n%s\n```", message, b))
+}
+
+// getReplayMessage はジャンケンの経過を1回ずつ返す
+func (p *Plugin) getReplayMessage(game *game) string {
+	l := p.getLocalizer(game.Language)
+	lines := make([]string, 0, len(game.Rounds))
+	for _, event := range game.Rounds {
+		lines = append(lines, p.getRoundEventMessage(l, game.getHandSet(), event))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getRoundEventMessage はジャンケン1回分の結果を返す
+func (p *Plugin) getRoundEventMessage(l *i18n.Localizer, hs *handSet, event *roundEvent) string {
+	hands := make([]string, 0, len(event.Members))
+	for i, id := range event.Members {
+		hands = append(hands, fmt.Sprintf("%s %s", p.getMention(id), hs.icon(event.Hands[i])))
+	}
+	params := map[string]interface{}{
+		"Round": event.Round + 1,
+		"Hands": strings.Join(hands, " "),
+	}
+	if event.Draw {
+		return Localize(l, roundEventDrawMessage, params)
+	}
+
+	mentions := func(ids []string) string {
+		m := make([]string, 0, len(ids))
+		for _, id := range ids {
+			m = append(m, p.getMention(id))
+		}
+		return strings.Join(m, ", ")
+	}
+	params["Winners"] = mentions(event.Winners)
+	params["Losers"] = mentions(event.Losers)
+	return Localize(l, roundEventMessage, params)
+}
+
+/*
+getResultMessage は結果の表を返す．
+ratingsがある場合はレーティングの列を追加する
+*/
+func (p *Plugin) getResultMessage(game *game, result []*participant, ratings map[string]*ratingChange) string {
+	l := p.getLocalizer(game.Language)
+
 	rankLabel := Localize(l, resultTableRankLabel, nil)
 	userNameLabel := Localize(l, resultTableUsernameLabel, nil)
 	handsLabel := Localize(l, resultTableHandsLabel, nil)
@@ -242,33 +661,290 @@ func (p *Plugin) handleResult(w http.ResponseWriter, r *http.Request) {
 	resultStr := Localize(l, resultTableTitle, map[string]interface{}{
 		"ID": game.getShortID(),
 	})
-	resultStr = fmt.Sprintf("%s\n%s", resultStr, fmt.Sprintf("|%s|%s|%s|", rankLabel, userNameLabel, handsLabel))
-	resultStr = fmt.Sprintf("%s\n%s", resultStr, "|:---:|:---|:---|")
+	if game.Title != "" {
+		resultStr = Localize(l, resultTableCustomTitle, map[string]interface{}{
+			"Title": game.Title,
+			"ID":    game.getShortID(),
+		})
+	}
+	if game.Purpose != "" {
+		resultStr += Localize(l, jankenGamePurposeDescription, map[string]interface{}{
+			"Purpose": game.Purpose,
+		}) + "\n"
+	}
+	header := fmt.Sprintf("|%s|%s|%s|", rankLabel, userNameLabel, handsLabel)
+	separator := "|:---:|:---|:---|"
+	noter, hasNote := game.Impl.(gameResultNoter)
+	if hasNote {
+		header = fmt.Sprintf("%s%s|", header, Localize(l, resultTableNoteLabel, nil))
+		separator += ":---|"
+	}
+	hasRating := len(ratings) > 0
+	if hasRating {
+		header = fmt.Sprintf("%s%s|", header, Localize(l, resultTableRatingLabel, nil))
+		separator += "---:|"
+	}
+	hasOutcome := len(game.Outcomes) > 0
+	outcomes := assignOutcomes(game.Outcomes, result)
+	if hasOutcome {
+		header = fmt.Sprintf("%s%s|", header, Localize(l, resultTableOutcomeLabel, nil))
+		separator += ":---|"
+	}
+	resultStr = fmt.Sprintf("%s\n%s\n%s", resultStr, header, separator)
+	hs := game.getHandSet()
+	usernames := make(map[string]string)
 	for _, participant := range result {
-		username := participant.UserID
-		u, err := p.API.GetUser(participant.UserID)
-		if err == nil {
-			username = u.Username
-		}
+		username := p.getUsername(participant.UserID)
+		usernames[participant.UserID] = username
 
 		hands := make([]string, 0, len(participant.Hands))
 		for _, h := range participant.Hands {
-			hands = append(hands, handIcons[h])
+			hands = append(hands, hs.icon(h))
 		}
 		handsStr := strings.Join(hands, " ")
 
-		text := fmt.Sprintf("|%d|@%s|%s|", participant.Rank, username, handsStr)
+		// 順位なしは"-"で表示する
+		rankStr := "-"
+		if participant.Rank > 0 {
+			rankStr = strconv.Itoa(participant.Rank)
+		}
+
+		text := fmt.Sprintf("|%s|@%s|%s|", rankStr, username, handsStr)
+		if hasNote {
+			text = fmt.Sprintf("%s%s|", text, noter.getResultNote(l, participant))
+		}
+		if hasRating {
+			text = fmt.Sprintf("%s%s|", text, formatRatingChange(ratings[participant.UserID]))
+		}
+		if hasOutcome {
+			text = fmt.Sprintf("%s%s|", text, escapeTableCell(strings.Join(outcomes[participant.UserID], ", ")))
+		}
 		resultStr = fmt.Sprintf("%s\n%s", resultStr, text)
 	}
 
-	// 結果を追加
-	appendMessage(post, resultStr)
+	// ゲームの種類ごとの詳細を追加
+	if d, ok := game.Impl.(gameResultDetailer); ok {
+		resultStr = fmt.Sprintf("%s\n\n%s", resultStr, d.getResultDetail(l, usernames))
+	}
+
+	return resultStr
+}
+
+// getUsername はユーザー名を返す．ユーザーが見つからない場合はUserIDを返す
+func (p *Plugin) getUsername(userID string) string {
+	u, err := p.API.GetUser(userID)
+	if err != nil {
+		return userID
+	}
+	return u.Username
+}
+
+// getMention はユーザーへのメンションを返す．ユーザーが見つからない場合(チーム名など)はそのまま返す
+func (p *Plugin) getMention(id string) string {
+	u, err := p.API.GetUser(id)
+	if err != nil {
+		return id
+	}
+	return "@" + u.Username
+}
+
+func (p *Plugin) handleLiveStart(w http.ResponseWriter, r *http.Request) {
+	req := model.PostActionIntegrationRequestFromJson(r.Body)
+
+	userID := req.UserId
+	postID := req.PostId
+	post, _ := p.API.GetPost(postID)
+
+	gameID := req.Context["id"].(string)
+	game, err := p.store.jankenStore.Update(gameID, func(game *game) error {
+		// 権限チェック
+		if permission, _ := p.HasPermission(game, userID); !permission {
+			return errPermission
+		}
+		// 最低人数を満たしているかチェック
+		if !game.hasEnoughParticipants() {
+			return errNotEnoughParticipants
+		}
+		live, ok := game.Impl.(*gameImplLive)
+		if !ok {
+			return errLiveNotPlaying
+		}
+		if err := live.start(game); err != nil {
+			return err
+		}
+		game.PostID = postID
+		return nil
+	})
+	if err != nil {
+		p.sendLiveErrorMessage(req.ChannelId, userID, gameID, err)
+		return
+	}
+
+	p.publishLiveGame(game, game.Impl.(*gameImplLive), post, nil)
+
+	response := &model.PostActionIntegrationResponse{}
+	response.Update = post
+	writePostActionIntegrationResponse(response, w, r)
+}
+
+func (p *Plugin) handleLiveHand(w http.ResponseWriter, r *http.Request) {
+	req := model.PostActionIntegrationRequestFromJson(r.Body)
+
+	userID := req.UserId
+	postID := req.PostId
+	post, _ := p.API.GetPost(postID)
+
+	gameID := req.Context["id"].(string)
+	hand, _ := req.Context["hand"].(string)
+	var events []*roundEvent
+	var played bool
+	game, err := p.store.jankenStore.Update(gameID, func(game *game) error {
+		live, ok := game.Impl.(*gameImplLive)
+		if !ok {
+			return errLiveNotPlaying
+		}
+		// 時間切れのジャンケンは手を出していないメンバーの手を乱数で決めて先に進める．
+		// 既に手を出したメンバーや他のグループの参加者のボタンでも進められるように手を出す前に確認する
+		timedOut := live.isTimedOut(model.GetMillis())
+		err := live.play(game, userID, hand)
+		if err != nil && (!timedOut || game.GetParticipant(userID) == nil) {
+			return err
+		}
+		played = err == nil
+		if timedOut {
+			live.fillMissingHands(game)
+		}
+		events = advanceLiveGame(game, live)
+		return nil
+	})
+	if err != nil {
+		p.sendLiveErrorMessage(req.ChannelId, userID, gameID, err)
+		return
+	}
+
+	if played {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, liveHandPlayedMessage, map[string]interface{}{
+			"Hand": game.getHandSet().icon(hand),
+		})
+		p.sendEphemeralPost(req.ChannelId, userID, message)
+	}
+
+	if !p.publishLiveGame(game, game.Impl.(*gameImplLive), post, events) {
+		return
+	}
 
 	response := &model.PostActionIntegrationResponse{}
 	response.Update = post
 	writePostActionIntegrationResponse(response, w, r)
 }
 
+/*
+handleLiveTimeout は時間切れになったジャンケンの手を乱数で決めて先に進める．
+startedAtが現在のジャンケンの開始日時と異なる場合は既に先に進んでいるので何もしない．
+時間切れは締め切りと同じジョブで保存したジャンケンの開始日時から判定するので，プラグインを再起動しても失われない
+*/
+func (p *Plugin) handleLiveTimeout(gameID string, startedAt int64) {
+	var events []*roundEvent
+	game, err := p.store.jankenStore.Update(gameID, func(game *game) error {
+		live, ok := game.Impl.(*gameImplLive)
+		if !ok || !live.isPlaying() || live.RoundStartedAt != startedAt {
+			return errLiveNotPlaying
+		}
+		live.fillMissingHands(game)
+		events = advanceLiveGame(game, live)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	post, appErr := p.API.GetPost(game.PostID)
+	if appErr != nil {
+		p.API.LogError(appErr.Error())
+		return
+	}
+
+	if p.publishLiveGame(game, game.Impl.(*gameImplLive), post, events) {
+		p.API.UpdatePost(post)
+	}
+}
+
+// sendLiveErrorMessage はライブ対戦の操作に失敗した理由をユーザーに送る
+func (p *Plugin) sendLiveErrorMessage(channelID, userID, gameID string, err error) {
+	var message *i18n.Message
+	switch err {
+	case errPermission:
+		message = resultPermissionErrorMessage
+	case errNotEnoughParticipants:
+		message = resultNotEnoughParticipantsErrorMessage
+	case errLiveAlreadyStarted:
+		message = liveAlreadyStartedErrorMessage
+	case errLiveNotPlaying:
+		message = liveNotPlayingErrorMessage
+	case errLiveNotYourTurn, errLiveInvalidHand:
+		message = liveNotYourTurnErrorMessage
+	case errLiveAlreadyPlayed:
+		message = liveAlreadyPlayedErrorMessage
+	default:
+		p.API.LogError(err.Error())
+		l := p.getLocalizer(p.configuration.DefaultLanguage)
+		p.sendEphemeralPost(channelID, userID, Localize(l, failedToGetStoredGameErrorMessage, nil))
+		return
+	}
+
+	language := p.configuration.DefaultLanguage
+	minParticipants := defaultMinParticipants
+	if game, err := p.store.jankenStore.Get(gameID); err == nil {
+		language = game.Language
+		minParticipants = game.minParticipants()
+	}
+	l := p.getLocalizer(language)
+	p.sendEphemeralPost(channelID, userID, Localize(l, message, map[string]interface{}{
+		"Min": minParticipants,
+	}))
+}
+
+/*
+advanceLiveGame は手が揃ったジャンケンを進める．
+Returns:
+    []*roundEvent: 進めたジャンケンの結果
+*/
+func advanceLiveGame(game *game, live *gameImplLive) []*roundEvent {
+	events := []*roundEvent{}
+	for event := live.advance(game); event != nil; event = live.advance(game) {
+		events = append(events, event)
+	}
+	return events
+}
+
+/*
+publishLiveGame は進めたジャンケンの結果をpostに追加する．
+全員の順位が決まった場合は結果を表示してゲームを削除する
+Returns:
+    bool: postを更新する場合はtrue．他の操作が先に結果を表示した場合はfalse
+*/
+func (p *Plugin) publishLiveGame(game *game, live *gameImplLive, post *model.Post, events []*roundEvent) bool {
+	if live.isFinished() {
+		if game = p.claimGame(game.ID, checkLiveFinished); game == nil {
+			return false
+		}
+	}
+
+	l := p.getLocalizer(game.Language)
+	hs := game.getHandSet()
+	for _, event := range events {
+		appendMessage(post, p.getRoundEventMessage(l, hs, event))
+	}
+
+	if live.isFinished() {
+		p.finishGame(game, post)
+		return true
+	}
+
+	p.attachGameToPost(post, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, game)
+	return true
+}
+
 func (p *Plugin) handleConfig(w http.ResponseWriter, r *http.Request) {
 	req := model.PostActionIntegrationRequestFromJson(r.Body)
 
@@ -296,7 +972,7 @@ func (p *Plugin) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	d := newConfigDialog(p.API, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, p)
-	d.Open(req.TriggerId, postID, game)
+	d.Open(req.TriggerId, postID, userID, game)
 
 	response := &model.PostActionIntegrationResponse{}
 	writePostActionIntegrationResponse(response, w, r)
@@ -316,7 +992,7 @@ func (p *Plugin) handleConfigSubmit(w http.ResponseWriter, r *http.Request) {
 	post, _ := p.API.GetPost(postID)
 
 	gameID := req.State
-	game, err := p.store.jankenStore.Get(gameID)
+	stored, err := p.store.jankenStore.Get(gameID)
 	if err != nil {
 		p.API.LogError(err.Error())
 		l := p.getLocalizer(p.configuration.DefaultLanguage)
@@ -327,32 +1003,197 @@ func (p *Plugin) handleConfigSubmit(w http.ResponseWriter, r *http.Request) {
 
 	destroy, _ := strconv.ParseBool(req.Submission["destroy"].(string))
 	maxRounds, _ := strconv.Atoi(req.Submission["max_rounds"].(string))
-	p.API.LogDebug("submission", "destroy", destroy, "maxRounds", maxRounds)
+	handSet, _ := req.Submission["hand_set"].(string)
+	gameType, _ := req.Submission["game_type"].(string)
+	numWinners, numWinnersErr := submissionToInt(req.Submission["num_winners"])
+	maxParticipants, maxParticipantsErr := submissionToInt(req.Submission["max_participants"])
+	minParticipants, minParticipantsErr := submissionToInt(req.Submission["min_participants"])
+	autoResultStr, _ := req.Submission["auto_result"].(string)
+	autoResult, _ := strconv.ParseBool(autoResultStr)
+	deadlineStr, _ := req.Submission["deadline"].(string)
+	title, _ := req.Submission["title"].(string)
+	title = strings.TrimSpace(title)
+	purpose, _ := req.Submission["purpose"].(string)
+	purpose = strings.TrimSpace(purpose)
+	outcomesStr, _ := req.Submission["outcomes"].(string)
+	resultPublishMode, _ := req.Submission["result_publish_mode"].(string)
+	outcomes, outcomesErr := parseOutcomes(outcomesStr)
+	p.API.LogDebug("submission", "destroy", destroy, "maxRounds", maxRounds, "handSet", handSet, "gameType", gameType, "numWinners", numWinners)
 
 	if destroy {
-		p.store.jankenStore.Delete(gameID)
-		// Attachmentを削除
-		model.ParseSlackAttachment(post, nil)
+		if !p.destroyGame(stored.ID, post, userID) {
+			l := p.getLocalizer(stored.Language)
+			message := Localize(l, failedToGetStoredGameErrorMessage, nil)
+			p.sendEphemeralPost(req.ChannelId, userID, message)
+		}
+		return
+	}
 
-		// メッセージを追加
-		l := p.getLocalizer(game.Language)
-		user, _ := p.API.GetUser(req.UserId)
-		message := Localize(l, jankenGameDestroyedMessage, map[string]interface{}{
-			"Username": user.Username,
+	// 入力値のチェック
+	l := p.getLocalizer(stored.Language)
+	dialogErrors := map[string]string{}
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		dialogErrors["title"] = Localize(l, configInvalidTitleErrorMessage, map[string]interface{}{
+			"Max": maxTitleLength,
 		})
-		appendMessage(post, message)
+	}
+	if utf8.RuneCountInString(purpose) > maxPurposeLength {
+		dialogErrors["purpose"] = Localize(l, configInvalidPurposeErrorMessage, map[string]interface{}{
+			"Max": maxPurposeLength,
+		})
+	}
+	if outcomesErr != nil {
+		dialogErrors["outcomes"] = Localize(l, configInvalidOutcomesErrorMessage, map[string]interface{}{
+			"Max":       maxOutcomes,
+			"MaxLength": maxOutcomeLength,
+		})
+	}
+	if numWinnersErr != nil || numWinners < 1 {
+		dialogErrors["num_winners"] = Localize(l, configInvalidNumWinnersErrorMessage, nil)
+	}
+	if maxParticipantsErr != nil || maxParticipants < 0 || maxParticipants == 1 || (maxParticipants > 0 && maxParticipants < len(stored.Participants)) {
+		dialogErrors["max_participants"] = Localize(l, configInvalidMaxParticipantsErrorMessage, map[string]interface{}{
+			"Count": len(stored.Participants),
+		})
+	}
+	if minParticipantsErr != nil || minParticipants < defaultMinParticipants || (maxParticipants > 0 && minParticipants > maxParticipants) {
+		dialogErrors["min_participants"] = Localize(l, configInvalidMinParticipantsErrorMessage, nil)
+	}
+	// 締め切りを変更していない場合は秒を切り捨てずにそのままにする
+	location := p.getUserLocation(userID)
+	deadline, deadlineErr := stored.Deadline, error(nil)
+	if strings.TrimSpace(deadlineStr) != formatDeadlineInput(stored.Deadline, location) {
+		deadline, deadlineErr = parseDeadline(deadlineStr, time.Now().In(location))
+	}
+	if deadlineErr != nil {
+		dialogErrors["deadline"] = Localize(l, configInvalidDeadlineErrorMessage, nil)
+	}
+	// ライブ対戦の開始後はゲームの種類，手の組み合わせ，最大対戦回数を変更できない
+	for _, name := range getLiveSettingChanges(stored, gameType, handSet, maxRounds) {
+		dialogErrors[name] = Localize(l, liveAlreadyStartedErrorMessage, nil)
+	}
+	if len(dialogErrors) > 0 {
+		response := &model.SubmitDialogResponse{Errors: dialogErrors}
+		writeSubmitDialogResponse(response, w, r)
+		return
+	}
 
-		// 更新
-		p.API.UpdatePost(post)
+	// 最新のゲームに反映して保存する
+	game, err := p.store.jankenStore.Update(gameID, func(g *game) error {
+		// ダイアログを開いた後にライブ対戦が開始した場合も変更しない
+		if len(getLiveSettingChanges(g, gameType, handSet, maxRounds)) > 0 {
+			return errLiveAlreadyStarted
+		}
+		g.Title = title
+		g.Purpose = purpose
+		g.Outcomes = outcomes
+		// プラグインの設定を使う場合は空にする
+		g.ResultPublishMode = ""
+		if isValidResultPublishMode(resultPublishMode) {
+			g.ResultPublishMode = resultPublishMode
+		}
+		g.MaxRounds = maxRounds
+		g.NumWinners = numWinners
+		g.Deadline = deadline
+		// ダイアログを開いた後に参加者が増えた場合は参加者を減らさずに現在の人数を最大参加人数にする
+		g.MaxParticipants = maxParticipants
+		if maxParticipants > 0 && maxParticipants < len(g.Participants) {
+			g.MaxParticipants = len(g.Participants)
+		}
+		g.AutoResult = autoResult
+		g.MinParticipants = minParticipants
+		if isValidHandSet(handSet) {
+			g.setHandSet(handSet)
+		}
+		if f := newGameFuncMapping[gameType]; f != nil && gameType != g.GameType {
+			g.setImpl(f())
+		}
+		return nil
+	})
+	if err == errLiveAlreadyStarted {
+		p.sendEphemeralPost(req.ChannelId, userID, Localize(l, liveAlreadyStartedErrorMessage, nil))
+		return
+	}
+	if err != nil {
+		p.API.LogError(err.Error())
+		message := Localize(l, failedToGetStoredGameErrorMessage, nil)
+		p.sendEphemeralPost(req.ChannelId, userID, message)
 		return
 	}
 
-	game.MaxRounds = maxRounds
+	// 変更した最大参加人数に既に達している場合は結果を表示する．他の操作が先に結果を表示した場合はpostを更新しない
+	if !game.shouldAutoResult() {
+		p.attachGameToPost(post, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, game)
+		p.API.UpdatePost(post)
+	} else if p.publishAutoResult(game, post) {
+		p.API.UpdatePost(post)
+	}
+}
+
+/*
+destroyGame はゲームを削除してpostのボタンを消し，削除したユーザーを表示する．
+他の操作が先に結果を表示したり削除したりした場合はpostを変更しない
+Returns:
+    bool: ゲームを削除した場合はtrue
+*/
+func (p *Plugin) destroyGame(id string, post *model.Post, userID string) bool {
+	game := p.claimGame(id, nil)
+	if game == nil {
+		return false
+	}
+	// Attachmentを削除
+	model.ParseSlackAttachment(post, nil)
 
-	p.store.jankenStore.Save(game)
+	// メッセージを追加
+	l := p.getLocalizer(game.Language)
+	message := Localize(l, jankenGameDestroyedMessage, map[string]interface{}{
+		"Username": p.getUsername(userID),
+	})
+	appendMessage(post, message)
 
-	p.attachGameToPost(post, *p.ServerConfig.ServiceSettings.SiteURL, PluginID, game)
+	// 更新
 	p.API.UpdatePost(post)
+	return true
+}
+
+/*
+handleAutocompleteGames はオートコンプリートでチャンネルの受付中のゲームのIDの候補を返す．
+チャンネルのメンバーでないユーザーには候補を返さない
+*/
+func (p *Plugin) handleAutocompleteGames(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	channelID := r.URL.Query().Get("channel_id")
+	if _, appErr := p.API.GetChannelMember(channelID, userID); appErr != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	games, err := p.store.jankenStore.ListByChannel(channelID)
+	if err != nil {
+		p.API.LogWarn("failed to list games", "channel_id", channelID, "error", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+	items := []model.AutocompleteListItem{}
+	for _, g := range games {
+		items = append(items, model.AutocompleteListItem{
+			Item: g.getShortID(),
+			HelpText: Localize(l, commandGameIDItemHelp, map[string]interface{}{
+				"Username": p.getUsername(g.Creator),
+				"Count":    len(g.Participants),
+			}),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(model.AutocompleteStaticListItemsToJSON(items)))
 }
 
 func (p *Plugin) handleIcon(w http.ResponseWriter, r *http.Request) {
@@ -372,3 +1213,9 @@ func writePostActionIntegrationResponse(response *model.PostActionIntegrationRes
 	w.WriteHeader(http.StatusOK)
 	w.Write(response.ToJson())
 }
+
+func writeSubmitDialogResponse(response *model.SubmitDialogResponse, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response.ToJson())
+}
@@ -5,10 +5,12 @@ import (
 	"testing"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/text/language"
 )
 
-
 func TestWritePostActionIntegrationResponse(t *testing.T) {
 	for name, test := range map[string]struct {
 		ExpectedHeader     http.Header
@@ -33,3 +35,108 @@ func TestWritePostActionIntegrationResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteSubmitDialogResponse(t *testing.T) {
+	for name, test := range map[string]struct {
+		ExpectedHeader http.Header
+	}{
+		"successfully": {
+			ExpectedHeader: http.Header{
+				"Content-Type": []string{"application/json"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			response := &model.SubmitDialogResponse{}
+			w := NewTestResponseWriter()
+			r := &http.Request{}
+			writeSubmitDialogResponse(response, w, r)
+
+			assert.Equal(test.ExpectedHeader, w.header)
+		})
+	}
+}
+
+func TestCheckLiveWaiting(t *testing.T) {
+	assert := assert.New(t)
+	b, g := newLiveTestGame("p1", "p2")
+	assert.Nil(checkLiveWaiting(b))
+	assert.Nil(checkLiveWaiting(newGame(&gameImpl1{})))
+
+	g.start(b)
+	assert.Equal(errLiveAlreadyStarted, checkLiveWaiting(b))
+}
+
+func TestGetLiveSettingChanges(t *testing.T) {
+	for name, test := range map[string]struct {
+		Started   bool
+		GameType  string
+		HandSet   string
+		MaxRounds int
+		Expected  []string
+	}{
+		"no changes":               {Started: true, GameType: "gameImplLive", HandSet: "rps", MaxRounds: 5, Expected: []string{}},
+		"changes before the start": {GameType: "gameImpl1", HandSet: "rpsls", MaxRounds: 10, Expected: []string{}},
+		"game type":                {Started: true, GameType: "gameImpl1", HandSet: "rps", MaxRounds: 5, Expected: []string{"game_type"}},
+		"unknown game type":        {Started: true, GameType: "unknown", HandSet: "rps", MaxRounds: 5, Expected: []string{}},
+		"hand set and max rounds":  {Started: true, GameType: "gameImplLive", HandSet: "rpsls", MaxRounds: 10, Expected: []string{"hand_set", "max_rounds"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, g := newLiveTestGame("p1", "p2")
+			if test.Started {
+				g.start(b)
+			}
+			assert.Equal(t, test.Expected, getLiveSettingChanges(b, test.GameType, test.HandSet, test.MaxRounds))
+		})
+	}
+}
+
+func TestDestroyGame(t *testing.T) {
+	newPlugin := func() (*Plugin, *atomicKV, *[]*model.Post) {
+		api, kv := newAtomicKVAPI()
+		updated := []*model.Post{}
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(
+			func(post *model.Post) *model.Post {
+				updated = append(updated, post)
+				return post
+			},
+			nil)
+		api.On("GetUser", "user").Return(&model.User{Username: "user"}, nil)
+		p := &Plugin{}
+		p.API = api
+		p.store = NewStore(api)
+		p.bundle = i18n.NewBundle(language.English)
+		return p, kv, &updated
+	}
+
+	t.Run("destroys the game only once", func(t *testing.T) {
+		assert := assert.New(t)
+		p, kv, updated := newPlugin()
+		g := newGame(&gameImpl1{})
+		assert.Nil(p.store.jankenStore.Save(g))
+
+		assert.True(p.destroyGame(g.ID, &model.Post{Message: "game"}, "user"))
+		assert.Nil(kv.values[keyPrefix+g.ID])
+		assert.Len(*updated, 1)
+		assert.Contains((*updated)[0].Message, "@user")
+
+		assert.False(p.destroyGame(g.ID, &model.Post{Message: "game"}, "user"))
+		assert.Len(*updated, 1)
+	})
+
+	t.Run("keeps the post of a game whose result is claimed", func(t *testing.T) {
+		assert := assert.New(t)
+		p, _, updated := newPlugin()
+		g := newGame(&gameImpl1{})
+		assert.Nil(p.store.jankenStore.Save(g))
+		_, err := p.store.jankenStore.Claim(g.ID, nil)
+		assert.Nil(err)
+
+		post := &model.Post{Message: "result"}
+		assert.False(p.destroyGame(g.ID, post, "user"))
+		assert.Equal("result", post.Message)
+		assert.Empty(*updated)
+	})
+}
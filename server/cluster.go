@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+/*
+clusterMutex はKVストアを使ってクラスタ内のサーバー間で排他制御する．
+ロックを解除する前にサーバーが停止した場合でも期限が過ぎるとロックは外れる．
+mattermost-plugin-apiのcluster.Mutexはこのプラグインの依存に含まれないため同じ仕組みをKVストアで実装する．
+cluster.Mutexと違って保持中に期限を延長しないので，処理が期限より長くかかると他のサーバーも
+ロックを取得して同時に処理する．このロックは重複した処理を減らすためのもので，
+ゲームの結果を1回だけ表示することはjankenStore.Claimで保証する
+*/
+type clusterMutex struct {
+	api             plugin.API
+	key             string
+	expireInSeconds int64
+	// ロックを取得したときに保存した値．期限が過ぎた後に他のサーバーが取得したロックを解除しないために使う
+	token []byte
+}
+
+func newClusterMutex(api plugin.API, key string, expireInSeconds int64) *clusterMutex {
+	return &clusterMutex{
+		api:             api,
+		key:             key,
+		expireInSeconds: expireInSeconds,
+	}
+}
+
+/*
+tryLock はロックを取得する．
+Returns:
+    bool: ロックを取得できた場合はtrue．他のサーバーがロックしている場合はfalse
+    error: KVストアのエラー
+*/
+func (m *clusterMutex) tryLock() (bool, error) {
+	// OldValueがnilの場合はキーが存在しないときだけ保存される
+	token := []byte(model.NewId())
+	ok, appErr := m.api.KVSetWithOptions(m.key, token, model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: m.expireInSeconds,
+	})
+	if appErr != nil {
+		return false, errors.New(appErr.DetailedError)
+	}
+	if ok {
+		m.token = token
+	}
+	return ok, nil
+}
+
+/*
+unlock はロックを解除する．
+期限が過ぎて他のサーバーがロックを取得している場合は解除しない
+*/
+func (m *clusterMutex) unlock() {
+	if m.token == nil {
+		return
+	}
+	// 値がロックを取得したときの値と同じ場合だけ削除する
+	ok, appErr := m.api.KVSetWithOptions(m.key, nil, model.PluginKVSetOptions{
+		Atomic:   true,
+		OldValue: m.token,
+	})
+	m.token = nil
+	if appErr != nil {
+		m.api.LogWarn("failed to unlock", "key", m.key, "error", appErr.Error())
+		return
+	}
+	if !ok {
+		m.api.LogWarn("the lock has expired before unlocking", "key", m.key)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterMutex(t *testing.T) {
+	assert := assert.New(t)
+
+	api, kv := newAtomicKVAPI()
+	m1 := newClusterMutex(api, "janken_lock_test", 60)
+	m2 := newClusterMutex(api, "janken_lock_test", 60)
+
+	locked, err := m1.tryLock()
+	assert.Nil(err)
+	assert.True(locked)
+	assert.Equal(int64(60), kv.expiries["janken_lock_test"])
+
+	// 他のサーバーがロックしている間は取得できない
+	locked, err = m2.tryLock()
+	assert.Nil(err)
+	assert.False(locked)
+
+	m1.unlock()
+	locked, err = m2.tryLock()
+	assert.Nil(err)
+	assert.True(locked)
+
+	// ロックしていないサーバーや期限が過ぎた後のサーバーは他のサーバーのロックを解除しない
+	m1.unlock()
+	assert.NotNil(kv.values["janken_lock_test"])
+	expired := newClusterMutex(api, "janken_lock_test", 60)
+	expired.token = []byte("expired")
+	expired.unlock()
+	assert.Equal(m2.token, kv.values["janken_lock_test"])
+
+	m2.unlock()
+	assert.Nil(kv.values["janken_lock_test"])
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/kballard/go-shellquote"
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -21,6 +24,18 @@ var (
 		ID:    "gameTitle",
 		Other: "Janken game ({{.ID}}) created by @{{.Username}}",
 	}
+	jankenGameCustomTitle = &i18n.Message{
+		ID:    "gameCustomTitle",
+		Other: "{{.Title}} ({{.ID}}) created by @{{.Username}}",
+	}
+	jankenGamePurposeDescription = &i18n.Message{
+		ID:    "gamePurposeDescription",
+		Other: "Purpose: {{.Purpose}}",
+	}
+	jankenGameAnonymousParticipants = &i18n.Message{
+		ID:    "gameAnonymousParticipants",
+		Other: "(hidden until the result is shown)",
+	}
 	jankenGameDescription = &i18n.Message{
 		ID: "gameDescription",
 		Other: `Please join this janken game.
@@ -38,10 +53,127 @@ participants ({{.participantsNum}}): {{.participantsStr}}`,
 		ID:    "gameResultButtonLabel",
 		Other: "Result",
 	}
+	jankenGameSeedCommitmentFooter = &i18n.Message{
+		ID:    "gameSeedCommitmentFooter",
+		Other: "Seed commitment: {{.SeedCommitment}}",
+	}
+	jankenGameStartButtonLabel = &i18n.Message{
+		ID:    "gameStartButtonLabel",
+		Other: "Start",
+	}
+	jankenGameLiveDescription = &i18n.Message{
+		ID: "gameLiveDescription",
+		Other: `Round {{.Round}}: {{.Members}}
+Waiting for: {{.Waiting}}
+Choose your hand within {{.Timeout}} seconds. Hands not chosen in time are picked at random.`,
+	}
+	jankenGameInvitedDescription = &i18n.Message{
+		ID:    "gameInvitedDescription",
+		Other: "Invited ({{.Registered}}/{{.Invited}} registered): {{.Users}}",
+	}
+	jankenListTitle = &i18n.Message{
+		ID:    "listTitle",
+		Other: "Open janken games in this channel",
+	}
+	jankenListHeader = &i18n.Message{
+		ID: "listHeader",
+		Other: `| ID | Created by | Participants | Age | Post |
+| --- | --- | --- | --- | --- |`,
+	}
+	jankenListLinkLabel = &i18n.Message{
+		ID:    "listLinkLabel",
+		Other: "Open",
+	}
+	jankenUserNotFoundErrorMessage = &i18n.Message{
+		ID:    "userNotFoundErrorMessage",
+		Other: "User {{.Username}} is not found.",
+	}
+	jankenListEmptyMessage = &i18n.Message{
+		ID:    "listEmptyMessage",
+		Other: "There are no open janken games in this channel.",
+	}
+	createInvalidRoundsErrorMessage = &i18n.Message{
+		ID:    "createInvalidRoundsErrorMessage",
+		Other: "-rounds must be between 1 and {{.Max}}.",
+	}
+	createInvalidWinnersErrorMessage = &i18n.Message{
+		ID:    "createInvalidWinnersErrorMessage",
+		Other: "-winners must be 1 or more.",
+	}
+	createWinnersWithTypeErrorMessage = &i18n.Message{
+		ID:    "createWinnersWithTypeErrorMessage",
+		Other: "-winners can be used only with -type lottery.",
+	}
+	createInvalidMaxErrorMessage = &i18n.Message{
+		ID:    "createInvalidMaxErrorMessage",
+		Other: "-max must be 0 (no limit) or 2 or more.",
+	}
+	createAutoWithoutMaxErrorMessage = &i18n.Message{
+		ID:    "createAutoWithoutMaxErrorMessage",
+		Other: "-auto requires -max.",
+	}
+	createInvalidMinErrorMessage = &i18n.Message{
+		ID:    "createInvalidMinErrorMessage",
+		Other: "-min must be 2 or more and not more than -max.",
+	}
+	createDeadlineAndAtErrorMessage = &i18n.Message{
+		ID:    "createDeadlineAndAtErrorMessage",
+		Other: "Specify either -deadline or -at.",
+	}
+	createInvalidDeadlineErrorMessage = &i18n.Message{
+		ID:    "createInvalidDeadlineErrorMessage",
+		Other: "-deadline must be a duration like 15m or 1h30m.",
+	}
+	createInvalidAtErrorMessage = &i18n.Message{
+		ID:    "createInvalidAtErrorMessage",
+		Other: "-at must be a time like 17:00.",
+	}
+	createTitleTooLongErrorMessage = &i18n.Message{
+		ID:    "createTitleTooLongErrorMessage",
+		Other: "-title must be at most {{.Max}} characters.",
+	}
+	createAnonymousLiveErrorMessage = &i18n.Message{
+		ID:    "createAnonymousLiveErrorMessage",
+		Other: "-anonymous can't be used with -type live.",
+	}
+)
+
+const (
+	// maxTitleLength はタイトルの最大文字数
+	maxTitleLength = 100
+	// maxPurposeLength は目的の最大文字数
+	maxPurposeLength = 300
+)
+
+const (
+	// subcommandList はチャンネルの受付中のゲームを一覧表示するサブコマンド
+	subcommandList = "list"
 )
 
 type parsedArgs struct {
-	Language *string
+	Language   *string
+	HandSet    *string
+	NumWinners *int
+	// -winnersが指定された場合は当選者を選ぶゲームにする
+	Lottery bool
+	// 自動で結果を表示するまでの時間または時刻
+	Deadline *string
+	At       *string
+	// 最大参加人数と達したときに自動で結果を表示するか
+	MaxParticipants *int
+	AutoResult      *bool
+	// 結果を表示するのに必要な参加人数
+	MinParticipants *int
+	// 招待するユーザーのusername
+	Invited []string
+	// ゲームの種類(-typeの名前)と最大対戦回数
+	GameType  *string
+	MaxRounds *int
+	// タイトルと結果を表示するまで参加者を隠すか
+	Title     *string
+	Anonymous *bool
+	// 結果の表示方法．空の場合はプラグインの設定を使う
+	ResultPublishMode *string
 }
 
 // ExecuteCommand executes a command that has been previously registered via the RegisterCommand API.
@@ -50,26 +182,74 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 
 	siteURL := *p.ServerConfig.ServiceSettings.SiteURL
 
+	// サブコマンドがない場合はゲームを作成する
+	name := getSubcommand(args.Command)
+	if name == "" {
+		name = subcommandCreate
+	}
+	if sc := findSubcommand(name); sc != nil {
+		return sc.Execute(p, siteURL, args), nil
+	}
+	return p.newUsageErrorResponse(siteURL, newUsageError(usageUnknownSubcommandErrorMessage, map[string]interface{}{
+		"Subcommand": name,
+	})), nil
+}
+
+// executeCreateCommand はゲームを作成してpostを表示する
+func (p *Plugin) executeCreateCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
 	parsedArgs, err := p.parseArgs(args.Command)
 	if err != nil {
-		message := p.getCommandUsage()
-		if err.Error() != "" {
-			errmsg := fmt.Sprintf("Failed to parse arguments.: %s", err.Error())
-			message = fmt.Sprintf("%s\n\n%s", message, errmsg)
+		return p.newUsageErrorResponse(siteURL, err)
+	}
+
+	// 招待するユーザーのUserID
+	invited := []string{}
+	for _, username := range parsedArgs.Invited {
+		user, appErr := p.API.GetUserByUsername(username)
+		if appErr != nil {
+			l := p.getLocalizer(p.configuration.DefaultLanguage)
+			message := Localize(l, jankenUserNotFoundErrorMessage, map[string]interface{}{
+				"Username": "@" + username,
+			})
+			return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+		}
+		if !containsString(invited, user.Id) {
+			invited = append(invited, user.Id)
 		}
-		response := newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
-		return response, nil
 	}
 
-	game := newGame(&gameImpl1{})
+	// -typeを省略して-winnersを指定した場合は当選者を選ぶゲームにする
+	gameType := "gameImpl1"
+	if parsedArgs.Lottery {
+		gameType = "gameImplLottery"
+	}
+	if *parsedArgs.GameType != "" {
+		gameType = getGameTypeByOptionName(*parsedArgs.GameType)
+	}
+	game := newGame(newGameFuncMapping[gameType]())
 	game.Creator = args.UserId
+	game.Title = *parsedArgs.Title
+	game.Anonymous = *parsedArgs.Anonymous
+	game.ResultPublishMode = *parsedArgs.ResultPublishMode
+	game.MaxRounds = *parsedArgs.MaxRounds
+	game.TeamID = args.TeamId
+	game.ChannelID = args.ChannelId
 	game.Language = *parsedArgs.Language
-	err = p.store.jankenStore.Save(game)
-	if err != nil {
-		errmsg := fmt.Sprintf("Failed to store game data.: %s", err.Error())
-		response := newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
-		return response, nil
+	game.setHandSet(*parsedArgs.HandSet)
+	game.NumWinners = *parsedArgs.NumWinners
+	game.MaxParticipants = *parsedArgs.MaxParticipants
+	game.AutoResult = *parsedArgs.AutoResult
+	game.MinParticipants = *parsedArgs.MinParticipants
+	if len(invited) > 0 {
+		game.Invited = invited
+	}
+	if deadline := *parsedArgs.Deadline + *parsedArgs.At; deadline != "" {
+		game.Deadline, err = parseDeadline(deadline, time.Now().In(p.getUserLocation(args.UserId)))
+		if err != nil {
+			return p.newUsageErrorResponse(siteURL, newUsageError(createInvalidDeadlineErrorMessage, nil))
+		}
 	}
+	game.commitSeed()
 
 	if !p.isValidLanguage(game.Language) {
 		defaultLanguageStr := p.configuration.DefaultLanguage
@@ -80,9 +260,145 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		game.Language = defaultLanguageStr
 	}
 
-	attachments := p.getGameAttachments(siteURL, PluginID, game)
-	response := newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, "", attachments)
-	return response, nil
+	err = p.store.jankenStore.Save(game)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to store game data.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+
+	if err = p.createGamePost(siteURL, args, game); err != nil {
+		p.store.jankenStore.Delete(game.ID)
+		errmsg := fmt.Sprintf("Failed to create the post.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+
+	return &model.CommandResponse{}
+}
+
+/*
+createGamePost はゲームのpostを作成してpostのIDをゲームに保存する．
+postのIDは一覧からpostにリンクするために使う
+*/
+func (p *Plugin) createGamePost(siteURL string, args *model.CommandArgs, g *game) error {
+	post := newGamePost(siteURL, args.UserId, args.ChannelId)
+	p.attachGameToPost(post, siteURL, PluginID, g)
+	post, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return appErr
+	}
+	g.PostID = post.Id
+
+	_, err := p.store.jankenStore.Update(g.ID, func(stored *game) error {
+		stored.PostID = post.Id
+		return nil
+	})
+	if err != nil {
+		p.API.LogWarn("failed to save the post id", "id", g.ID, "error", err.Error())
+	}
+	return nil
+}
+
+/*
+getSubcommand はコマンドの最初の引数を返す．
+引数がない場合やオプションまたは@usernameで始まる場合は空文字を返す
+*/
+func getSubcommand(command string) string {
+	args, err := shellquote.Split(command)
+	if err != nil || len(args) < 2 || strings.HasPrefix(args[1], "-") || strings.HasPrefix(args[1], "@") {
+		return ""
+	}
+	return args[1]
+}
+
+// executeListCommand はチャンネルの受付中のゲームを作成者・参加人数・経過時間・postへのリンクと一緒に表示する
+func (p *Plugin) executeListCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	games, err := p.store.jankenStore.ListByChannel(args.ChannelId)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get games.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+	if len(games) == 0 {
+		message := Localize(l, jankenListEmptyMessage, nil)
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	teamName := ""
+	if team, appErr := p.API.GetTeam(args.TeamId); appErr == nil {
+		teamName = team.Name
+	}
+
+	now := model.GetMillis()
+	lines := []string{
+		fmt.Sprintf("#### %s", Localize(l, jankenListTitle, nil)),
+		Localize(l, jankenListHeader, nil),
+	}
+	for _, game := range games {
+		link := ""
+		if game.PostID != "" {
+			link = fmt.Sprintf("[%s](%s/%s/pl/%s)", Localize(l, jankenListLinkLabel, nil), siteURL, teamName, game.PostID)
+		}
+		lines = append(lines, fmt.Sprintf("| %s | @%s | %d | %s | %s |",
+			game.getShortID(),
+			p.getUsername(game.Creator),
+			len(game.Participants),
+			formatAge(now-game.CreatedAt),
+			link,
+		))
+	}
+	message := strings.Join(lines, "\n")
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+/*
+getCommandTargetUser はサブコマンドの引数の@usernameのユーザーを返す．
+引数がない場合はコマンドを実行したユーザーを返す
+Returns:
+    *model.User: ユーザー
+    *model.CommandResponse: 引数が不正な場合やユーザーが見つからない場合に返す応答
+*/
+func (p *Plugin) getCommandTargetUser(siteURL string, args *model.CommandArgs) (*model.User, *model.CommandResponse) {
+	fields := strings.Fields(args.Command)
+	if len(fields) > 3 {
+		return nil, p.newUsageErrorResponse(siteURL, fmt.Errorf("Invalid arguments: %s", fields[3:]))
+	}
+
+	username := args.UserId
+	user, appErr := p.API.GetUser(args.UserId)
+	if len(fields) == 3 {
+		username = fields[2]
+		user, appErr = p.API.GetUserByUsername(strings.TrimPrefix(username, "@"))
+	}
+	if appErr != nil {
+		l := p.getLocalizer(p.configuration.DefaultLanguage)
+		message := Localize(l, jankenUserNotFoundErrorMessage, map[string]interface{}{
+			"Username": username,
+		})
+		return nil, newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+	return user, nil
+}
+
+/*
+formatAge はミリ秒の経過時間を短い文字列にする．
+Args:
+    millis: 経過時間(ミリ秒)
+Returns:
+    string: 1時間未満は"5m"，1日未満は"3h 5m"，それ以上は"2d 3h"
+*/
+func formatAge(millis int64) string {
+	minutes := millis / 1000 / 60
+	hours := minutes / 60
+	days := hours / 24
+	switch {
+	case hours < 1:
+		return fmt.Sprintf("%dm", minutes)
+	case days < 1:
+		return fmt.Sprintf("%dh %dm", hours, minutes%60)
+	default:
+		return fmt.Sprintf("%dd %dh", days, hours%24)
+	}
 }
 
 func (p *Plugin) isValidLanguage(language string) bool {
@@ -94,11 +410,28 @@ func (p *Plugin) isValidLanguage(language string) bool {
 	return false
 }
 
+/*
+parseArgs はcreateサブコマンドのオプションと招待する@usernameを返す．
+サブコマンドを省略した場合もcreateサブコマンドとして扱う
+*/
 func (p *Plugin) parseArgs(command string) (*parsedArgs, error) {
 	parsedArgs := &parsedArgs{Language: &p.configuration.DefaultLanguage}
 
-	fs := flag.NewFlagSet("janken", flag.ContinueOnError)
+	fs := flag.NewFlagSet(subcommandCreate, flag.ContinueOnError)
+	fs.SetOutput(&strings.Builder{})
 	parsedArgs.Language = fs.String("l", "", `Language option. Available values are "en" or "ja".`)
+	parsedArgs.HandSet = fs.String("hands", defaultHandSetName, `Hands option. Available values are "rps" or "rpsls".`)
+	parsedArgs.GameType = fs.String("type", "", `Game type option. Available values are "ranking", "bracket", "league", "loser", "lottery", "team" or "live".`)
+	parsedArgs.MaxRounds = fs.Int("rounds", defaultMaxRounds, `Max rounds option. Janken is repeated up to this number of times in a match.`)
+	parsedArgs.NumWinners = fs.Int("winners", defaultNumWinners, `Number of winners option. The game picks this number of winners.`)
+	parsedArgs.Deadline = fs.String("deadline", "", `Deadline option. The result is shown automatically after this duration like "15m".`)
+	parsedArgs.At = fs.String("at", "", `Deadline option. The result is shown automatically at this time like "17:00".`)
+	parsedArgs.MaxParticipants = fs.Int("max", 0, `Max participants option. No more users can join after this number of users have joined.`)
+	parsedArgs.AutoResult = fs.Bool("auto", false, `Auto result option. The result is shown automatically when the number of participants reaches the max participants.`)
+	parsedArgs.MinParticipants = fs.Int("min", defaultMinParticipants, `Min participants option. The result can be shown after this number of users have joined.`)
+	parsedArgs.Title = fs.String("title", "", `Title option. The title is shown in the game post and the result.`)
+	parsedArgs.Anonymous = fs.Bool("anonymous", false, `Anonymous option. The participants are hidden until the result is shown.`)
+	parsedArgs.ResultPublishMode = fs.String("publish", "", `Result option. Available values are "inplace", "post" or "thread".`)
 	flag.ErrHelp = errors.New("")
 
 	// split command string like shell arguments
@@ -106,13 +439,108 @@ func (p *Plugin) parseArgs(command string) (*parsedArgs, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := fs.Parse(args[1:]); err != nil {
-		return nil, err
+	args = args[1:]
+	if len(args) > 0 && args[0] == subcommandCreate {
+		args = args[1:]
+	}
+
+	// @usernameは招待するユーザーとしてオプションより前でも後でも指定できる
+	options := []string{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") && len(arg) > 1 {
+			parsedArgs.Invited = append(parsedArgs.Invited, strings.TrimPrefix(arg, "@"))
+		} else {
+			options = append(options, arg)
+		}
+	}
+	if err := fs.Parse(options); err != nil {
+		return nil, newFlagError(err)
 	}
 
 	positionalArgs := fs.Args()
 	if len(positionalArgs) > 0 {
-		return nil, fmt.Errorf("Invalid arguments: %s", positionalArgs)
+		return nil, newUsageError(usageUnexpectedArgumentsErrorMessage, map[string]interface{}{
+			"Arguments": strings.Join(positionalArgs, " "),
+		})
+	}
+
+	if !isValidHandSet(*parsedArgs.HandSet) {
+		return nil, newUsageError(usageInvalidChoiceErrorMessage, map[string]interface{}{
+			"Value":  *parsedArgs.HandSet,
+			"Option": "hands",
+			"Values": strings.Join(handSetNames, ", "),
+		})
+	}
+
+	if *parsedArgs.GameType != "" && getGameTypeByOptionName(*parsedArgs.GameType) == "" {
+		return nil, newUsageError(usageInvalidChoiceErrorMessage, map[string]interface{}{
+			"Value":  *parsedArgs.GameType,
+			"Option": "type",
+			"Values": strings.Join(gameTypeOptionNames, ", "),
+		})
+	}
+
+	if *parsedArgs.MaxRounds < 1 || *parsedArgs.MaxRounds > maxHands {
+		return nil, newUsageError(createInvalidRoundsErrorMessage, map[string]interface{}{
+			"Max": maxHands,
+		})
+	}
+
+	if *parsedArgs.NumWinners < 1 {
+		return nil, newUsageError(createInvalidWinnersErrorMessage, nil)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "winners" {
+			parsedArgs.Lottery = true
+		}
+	})
+	// -winnersは当選者を選ぶゲームでのみ指定できる
+	if parsedArgs.Lottery && *parsedArgs.GameType != "" && getGameTypeByOptionName(*parsedArgs.GameType) != "gameImplLottery" {
+		return nil, newUsageError(createWinnersWithTypeErrorMessage, nil)
+	}
+
+	if *parsedArgs.MaxParticipants < 0 || *parsedArgs.MaxParticipants == 1 {
+		return nil, newUsageError(createInvalidMaxErrorMessage, nil)
+	}
+	if *parsedArgs.AutoResult && *parsedArgs.MaxParticipants == 0 {
+		return nil, newUsageError(createAutoWithoutMaxErrorMessage, nil)
+	}
+	if *parsedArgs.MinParticipants < defaultMinParticipants ||
+		(*parsedArgs.MaxParticipants > 0 && *parsedArgs.MinParticipants > *parsedArgs.MaxParticipants) {
+		return nil, newUsageError(createInvalidMinErrorMessage, nil)
+	}
+
+	if *parsedArgs.Deadline != "" && *parsedArgs.At != "" {
+		return nil, newUsageError(createDeadlineAndAtErrorMessage, nil)
+	}
+	if *parsedArgs.Deadline != "" {
+		if d, err := time.ParseDuration(*parsedArgs.Deadline); err != nil || d <= 0 {
+			return nil, newUsageError(createInvalidDeadlineErrorMessage, nil)
+		}
+	}
+	if *parsedArgs.At != "" {
+		if _, err := time.Parse(deadlineTimeLayout, *parsedArgs.At); err != nil {
+			return nil, newUsageError(createInvalidAtErrorMessage, nil)
+		}
+	}
+
+	if *parsedArgs.ResultPublishMode != "" && !isValidResultPublishMode(*parsedArgs.ResultPublishMode) {
+		return nil, newUsageError(usageInvalidChoiceErrorMessage, map[string]interface{}{
+			"Value":  *parsedArgs.ResultPublishMode,
+			"Option": "publish",
+			"Values": strings.Join(resultPublishModes, ", "),
+		})
+	}
+
+	*parsedArgs.Title = strings.TrimSpace(*parsedArgs.Title)
+	if utf8.RuneCountInString(*parsedArgs.Title) > maxTitleLength {
+		return nil, newUsageError(createTitleTooLongErrorMessage, map[string]interface{}{
+			"Max": maxTitleLength,
+		})
+	}
+	// ライブ対戦は各回の対戦者を表示するので参加者を隠せない
+	if *parsedArgs.Anonymous && getGameTypeByOptionName(*parsedArgs.GameType) == "gameImplLive" {
+		return nil, newUsageError(createAnonymousLiveErrorMessage, nil)
 	}
 
 	return parsedArgs, nil
@@ -128,6 +556,9 @@ func (p *Plugin) getGameAttachments(siteURL, pluginID string, game *game) []*mod
 			continue
 		}
 		participants[i] = user.Username
+		if pp.Team != "" {
+			participants[i] = fmt.Sprintf("%s (%s)", user.Username, pp.Team)
+		}
 	}
 	// カンマ区切りの文字列に変換
 	participantsStr := strings.Join(participants, ", ")
@@ -149,17 +580,60 @@ func (p *Plugin) getGameAttachments(siteURL, pluginID string, game *game) []*mod
 		"ID":       game.getShortID(),
 		"Username": username,
 	})
+	if game.Title != "" {
+		title = Localize(l, jankenGameCustomTitle, map[string]interface{}{
+			"Title":    game.Title,
+			"ID":       game.getShortID(),
+			"Username": username,
+		})
+	}
+	// 匿名のゲームは結果を表示するまで参加者を表示しない
+	if game.Anonymous {
+		participantsStr = Localize(l, jankenGameAnonymousParticipants, nil)
+	}
+	// 最大参加人数がある場合は"3/5"のように表示する
+	participantsNum := strconv.Itoa(len(participants))
+	if game.MaxParticipants > 0 {
+		participantsNum = fmt.Sprintf("%d/%d", len(participants), game.MaxParticipants)
+	}
 	description := Localize(l, jankenGameDescription, map[string]interface{}{
-		"participantsNum": len(participants),
+		"participantsNum": participantsNum,
 		"participantsStr": participantsStr,
 	})
+	if game.Purpose != "" {
+		description = Localize(l, jankenGamePurposeDescription, map[string]interface{}{
+			"Purpose": game.Purpose,
+		}) + "\n" + description
+	}
+	if len(game.Outcomes) > 0 {
+		description += "\n" + getOutcomesDescription(l, game.Outcomes)
+	}
+	if len(game.Invited) > 0 {
+		description += "\n" + p.getInvitedDescription(l, game)
+	}
+	if game.Deadline > 0 {
+		description += "\n" + Localize(l, jankenGameDeadlineDescription, map[string]interface{}{
+			"Deadline": formatDeadline(game.Deadline, userLocation(user)),
+		})
+	}
 	joinButtonLabel := Localize(l, jankenGameJoinButtonLabel, nil)
 	configButtonLabel := Localize(l, jankenGameConfigButtonLabel, nil)
 	resultButtonLabel := Localize(l, jankenGameResultButtonLabel, nil)
+	footer := Localize(l, jankenGameSeedCommitmentFooter, map[string]interface{}{
+		"SeedCommitment": game.SeedCommitment,
+	})
+
+	// ライブ対戦
+	if live, ok := game.Impl.(*gameImplLive); ok {
+		attachments := p.getLiveGameAttachments(siteURL, pluginID, game, live, title, description)
+		attachments[0].Footer = footer
+		return attachments
+	}
 
 	attachments := []*model.SlackAttachment{{
-		Title: title,
-		Text:  description,
+		Title:  title,
+		Text:   description,
+		Footer: footer,
 		Actions: []*model.PostAction{
 			{
 				Name: joinButtonLabel,
@@ -190,6 +664,122 @@ func (p *Plugin) getGameAttachments(siteURL, pluginID string, game *game) []*mod
 	return attachments
 }
 
+/*
+getInvitedDescription は招待したユーザーを手を登録したかどうかと一緒に返す．
+登録済みのユーザーには:white_check_mark:，未登録のユーザーには:hourglass:を付ける．
+匿名のゲームは誰が登録したかを表示しない
+*/
+func (p *Plugin) getInvitedDescription(l *i18n.Localizer, game *game) string {
+	users := make([]string, 0, len(game.Invited))
+	registered := 0
+	for _, userID := range game.Invited {
+		icon := ":hourglass:"
+		if game.GetParticipant(userID) != nil {
+			icon = ":white_check_mark:"
+			registered++
+		}
+		if game.Anonymous {
+			users = append(users, "@"+p.getUsername(userID))
+			continue
+		}
+		users = append(users, fmt.Sprintf("%s @%s", icon, p.getUsername(userID)))
+	}
+	return Localize(l, jankenGameInvitedDescription, map[string]interface{}{
+		"Registered": registered,
+		"Invited":    len(game.Invited),
+		"Users":      strings.Join(users, ", "),
+	})
+}
+
+/*
+getLiveGameAttachments はライブ対戦のAttachmentを返す．
+開始前は参加と開始のボタン，対戦中は現在のジャンケンの状況と手のボタンを表示する
+*/
+func (p *Plugin) getLiveGameAttachments(siteURL, pluginID string, game *game, live *gameImplLive, title, description string) []*model.SlackAttachment {
+	l := p.getLocalizer(game.Language)
+
+	group := live.currentGroup()
+	if group == nil {
+		return []*model.SlackAttachment{{
+			Title: title,
+			Text:  description,
+			Actions: []*model.PostAction{
+				{
+					Name: Localize(l, jankenGameJoinButtonLabel, nil),
+					Type: model.POST_ACTION_TYPE_BUTTON,
+					Integration: &model.PostActionIntegration{
+						URL:     fmt.Sprintf("%s/plugins/%s/api/v1/janken/join", siteURL, pluginID),
+						Context: map[string]interface{}{"id": game.ID},
+					},
+				},
+				{
+					Name: Localize(l, jankenGameConfigButtonLabel, nil),
+					Type: model.POST_ACTION_TYPE_BUTTON,
+					Integration: &model.PostActionIntegration{
+						URL:     fmt.Sprintf("%s/plugins/%s/api/v1/janken/config", siteURL, pluginID),
+						Context: map[string]interface{}{"id": game.ID},
+					},
+				},
+				{
+					Name: Localize(l, jankenGameStartButtonLabel, nil),
+					Type: model.POST_ACTION_TYPE_BUTTON,
+					Integration: &model.PostActionIntegration{
+						URL:     fmt.Sprintf("%s/plugins/%s/api/v1/janken/live/start", siteURL, pluginID),
+						Context: map[string]interface{}{"id": game.ID},
+					},
+				},
+			},
+		}}
+	}
+
+	members := make([]string, 0, len(group.Members))
+	for _, userID := range group.Members {
+		members = append(members, fmt.Sprintf("@%s", p.getUsername(userID)))
+	}
+	waiting := []string{}
+	for _, userID := range live.waitingFor(game) {
+		waiting = append(waiting, fmt.Sprintf("@%s", p.getUsername(userID)))
+	}
+	text := Localize(l, jankenGameLiveDescription, map[string]interface{}{
+		"Round":   group.Round + 1,
+		"Members": strings.Join(members, ", "),
+		"Waiting": strings.Join(waiting, ", "),
+		"Timeout": liveRoundTimeoutSeconds,
+	})
+
+	// 手のボタン
+	hs := game.getHandSet()
+	actions := []*model.PostAction{}
+	for _, h := range hs.Hands {
+		actions = append(actions, &model.PostAction{
+			Name: Localize(l, hs.Messages[h], nil),
+			Type: model.POST_ACTION_TYPE_BUTTON,
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("%s/plugins/%s/api/v1/janken/live/hand", siteURL, pluginID),
+				Context: map[string]interface{}{
+					"id":   game.ID,
+					"hand": h,
+				},
+			},
+		})
+	}
+	// 結果ボタンは残りのジャンケンをランダムな手で終わらせる
+	actions = append(actions, &model.PostAction{
+		Name: Localize(l, jankenGameResultButtonLabel, nil),
+		Type: model.POST_ACTION_TYPE_BUTTON,
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("%s/plugins/%s/api/v1/janken/result", siteURL, pluginID),
+			Context: map[string]interface{}{"id": game.ID},
+		},
+	})
+
+	return []*model.SlackAttachment{{
+		Title:   title,
+		Text:    text,
+		Actions: actions,
+	}}
+}
+
 func (p *Plugin) attachGameToPost(post *model.Post, siteURL, pluginID string, game *game) *model.Post {
 	attachments := p.getGameAttachments(siteURL, pluginID, game)
 
@@ -197,14 +787,16 @@ func (p *Plugin) attachGameToPost(post *model.Post, siteURL, pluginID string, ga
 	return post
 }
 
-func (p *Plugin) getCommandUsage() string {
-	template := `
-	Usage: /%s [-l en|ja]
-
-	Optional arguments
-	  -l en|ja   Language
-	`
-	return fmt.Sprintf(template, p.configuration.Trigger)
+// newGamePost はコマンドの応答と同じ見た目のゲームのpostを返す
+func newGamePost(siteURL, userID, channelID string) *model.Post {
+	post := &model.Post{
+		UserId:    userID,
+		ChannelId: channelID,
+	}
+	post.AddProp("from_webhook", "true")
+	post.AddProp("override_username", commandResponseUsername)
+	post.AddProp("override_icon_url", fmt.Sprintf("%s/plugins/%s/%s", siteURL, PluginID, iconFilename))
+	return post
 }
 
 func newCommandResponse(siteURL, responseType, text string, attachments []*model.SlackAttachment) *model.CommandResponse {
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubcommand(t *testing.T) {
+	for name, test := range map[string]struct {
+		Command  string
+		Expected string
+	}{
+		"list":            {Command: "/janken list", Expected: "list"},
+		"no arguments":    {Command: "/janken", Expected: ""},
+		"options":         {Command: "/janken -l ja", Expected: ""},
+		"invalid quoting": {Command: `/janken "list`, Expected: ""},
+		"invited users":   {Command: "/janken @alice", Expected: ""},
+		"create":          {Command: "/janken create -max 3", Expected: "create"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, getSubcommand(test.Command))
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	minute := int64(60 * 1000)
+	for name, test := range map[string]struct {
+		Millis   int64
+		Expected string
+	}{
+		"minutes": {Millis: 5 * minute, Expected: "5m"},
+		"hours":   {Millis: 185 * minute, Expected: "3h 5m"},
+		"days":    {Millis: (2*24 + 3) * 60 * minute, Expected: "2d 3h"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, formatAge(test.Millis))
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	for name, test := range map[string]struct {
+		Command                 string
+		ExpectedMaxParticipants int
+		ExpectedAutoResult      bool
+		ExpectedDeadline        string
+		ExpectedAt              string
+		ExpectedMinParticipants int
+		ExpectedInvited         []string
+		ExpectedGameType        string
+		ExpectedMaxRounds       int
+		ExpectedTitle           string
+		ExpectedAnonymous       bool
+		ShouldError             bool
+	}{
+		"no options":              {Command: "/janken"},
+		"max participants":        {Command: "/janken -max 5", ExpectedMaxParticipants: 5},
+		"auto result":             {Command: "/janken -max 5 -auto", ExpectedMaxParticipants: 5, ExpectedAutoResult: true},
+		"auto result without max": {Command: "/janken -auto", ShouldError: true},
+		"max participants of 1":   {Command: "/janken -max 1", ShouldError: true},
+		"negative max":            {Command: "/janken -max -2", ShouldError: true},
+		"deadline":                {Command: "/janken -deadline 15m", ExpectedDeadline: "15m"},
+		"at":                      {Command: "/janken -at 17:00", ExpectedAt: "17:00"},
+		"invalid deadline":        {Command: "/janken -deadline 17:00", ShouldError: true},
+		"invalid at":              {Command: "/janken -at 15m", ShouldError: true},
+		"deadline and at":         {Command: "/janken -deadline 15m -at 17:00", ShouldError: true},
+		"create subcommand":       {Command: "/janken create -max 5", ExpectedMaxParticipants: 5},
+		"min participants":        {Command: "/janken -min 3", ExpectedMinParticipants: 3},
+		"min participants of 1":   {Command: "/janken -min 1", ShouldError: true},
+		"min more than max":       {Command: "/janken -min 4 -max 3", ShouldError: true},
+		"invited users":           {Command: "/janken -max 3 @alice @bob", ExpectedMaxParticipants: 3, ExpectedInvited: []string{"alice", "bob"}},
+		"unknown argument":        {Command: "/janken alice", ShouldError: true},
+		"game type":               {Command: "/janken -type bracket", ExpectedGameType: "bracket"},
+		"invalid game type":       {Command: "/janken -type gameImpl1", ShouldError: true},
+		"winners with lottery":    {Command: "/janken -type lottery -winners 2", ExpectedGameType: "lottery"},
+		"winners with other type": {Command: "/janken -type league -winners 2", ShouldError: true},
+		"rounds":                  {Command: "/janken -rounds 3", ExpectedMaxRounds: 3},
+		"rounds of 0":             {Command: "/janken -rounds 0", ShouldError: true},
+		"too many rounds":         {Command: "/janken -rounds 11", ShouldError: true},
+		"rounds not a number":     {Command: "/janken -rounds three", ShouldError: true},
+		"title":                   {Command: `/janken -title " Who buys coffee? "`, ExpectedTitle: "Who buys coffee?"},
+		"too long title":          {Command: "/janken -title " + strings.Repeat("a", maxTitleLength+1), ShouldError: true},
+		"anonymous":               {Command: "/janken -anonymous -type team", ExpectedGameType: "team", ExpectedAnonymous: true},
+		"anonymous live game":     {Command: "/janken -anonymous -type live", ShouldError: true},
+		"unknown option":          {Command: "/janken -foo", ShouldError: true},
+		"missing value":           {Command: "/janken -max", ShouldError: true},
+		"invalid publish":         {Command: "/janken -publish email", ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p := &Plugin{configuration: &pluginConfig{DefaultLanguage: "en"}}
+			parsedArgs, err := p.parseArgs(test.Command)
+
+			if test.ShouldError {
+				// flagパッケージのエラーも翻訳できるエラーにする
+				assert.IsType(&usageError{}, err)
+				return
+			}
+			assert.Nil(err)
+			if test.ExpectedMaxRounds == 0 {
+				test.ExpectedMaxRounds = defaultMaxRounds
+			}
+			assert.Equal(test.ExpectedGameType, *parsedArgs.GameType)
+			assert.Equal(test.ExpectedMaxRounds, *parsedArgs.MaxRounds)
+			assert.Equal(test.ExpectedTitle, *parsedArgs.Title)
+			assert.Equal(test.ExpectedAnonymous, *parsedArgs.Anonymous)
+			assert.Equal(test.ExpectedMaxParticipants, *parsedArgs.MaxParticipants)
+			assert.Equal(test.ExpectedAutoResult, *parsedArgs.AutoResult)
+			assert.Equal(test.ExpectedDeadline, *parsedArgs.Deadline)
+			assert.Equal(test.ExpectedAt, *parsedArgs.At)
+			if test.ExpectedMinParticipants == 0 {
+				test.ExpectedMinParticipants = defaultMinParticipants
+			}
+			assert.Equal(test.ExpectedMinParticipants, *parsedArgs.MinParticipants)
+			assert.Equal(test.ExpectedInvited, parsedArgs.Invited)
+		})
+	}
+}
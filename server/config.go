@@ -1,13 +1,24 @@
 package main
 
 import (
+	"strconv"
+
 	"github.com/pkg/errors"
 	"golang.org/x/text/language"
 )
 
+// defaultHistoryRetentionDays は結果を表示したゲームを保存する日数の初期値
+const defaultHistoryRetentionDays = 90
+
 type pluginConfig struct {
 	Trigger         string
 	DefaultLanguage string
+	// 結果を表示したゲームを保存する日数．0の場合は削除しない
+	HistoryRetentionDays string
+	// ランキングの順位を決めるスコア
+	LeaderboardScore string
+	// 結果の表示方法の初期値
+	ResultPublishMode string
 }
 
 func (c *pluginConfig) GetDefaultLanguageTag() language.Tag {
@@ -23,6 +34,39 @@ func (c *pluginConfig) GetDefaultLanguageTag() language.Tag {
 	return t
 }
 
+/*
+GetHistoryExpireInSeconds は結果を表示したゲームを保存する秒数を返す．
+Returns:
+    int64: 保存する秒数．0の場合は削除しない．設定が数値でない場合は初期値の日数を使う
+*/
+func (c *pluginConfig) GetHistoryExpireInSeconds() int64 {
+	days := defaultHistoryRetentionDays
+	if c != nil {
+		if d, err := strconv.Atoi(c.HistoryRetentionDays); err == nil && d >= 0 {
+			days = d
+		}
+	}
+	return int64(days) * 24 * 60 * 60
+}
+
+// GetLeaderboardScore はランキングの順位を決めるスコアを返す．設定が不正な場合は初期値を使う
+func (c *pluginConfig) GetLeaderboardScore() string {
+	if c != nil {
+		if _, ok := leaderboardScoreMessages[c.LeaderboardScore]; ok {
+			return c.LeaderboardScore
+		}
+	}
+	return defaultLeaderboardScore
+}
+
+// GetResultPublishMode は結果の表示方法の初期値を返す．設定が不正な場合はゲームのpostに追加する
+func (c *pluginConfig) GetResultPublishMode() string {
+	if c != nil && isValidResultPublishMode(c.ResultPublishMode) {
+		return c.ResultPublishMode
+	}
+	return defaultResultPublishMode
+}
+
 // OnConfigurationChange loads the plugin configuration
 func (p *Plugin) OnConfigurationChange() error {
 	p.ServerConfig = p.API.GetConfig()
@@ -38,18 +82,21 @@ func (p *Plugin) OnConfigurationChange() error {
 		}
 	}
 
-	if err := p.API.RegisterCommand(getCommand(*p.ServerConfig.ServiceSettings.SiteURL, c.Trigger)); err != nil {
-		return errors.Wrap(err, "failed to register new command")
-	}
-
 	p.setConfiguration(c)
 
+	// オートコンプリートの説明を翻訳するため，コマンドを登録する前にバンドルを読み込む
 	bundle, err := p.InitBundle()
 	if err != nil {
 		return err
 	}
 	p.bundle = bundle
 
+	command := getCommand(*p.ServerConfig.ServiceSettings.SiteURL, c.Trigger)
+	command.AutocompleteData = p.getAutocompleteData(p.getLocalizer(c.DefaultLanguage), c.Trigger)
+	if err := p.API.RegisterCommand(command); err != nil {
+		return errors.Wrap(err, "failed to register new command")
+	}
+
 	return nil
 }
 
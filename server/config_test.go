@@ -134,4 +134,55 @@ func TestPluginConfig(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("GetHistoryExpireInSeconds", func(t *testing.T) {
+		day := int64(24 * 60 * 60)
+		for name, test := range map[string]struct {
+			Configuration *pluginConfig
+			Expected      int64
+		}{
+			"days":     {Configuration: &pluginConfig{HistoryRetentionDays: "7"}, Expected: 7 * day},
+			"forever":  {Configuration: &pluginConfig{HistoryRetentionDays: "0"}, Expected: 0},
+			"empty":    {Configuration: &pluginConfig{}, Expected: defaultHistoryRetentionDays * day},
+			"invalid":  {Configuration: &pluginConfig{HistoryRetentionDays: "abc"}, Expected: defaultHistoryRetentionDays * day},
+			"negative": {Configuration: &pluginConfig{HistoryRetentionDays: "-1"}, Expected: defaultHistoryRetentionDays * day},
+			"nil":      {Configuration: nil, Expected: defaultHistoryRetentionDays * day},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, test.Expected, test.Configuration.GetHistoryExpireInSeconds())
+			})
+		}
+	})
+
+	t.Run("GetLeaderboardScore", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			Configuration *pluginConfig
+			Expected      string
+		}{
+			"elo":     {Configuration: &pluginConfig{LeaderboardScore: "elo"}, Expected: leaderboardScoreElo},
+			"empty":   {Configuration: &pluginConfig{}, Expected: defaultLeaderboardScore},
+			"invalid": {Configuration: &pluginConfig{LeaderboardScore: "luck"}, Expected: defaultLeaderboardScore},
+			"nil":     {Configuration: nil, Expected: defaultLeaderboardScore},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, test.Expected, test.Configuration.GetLeaderboardScore())
+			})
+		}
+	})
+
+	t.Run("GetResultPublishMode", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			Configuration *pluginConfig
+			Expected      string
+		}{
+			"thread":  {Configuration: &pluginConfig{ResultPublishMode: "thread"}, Expected: resultPublishThread},
+			"empty":   {Configuration: &pluginConfig{}, Expected: defaultResultPublishMode},
+			"invalid": {Configuration: &pluginConfig{ResultPublishMode: "email"}, Expected: defaultResultPublishMode},
+			"nil":     {Configuration: nil, Expected: defaultResultPublishMode},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, test.Expected, test.Configuration.GetResultPublishMode())
+			})
+		}
+	})
 }
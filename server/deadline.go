@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// deadlineCheckInterval は締め切りを過ぎたゲームと時間切れになったライブ対戦のジャンケンを探す間隔
+	deadlineCheckInterval = 10 * time.Second
+	// deadlineLockKey はクラスタ内の1台のサーバーだけが締め切りを処理するためのロックのキー
+	deadlineLockKey = "janken_lock_deadlines"
+	// deadlineLockExpireInSeconds はロックを解除せずにサーバーが停止した場合にロックが外れるまでの秒数
+	deadlineLockExpireInSeconds int64 = 300
+
+	// 締め切りに指定できる時刻と日時の書式
+	deadlineTimeLayout     = "15:04"
+	deadlineDateTimeLayout = "2006-01-02 15:04"
+	// 締め切りを表示する書式
+	deadlineDisplayLayout = "2006-01-02 15:04 MST"
+)
+
+// errNotDue は締め切りを過ぎていないゲームの結果を表示しようとした場合のエラー
+var errNotDue = errors.New("the deadline has not passed")
+
+var (
+	jankenGameDeadlineDescription = &i18n.Message{
+		ID:    "gameDeadlineDescription",
+		Other: "The result will be shown automatically at {{.Deadline}}.",
+	}
+	deadlinePassedMessage = &i18n.Message{
+		ID:    "DeadlinePassedMessage",
+		Other: "The deadline has passed.",
+	}
+	deadlineNotEnoughParticipantsMessage = &i18n.Message{
+		ID:    "DeadlineNotEnoughParticipantsMessage",
+		Other: "This janken game was closed because less than {{.Min}} participants joined before the deadline.",
+	}
+)
+
+/*
+parseDeadline は締め切りの文字列をミリ秒の日時に変換する．
+Args:
+    value: "15m"のような現在からの時間，"17:00"のような時刻，または"2020-12-24 17:00"のような日時
+    now: 現在の日時．時刻と日時はnowのタイムゾーンで解釈する
+Returns:
+    int64: 締め切りの日時(ミリ秒)．valueが空の場合は0
+    error: 書式が正しくない場合や締め切りが過去の場合
+*/
+func parseDeadline(value string, now time.Time) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return 0, fmt.Errorf("Invalid deadline: %s", value)
+		}
+		return model.GetMillisForTime(now.Add(d)), nil
+	}
+
+	// 時刻は次にその時刻になる日時にする
+	if t, err := time.ParseInLocation(deadlineTimeLayout, value, now.Location()); err == nil {
+		deadline := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !deadline.After(now) {
+			deadline = deadline.AddDate(0, 0, 1)
+		}
+		return model.GetMillisForTime(deadline), nil
+	}
+
+	if t, err := time.ParseInLocation(deadlineDateTimeLayout, value, now.Location()); err == nil {
+		if !t.After(now) {
+			return 0, fmt.Errorf("Deadline is in the past: %s", value)
+		}
+		return model.GetMillisForTime(t), nil
+	}
+
+	return 0, fmt.Errorf("Invalid deadline: %s", value)
+}
+
+// formatDeadline はミリ秒の締め切りを指定したタイムゾーンの日時にする
+func formatDeadline(deadline int64, location *time.Location) string {
+	return time.Unix(0, deadline*int64(time.Millisecond)).In(location).Format(deadlineDisplayLayout)
+}
+
+/*
+formatDeadlineInput はミリ秒の締め切りをダイアログで入力する書式の日時にする．
+締め切りがない場合は空文字
+*/
+func formatDeadlineInput(deadline int64, location *time.Location) string {
+	if deadline == 0 {
+		return ""
+	}
+	return time.Unix(0, deadline*int64(time.Millisecond)).In(location).Format(deadlineDateTimeLayout)
+}
+
+/*
+userLocation はユーザーが設定したタイムゾーンを返す．
+ユーザーがnilの場合やタイムゾーンを読み込めない場合はサーバーのタイムゾーンを返す
+*/
+func userLocation(user *model.User) *time.Location {
+	if user == nil {
+		return time.Local
+	}
+	location, err := time.LoadLocation(user.GetPreferredTimezone())
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// getUserLocation は指定したuserIDのユーザーが設定したタイムゾーンを返す
+func (p *Plugin) getUserLocation(userID string) *time.Location {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return time.Local
+	}
+	return userLocation(user)
+}
+
+// deadlineJob は締め切りを過ぎたゲームの結果を定期的に表示する
+type deadlineJob struct {
+	plugin *Plugin
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// startDeadlineJob は締め切りを過ぎたゲームを定期的に処理するgoroutineを開始する
+func (p *Plugin) startDeadlineJob() *deadlineJob {
+	j := &deadlineJob{
+		plugin: p,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go j.run()
+	return j
+}
+
+func (j *deadlineJob) run() {
+	defer close(j.done)
+	ticker := time.NewTicker(deadlineCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			j.plugin.resolveDueGames()
+		}
+	}
+}
+
+// close は処理中のゲームが終わるのを待ってgoroutineを停止する
+func (j *deadlineJob) close() {
+	close(j.stop)
+	<-j.done
+}
+
+/*
+resolveDueGames は締め切りを過ぎたゲームの結果を表示し，時間切れになったライブ対戦のジャンケンを進める．
+クラスタの各サーバーで実行されるが，ロックを取得できたサーバーだけが処理する
+*/
+func (p *Plugin) resolveDueGames() {
+	mutex := newClusterMutex(p.API, deadlineLockKey, deadlineLockExpireInSeconds)
+	locked, err := mutex.tryLock()
+	if err != nil {
+		p.API.LogError("failed to lock the deadlines", "error", err.Error())
+		return
+	}
+	if !locked {
+		return
+	}
+	defer mutex.unlock()
+
+	games, err := p.store.jankenStore.ListDue(model.GetMillis())
+	if err != nil {
+		p.API.LogError("failed to get the games past the deadline", "error", err.Error())
+		return
+	}
+	for _, game := range games {
+		p.resolveDeadline(game)
+	}
+
+	p.advanceTimedOutLiveGames(model.GetMillis())
+}
+
+// advanceTimedOutLiveGames は保存したジャンケンの開始日時から時間切れになったライブ対戦のジャンケンを進める
+func (p *Plugin) advanceTimedOutLiveGames(now int64) {
+	games, err := p.store.jankenStore.ListLive()
+	if err != nil {
+		p.API.LogError("failed to get the live games", "error", err.Error())
+		return
+	}
+	for _, game := range games {
+		if live := game.Impl.(*gameImplLive); live.isTimedOut(now) {
+			p.handleLiveTimeout(game.ID, live.RoundStartedAt)
+		}
+	}
+}
+
+// checkDue は指定した日時に締め切りを過ぎているゲームかを確認する関数を返す
+func checkDue(now int64) func(*game) error {
+	return func(g *game) error {
+		if g.Deadline == 0 || g.Deadline > now {
+			return errNotDue
+		}
+		return nil
+	}
+}
+
+/*
+resolveDeadline は締め切りを過ぎたゲームの結果を表示してpostを更新する．
+一覧を取得した後の参加も含めるために最新のゲームを取り出してから結果を表示する．
+参加者が結果を表示するのに必要な人数に満たない場合は結果を表示せずにゲームを削除する
+*/
+func (p *Plugin) resolveDeadline(game *game) {
+	// 他の操作が先に結果を表示した場合や締め切りが変更された場合は何もしない
+	if game = p.claimGame(game.ID, checkDue(model.GetMillis())); game == nil {
+		return
+	}
+
+	post, appErr := p.API.GetPost(game.PostID)
+	if appErr != nil {
+		p.API.LogError("failed to get the post of the game past the deadline", "id", game.ID, "error", appErr.Error())
+		return
+	}
+
+	l := p.getLocalizer(game.Language)
+	if !game.hasEnoughParticipants() {
+		model.ParseSlackAttachment(post, nil)
+		appendMessage(post, Localize(l, deadlineNotEnoughParticipantsMessage, map[string]interface{}{
+			"Min": game.minParticipants(),
+		}))
+	} else {
+		appendMessage(post, Localize(l, deadlinePassedMessage, nil))
+		p.finishGame(game, post)
+	}
+
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		p.API.LogError("failed to update the post of the game past the deadline", "id", game.ID, "error", appErr.Error())
+	}
+}
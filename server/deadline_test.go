@@ -0,0 +1,232 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/text/language"
+)
+
+func TestParseDeadline(t *testing.T) {
+	location := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2020, 12, 24, 16, 30, 15, 0, location)
+	for name, test := range map[string]struct {
+		Value       string
+		Expected    time.Time
+		ShouldError bool
+	}{
+		"empty":                     {Value: "", Expected: time.Time{}},
+		"duration":                  {Value: "15m", Expected: now.Add(15 * time.Minute)},
+		"time later today":          {Value: "17:00", Expected: time.Date(2020, 12, 24, 17, 0, 0, 0, location)},
+		"time earlier than now":     {Value: "9:00", Expected: time.Date(2020, 12, 25, 9, 0, 0, 0, location)},
+		"date and time":             {Value: "2020-12-31 23:59", Expected: time.Date(2020, 12, 31, 23, 59, 0, 0, location)},
+		"zero duration":             {Value: "0s", ShouldError: true},
+		"negative duration":         {Value: "-15m", ShouldError: true},
+		"date and time in the past": {Value: "2020-12-24 16:30", ShouldError: true},
+		"invalid":                   {Value: "tomorrow", ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			deadline, err := parseDeadline(test.Value, now)
+
+			if test.ShouldError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			if test.Expected.IsZero() {
+				assert.Equal(int64(0), deadline)
+			} else {
+				assert.Equal(model.GetMillisForTime(test.Expected), deadline)
+			}
+		})
+	}
+}
+
+func TestFormatDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	location := time.FixedZone("JST", 9*60*60)
+	deadline := model.GetMillisForTime(time.Date(2020, 12, 24, 17, 0, 0, 0, location))
+
+	assert.Equal("2020-12-24 17:00 JST", formatDeadline(deadline, location))
+	assert.Equal("2020-12-24 08:00 UTC", formatDeadline(deadline, time.UTC))
+	assert.Equal("2020-12-24 17:00", formatDeadlineInput(deadline, location))
+	assert.Equal("", formatDeadlineInput(0, location))
+}
+
+func TestResolveDueGames(t *testing.T) {
+	newPlugin := func() (*Plugin, *atomicKV, *[]*model.Post) {
+		api, kv := newAtomicKVAPI()
+		updated := []*model.Post{}
+		api.On("GetPost", mock.AnythingOfType("string")).Return(
+			func(postID string) *model.Post {
+				return &model.Post{Id: postID, Message: "game"}
+			},
+			nil)
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(
+			func(post *model.Post) *model.Post {
+				updated = append(updated, post)
+				return post
+			},
+			nil)
+		p := &Plugin{}
+		p.API = api
+		p.store = NewStore(api)
+		p.bundle = i18n.NewBundle(language.English)
+		return p, kv, &updated
+	}
+	newDeadlineGame := func(deadline int64, participants int) *game {
+		g := newGame(&gameImpl1{})
+		g.PostID = model.NewId()
+		g.Deadline = deadline
+		for i := 0; i < participants; i++ {
+			g.Participants = append(g.Participants, newParticipant(model.NewId()))
+		}
+		return g
+	}
+
+	t.Run("closes games past the deadline without enough participants", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p, kv, updated := newPlugin()
+		now := model.GetMillis()
+		due := newDeadlineGame(now-1000, 1)
+		notDue := newDeadlineGame(now+60*1000, 1)
+		assert.Nil(p.store.jankenStore.Save(due))
+		assert.Nil(p.store.jankenStore.Save(notDue))
+
+		p.resolveDueGames()
+
+		assert.Len(*updated, 1)
+		assert.Equal(due.PostID, (*updated)[0].Id)
+		assert.True(strings.HasSuffix((*updated)[0].Message, Localize(p.getLocalizer("en"), deadlineNotEnoughParticipantsMessage, map[string]interface{}{"Min": 2})))
+		assert.Nil(kv.values[keyPrefix+due.ID])
+		assert.NotNil(kv.values[keyPrefix+notDue.ID])
+		assert.Nil(kv.values[deadlineLockKey])
+
+		ids, err := getIndex(p.API, deadlineIndexKey)
+		assert.Nil(err)
+		assert.Equal([]string{notDue.ID}, ids)
+	})
+
+	t.Run("resolves the latest game only once", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p, kv, updated := newPlugin()
+		due := newDeadlineGame(model.GetMillis()-1000, 1)
+		assert.Nil(p.store.jankenStore.Save(due))
+
+		// 一覧を取得した後に参加したユーザーも結果に含める
+		_, err := p.store.jankenStore.Update(due.ID, func(g *game) error {
+			g.UpdateHands("p2", []string{"rock"})
+			return nil
+		})
+		assert.Nil(err)
+		claimed := p.claimGame(due.ID, checkDue(model.GetMillis()))
+		assert.Len(claimed.Participants, 2)
+
+		// 他の操作が先に結果を表示したゲームは何もしない
+		kv.values[keyPrefix+due.ID], _ = due.ToBytes()
+		assert.NotNil(p.claimGame(due.ID, nil))
+		p.resolveDeadline(due)
+		assert.Empty(*updated)
+
+		// 締め切りが延長されたゲームは結果を表示しない
+		due.Deadline = model.GetMillis() + 60*1000
+		kv.values[keyPrefix+due.ID], _ = due.ToBytes()
+		p.resolveDeadline(due)
+		assert.Empty(*updated)
+		assert.NotNil(kv.values[keyPrefix+due.ID])
+	})
+
+	t.Run("resolves each game once when a slow pass outlives the lock", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p, kv, updated := newPlugin()
+		due := newDeadlineGame(model.GetMillis()-1000, 1)
+		assert.Nil(p.store.jankenStore.Save(due))
+
+		// 遅いサーバーがロックを取得して一覧を取得した後にロックの期限が過ぎる
+		slow := newClusterMutex(p.API, deadlineLockKey, deadlineLockExpireInSeconds)
+		locked, err := slow.tryLock()
+		assert.Nil(err)
+		assert.True(locked)
+		games, err := p.store.jankenStore.ListDue(model.GetMillis())
+		assert.Nil(err)
+		delete(kv.values, deadlineLockKey)
+
+		// 他のサーバーがロックを取得して同じゲームを処理する
+		p.resolveDueGames()
+		assert.Len(*updated, 1)
+
+		// 遅いサーバーが処理を続けても結果は1回だけ表示する
+		for _, g := range games {
+			p.resolveDeadline(g)
+		}
+		assert.Len(*updated, 1)
+
+		// 遅いサーバーは期限が過ぎた後に他のサーバーが取得したロックを解除しない
+		other := newClusterMutex(p.API, deadlineLockKey, deadlineLockExpireInSeconds)
+		locked, err = other.tryLock()
+		assert.Nil(err)
+		assert.True(locked)
+		slow.unlock()
+		assert.Equal(other.token, kv.values[deadlineLockKey])
+	})
+
+	t.Run("advances timed out live rounds from the stored start time", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p, kv, updated := newPlugin()
+		siteURL := dummySiteURL
+		p.ServerConfig = &model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}}
+		p.API.(*plugintest.API).On("GetUser", mock.AnythingOfType("string")).Return(&model.User{Username: "user"}, nil)
+		g, _ := newLiveTestGame("p1", "p2", "p3")
+		g.PostID = model.NewId()
+		assert.Nil(p.store.jankenStore.Save(g))
+		_, err := p.store.jankenStore.Update(g.ID, func(g *game) error {
+			live := g.Impl.(*gameImplLive)
+			if err := live.start(g); err != nil {
+				return err
+			}
+			// プラグインを再起動している間に時間切れになったジャンケン
+			live.RoundStartedAt = model.GetMillis() - liveRoundTimeoutSeconds*1000
+			return nil
+		})
+		assert.Nil(err)
+
+		p.resolveDueGames()
+
+		assert.Len(*updated, 1)
+		stored, _ := gameFromBytes(kv.values[keyPrefix+g.ID])
+		assert.Len(stored.Rounds, 1)
+		assert.False(stored.Impl.(*gameImplLive).isTimedOut(model.GetMillis()))
+
+		// 時間切れになっていないジャンケンは進めない
+		p.resolveDueGames()
+		assert.Len(*updated, 1)
+	})
+
+	t.Run("does nothing while another server holds the lock", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p, kv, updated := newPlugin()
+		due := newDeadlineGame(model.GetMillis()-1000, 1)
+		assert.Nil(p.store.jankenStore.Save(due))
+		kv.values[deadlineLockKey] = []byte("locked")
+
+		p.resolveDueGames()
+
+		assert.Empty(*updated)
+		assert.NotNil(kv.values[keyPrefix+due.ID])
+		assert.Equal([]byte("locked"), kv.values[deadlineLockKey])
+	})
+}
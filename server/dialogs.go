@@ -3,27 +3,13 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-var handMessages = map[string]*i18n.Message{
-	"rock": {
-		ID:    "joinDialogHandRock",
-		Other: "Rock",
-	},
-	"scissors": {
-		ID:    "joinDialogHandScissors",
-		Other: "Scissors",
-	},
-	"paper": {
-		ID:    "joinDialogHandPaper",
-		Other: "Paper",
-	},
-}
-
 var (
 	joinDialogTitle = &i18n.Message{
 		ID:    "joinDialogTitle",
@@ -45,6 +31,14 @@ var (
 		ID:    "joinDialogHandElementHelp",
 		Other: "Choose hand {{.Index}}",
 	}
+	joinDialogTeamElementLabel = &i18n.Message{
+		ID:    "joinDialogTeamElementLabel",
+		Other: "Team",
+	}
+	joinDialogTeamElementHelp = &i18n.Message{
+		ID:    "joinDialogTeamElementHelp",
+		Other: "Enter the name of your team. Current teams: {{.Teams}}",
+	}
 	configDialogTitle = &i18n.Message{
 		ID:    "configDialogTitle",
 		Other: "Config",
@@ -57,6 +51,94 @@ var (
 		ID:    "configDialogMaxRoundsLabel",
 		Other: "Max rounds",
 	}
+	configDialogHandSetLabel = &i18n.Message{
+		ID:    "configDialogHandSetLabel",
+		Other: "Hands",
+	}
+	configDialogGameTypeLabel = &i18n.Message{
+		ID:    "configDialogGameTypeLabel",
+		Other: "Game type",
+	}
+	configDialogNumWinnersLabel = &i18n.Message{
+		ID:    "configDialogNumWinnersLabel",
+		Other: "Number of winners",
+	}
+	configDialogNumWinnersHelp = &i18n.Message{
+		ID:    "configDialogNumWinnersHelp",
+		Other: "Used when the game type is \"Pick winners\".",
+	}
+	configDialogMaxParticipantsLabel = &i18n.Message{
+		ID:    "configDialogMaxParticipantsLabel",
+		Other: "Max participants",
+	}
+	configDialogMaxParticipantsHelp = &i18n.Message{
+		ID:    "configDialogMaxParticipantsHelp",
+		Other: "No more users can join after this number of users have joined. 0 means no limit.",
+	}
+	configDialogMinParticipantsLabel = &i18n.Message{
+		ID:    "configDialogMinParticipantsLabel",
+		Other: "Min participants",
+	}
+	configDialogMinParticipantsHelp = &i18n.Message{
+		ID:    "configDialogMinParticipantsHelp",
+		Other: "The result can be shown after this number of users have joined.",
+	}
+	configDialogAutoResultLabel = &i18n.Message{
+		ID:    "configDialogAutoResultLabel",
+		Other: "Show the result when full",
+	}
+	configDialogAutoResultHelp = &i18n.Message{
+		ID:    "configDialogAutoResultHelp",
+		Other: "Show the result automatically when the number of participants reaches the max participants. Not used for live games.",
+	}
+	configDialogAutoResultOffOption = &i18n.Message{
+		ID:    "configDialogAutoResultOffOption",
+		Other: "Wait for the Result button",
+	}
+	configDialogAutoResultOnOption = &i18n.Message{
+		ID:    "configDialogAutoResultOnOption",
+		Other: "Show the result",
+	}
+	configDialogDeadlineLabel = &i18n.Message{
+		ID:    "configDialogDeadlineLabel",
+		Other: "Deadline",
+	}
+	configDialogDeadlineHelp = &i18n.Message{
+		ID:    "configDialogDeadlineHelp",
+		Other: "The result is shown automatically at the deadline. Enter a duration (15m), a time (17:00) or a date and time (2020-12-24 17:00) in your timezone. Leave it empty to show the result manually.",
+	}
+	configDialogTitleLabel = &i18n.Message{
+		ID:    "configDialogTitleLabel",
+		Other: "Title",
+	}
+	configDialogTitleHelp = &i18n.Message{
+		ID:    "configDialogTitleHelp",
+		Other: "Shown instead of \"Janken game\" in the game post and the result.",
+	}
+	configDialogPurposeLabel = &i18n.Message{
+		ID:    "configDialogPurposeLabel",
+		Other: "Purpose",
+	}
+	configDialogPurposeHelp = &i18n.Message{
+		ID:    "configDialogPurposeHelp",
+		Other: "What this game decides, like \"Who presents at Friday demo?\". Shown in the game post and the result, and searchable with the history subcommand.",
+	}
+	configDialogOutcomesLabel = &i18n.Message{
+		ID:    "configDialogOutcomesLabel",
+		Other: "Prizes and penalties",
+	}
+	configDialogOutcomesHelp = &i18n.Message{
+		ID:    "configDialogOutcomesHelp",
+		Other: "One per line like \"1: picks lunch place\" or \"last: buys coffee\". The users of the rank are mentioned with the result.",
+	}
+	configDialogResultPublishModeLabel = &i18n.Message{
+		ID:    "configDialogResultPublishModeLabel",
+		Other: "Result",
+	}
+	configDialogResultPublishModeHelp = &i18n.Message{
+		ID:    "configDialogResultPublishModeHelp",
+		Other: "Where to show the result. A new message or a thread reply notifies the participants.",
+	}
 	configDialogDestroyLabel = &i18n.Message{
 		ID:    "configDialogDestroyLabel",
 		Other: "Destroy this game",
@@ -115,19 +197,13 @@ func (d *joinDialog) Open(triggerID, postID, userID string, game *game) {
 	cancelLabel := Localize(l, joinDialogCancelLabel, nil)
 
 	// ジャンケンで出せる手
-	var HandsOptions []*model.PostActionOptions = []*model.PostActionOptions{
-		{
-			Text:  Localize(l, handMessages["rock"], nil),
-			Value: "rock",
-		},
-		{
-			Text:  Localize(l, handMessages["scissors"], nil),
-			Value: "scissors",
-		},
-		{
-			Text:  Localize(l, handMessages["paper"], nil),
-			Value: "paper",
-		},
+	hs := game.getHandSet()
+	var HandsOptions []*model.PostActionOptions = []*model.PostActionOptions{}
+	for _, h := range hs.Hands {
+		HandsOptions = append(HandsOptions, &model.PostActionOptions{
+			Text:  Localize(l, hs.Messages[h], nil),
+			Value: h,
+		})
 	}
 
 	p := game.GetParticipant(userID)
@@ -135,9 +211,10 @@ func (d *joinDialog) Open(triggerID, postID, userID string, game *game) {
 		p = newParticipant(userID)
 	}
 
-	// 手の入力フォームを追加
+	// 手の入力フォームを追加．ライブ対戦では手は対戦中に選ぶ
 	elements := []model.DialogElement{}
-	for i := 0; i < game.MaxRounds; i++ {
+	_, isLive := game.Impl.(*gameImplLive)
+	for i := 0; i < game.MaxRounds && !isLive; i++ {
 
 		i1 := i + 1 // 1-base index
 		displayName := Localize(l, joinDialogHandElementLabel, map[string]interface{}{
@@ -148,8 +225,8 @@ func (d *joinDialog) Open(triggerID, postID, userID string, game *game) {
 			"Index": i1,
 		})
 
-		hand := p.getHand(i)
-		localizedHand := Localize(l, handMessages[hand], nil)
+		hand := game.fillHand(p, i)
+		localizedHand := Localize(l, hs.Messages[hand], nil)
 
 		elements = append(elements, model.DialogElement{
 			DisplayName: displayName,
@@ -163,6 +240,21 @@ func (d *joinDialog) Open(triggerID, postID, userID string, game *game) {
 		})
 	}
 
+	// チーム戦の場合はチーム名の入力フォームを追加
+	if _, ok := game.Impl.(*gameImplTeam); ok {
+		elements = append(elements, model.DialogElement{
+			DisplayName: Localize(l, joinDialogTeamElementLabel, nil),
+			Name:        "team",
+			Type:        "text",
+			Default:     p.Team,
+			Optional:    true,
+			MaxLength:   maxTeamNameLength,
+			HelpText: Localize(l, joinDialogTeamElementHelp, map[string]interface{}{
+				"Teams": strings.Join(game.getTeamNames(), ", "),
+			}),
+		})
+	}
+
 	elements = append(elements, model.DialogElement{
 		DisplayName: cancelLabel,
 		Name:        "cancel",
@@ -203,7 +295,7 @@ func newConfigDialog(api plugin.API, siteURL, pluginID string, plugin *Plugin) *
 	return d
 }
 
-func (d *configDialog) Open(triggerID, postID string, game *game) {
+func (d *configDialog) Open(triggerID, postID, userID string, game *game) {
 	d.API.LogDebug("openConfigDialog is called")
 
 	// options for maxRounds
@@ -217,10 +309,102 @@ func (d *configDialog) Open(triggerID, postID string, game *game) {
 	l := d.plugin.getLocalizer(game.Language)
 	dialogTitle := Localize(l, configDialogTitle, nil)
 	submitLabel := Localize(l, configDialogSubmitLabel, nil)
+	titleLabel := Localize(l, configDialogTitleLabel, nil)
+	titleHelp := Localize(l, configDialogTitleHelp, nil)
+	purposeLabel := Localize(l, configDialogPurposeLabel, nil)
+	purposeHelp := Localize(l, configDialogPurposeHelp, nil)
+	outcomesLabel := Localize(l, configDialogOutcomesLabel, nil)
+	outcomesHelp := Localize(l, configDialogOutcomesHelp, nil)
 	maxRoundsLabel := Localize(l, configDialogMaxRoundsLabel, nil)
+	handSetLabel := Localize(l, configDialogHandSetLabel, nil)
+	gameTypeLabel := Localize(l, configDialogGameTypeLabel, nil)
+	numWinnersLabel := Localize(l, configDialogNumWinnersLabel, nil)
+	numWinnersHelp := Localize(l, configDialogNumWinnersHelp, nil)
+	maxParticipantsLabel := Localize(l, configDialogMaxParticipantsLabel, nil)
+	maxParticipantsHelp := Localize(l, configDialogMaxParticipantsHelp, nil)
+	minParticipantsLabel := Localize(l, configDialogMinParticipantsLabel, nil)
+	minParticipantsHelp := Localize(l, configDialogMinParticipantsHelp, nil)
+	autoResultLabel := Localize(l, configDialogAutoResultLabel, nil)
+	autoResultHelp := Localize(l, configDialogAutoResultHelp, nil)
+	deadlineLabel := Localize(l, configDialogDeadlineLabel, nil)
+	deadlineHelp := Localize(l, configDialogDeadlineHelp, nil)
+	resultPublishModeLabel := Localize(l, configDialogResultPublishModeLabel, nil)
+	resultPublishModeHelp := Localize(l, configDialogResultPublishModeHelp, nil)
 	destroyLabel := Localize(l, configDialogDestroyLabel, nil)
 
+	// options for resultPublishMode
+	resultPublishModeOptions := []*model.PostActionOptions{{
+		Text: Localize(l, resultPublishDefaultMessage, map[string]interface{}{
+			"Mode": Localize(l, resultPublishModeMessages[d.plugin.getConfiguration().GetResultPublishMode()], nil),
+		}),
+		Value: resultPublishDefault,
+	}}
+	for _, mode := range resultPublishModes {
+		resultPublishModeOptions = append(resultPublishModeOptions, &model.PostActionOptions{
+			Text: Localize(l, resultPublishModeMessages[mode], nil), Value: mode,
+		})
+	}
+	resultPublishMode := resultPublishDefault
+	if isValidResultPublishMode(game.ResultPublishMode) {
+		resultPublishMode = game.ResultPublishMode
+	}
+
+	// options for handSet
+	handSetOptions := []*model.PostActionOptions{}
+	for _, name := range handSetNames {
+		handSetOptions = append(handSetOptions, &model.PostActionOptions{
+			Text: Localize(l, handSets[name].Label, nil), Value: name,
+		})
+	}
+	hs := game.getHandSet()
+
+	// options for autoResult
+	autoResultOptions := []*model.PostActionOptions{
+		{Text: Localize(l, configDialogAutoResultOffOption, nil), Value: "false"},
+		{Text: Localize(l, configDialogAutoResultOnOption, nil), Value: "true"},
+	}
+
+	// 締め切りは開いたユーザーのタイムゾーンで表示する
+	deadline := ""
+	if game.Deadline > 0 {
+		deadline = formatDeadlineInput(game.Deadline, d.plugin.getUserLocation(userID))
+	}
+
+	// options for gameType
+	gameTypeOptions := []*model.PostActionOptions{}
+	for _, name := range gameTypeNames {
+		gameTypeOptions = append(gameTypeOptions, &model.PostActionOptions{
+			Text: Localize(l, gameTypeMessages[name], nil), Value: name,
+		})
+	}
+
 	elements := []model.DialogElement{
+		{
+			DisplayName: titleLabel,
+			Name:        "title",
+			Type:        "text",
+			Default:     game.Title,
+			Optional:    true,
+			MaxLength:   maxTitleLength,
+			HelpText:    titleHelp,
+		},
+		{
+			DisplayName: purposeLabel,
+			Name:        "purpose",
+			Type:        "textarea",
+			Default:     game.Purpose,
+			Optional:    true,
+			MaxLength:   maxPurposeLength,
+			HelpText:    purposeHelp,
+		},
+		{
+			DisplayName: outcomesLabel,
+			Name:        "outcomes",
+			Type:        "textarea",
+			Default:     formatOutcomes(game.Outcomes),
+			Optional:    true,
+			HelpText:    outcomesHelp,
+		},
 		{
 			DisplayName: maxRoundsLabel,
 			Name:        "max_rounds",
@@ -229,6 +413,70 @@ func (d *configDialog) Open(triggerID, postID string, game *game) {
 			Default:     strconv.Itoa(game.MaxRounds),
 			Options:     maxRoundsOptions,
 		},
+		{
+			DisplayName: handSetLabel,
+			Name:        "hand_set",
+			Type:        "select",
+			Placeholder: Localize(l, hs.Label, nil),
+			Default:     hs.Name,
+			Options:     handSetOptions,
+		},
+		{
+			DisplayName: gameTypeLabel,
+			Name:        "game_type",
+			Type:        "select",
+			Placeholder: Localize(l, gameTypeMessages[game.GameType], nil),
+			Default:     game.GameType,
+			Options:     gameTypeOptions,
+		},
+		{
+			DisplayName: numWinnersLabel,
+			Name:        "num_winners",
+			Type:        "text",
+			SubType:     "number",
+			Default:     strconv.Itoa(game.NumWinners),
+			HelpText:    numWinnersHelp,
+		},
+		{
+			DisplayName: maxParticipantsLabel,
+			Name:        "max_participants",
+			Type:        "text",
+			SubType:     "number",
+			Default:     strconv.Itoa(game.MaxParticipants),
+			HelpText:    maxParticipantsHelp,
+		},
+		{
+			DisplayName: minParticipantsLabel,
+			Name:        "min_participants",
+			Type:        "text",
+			SubType:     "number",
+			Default:     strconv.Itoa(game.minParticipants()),
+			HelpText:    minParticipantsHelp,
+		},
+		{
+			DisplayName: autoResultLabel,
+			Name:        "auto_result",
+			Type:        "select",
+			Default:     strconv.FormatBool(game.AutoResult),
+			Options:     autoResultOptions,
+			HelpText:    autoResultHelp,
+		},
+		{
+			DisplayName: deadlineLabel,
+			Name:        "deadline",
+			Type:        "text",
+			Default:     deadline,
+			Optional:    true,
+			HelpText:    deadlineHelp,
+		},
+		{
+			DisplayName: resultPublishModeLabel,
+			Name:        "result_publish_mode",
+			Type:        "select",
+			Default:     resultPublishMode,
+			Options:     resultPublishModeOptions,
+			HelpText:    resultPublishModeHelp,
+		},
 		{
 			DisplayName: destroyLabel,
 			Name:        "destroy",
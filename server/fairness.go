@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// secretLength は塩とシードのバイト数
+const secretLength = 16
+
+/*
+revealedGame は結果と一緒に公開するゲームの情報．
+コミットメントと公開された塩・シードから結果を再計算して検証できる
+*/
+type revealedGame struct {
+	ID             string                 `json:"id"`
+	GameType       string                 `json:"game_type"`
+	HandSet        string                 `json:"hand_set"`
+	MaxRounds      int                    `json:"max_rounds"`
+	NumWinners     int                    `json:"num_winners"`
+	RandAlgorithm  string                 `json:"rand_algorithm"`
+	Seed           string                 `json:"seed"`
+	SeedCommitment string                 `json:"seed_commitment"`
+	Participants   []*revealedParticipant `json:"participants"`
+}
+
+// revealedParticipant は結果と一緒に公開する参加者の情報
+type revealedParticipant struct {
+	UserID string `json:"user_id"`
+	Team   string `json:"team,omitempty"`
+	// 参加時に登録した手．ライブ対戦では対戦中に出した手
+	Hands      []string `json:"hands"`
+	Salt       string   `json:"salt,omitempty"`
+	Commitment string   `json:"commitment,omitempty"`
+	Rank       int      `json:"rank"`
+}
+
+// newSecret はランダムな塩・シードを返す
+func newSecret() string {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// commitSeed はシードのコミットメントを返す
+func commitSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// commitHands は塩と手のコミットメントを返す
+func commitHands(salt string, hands []string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + strings.Join(hands, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// commitSeed はゲームのシードを決めてコミットメントを作る
+func (g *game) commitSeed() {
+	g.setSeed(newSecret())
+	g.SeedCommitment = commitSeed(g.Seed)
+}
+
+/*
+commitHands は参加者が登録した手に新しい塩をつけてコミットメントを作る．
+Returns:
+    string: コミットメント．参加者が存在しない場合は空文字
+*/
+func (g *game) commitHands(userID string) string {
+	p := g.GetParticipant(userID)
+	if p == nil {
+		return ""
+	}
+	p.Salt = newSecret()
+	p.CommittedHands = make([]string, len(p.Hands))
+	copy(p.CommittedHands, p.Hands)
+	p.Commitment = commitHands(p.Salt, p.CommittedHands)
+	return p.Commitment
+}
+
+// reveal は結果と一緒に公開するゲームの情報を返す
+func (g *game) reveal() *revealedGame {
+	r := &revealedGame{
+		ID:             g.ID,
+		GameType:       g.GameType,
+		HandSet:        g.HandSet,
+		MaxRounds:      g.MaxRounds,
+		NumWinners:     g.NumWinners,
+		RandAlgorithm:  g.RandAlgorithm,
+		Seed:           g.Seed,
+		SeedCommitment: g.SeedCommitment,
+	}
+	for _, p := range g.Participants {
+		rp := &revealedParticipant{
+			UserID:     p.UserID,
+			Team:       p.Team,
+			Hands:      p.CommittedHands,
+			Salt:       p.Salt,
+			Commitment: p.Commitment,
+			Rank:       p.Rank,
+		}
+		if p.Commitment == "" {
+			// 手を登録していない参加者(ライブ対戦など)は実際に出した手を公開する
+			rp.Hands = p.Hands
+		}
+		r.Participants = append(r.Participants, rp)
+	}
+	return r
+}
+
+/*
+verifyRevealedGame は公開されたゲームの情報を検証する．
+シードと各参加者の手がコミットメントと一致すること，
+公開された手とシードから再計算した順位が公開された順位と一致することを確認する
+*/
+func verifyRevealedGame(r *revealedGame) error {
+	if commitSeed(r.Seed) != r.SeedCommitment {
+		return fmt.Errorf("the seed does not match the commitment %s", r.SeedCommitment)
+	}
+	for _, p := range r.Participants {
+		if p.Commitment != "" && commitHands(p.Salt, p.Hands) != p.Commitment {
+			return fmt.Errorf("the hands of %s do not match the commitment %s", p.UserID, p.Commitment)
+		}
+	}
+
+	// ライブ対戦は1回ずつ手を出した通常の順位付けと同じ結果になる
+	gameType := r.GameType
+	if gameType == "gameImplLive" {
+		gameType = "gameImpl1"
+	}
+	f := newGameFuncMapping[gameType]
+	if f == nil {
+		return fmt.Errorf("unknown game type: %s", r.GameType)
+	}
+
+	g := newGame(f())
+	g.MaxRounds = r.MaxRounds
+	g.NumWinners = r.NumWinners
+	g.RandAlgorithm = r.RandAlgorithm
+	g.setSeed(r.Seed)
+	for _, rp := range r.Participants {
+		p := newParticipant(rp.UserID)
+		p.setHands(rp.Hands)
+		p.Team = rp.Team
+		g.Participants = append(g.Participants, p)
+	}
+	g.setHandSet(r.HandSet)
+	g.getResult()
+
+	for _, rp := range r.Participants {
+		if rank := g.GetParticipant(rp.UserID).Rank; rank != rp.Rank {
+			return fmt.Errorf("the rank of %s is %d, but %d is revealed", rp.UserID, rank, rp.Rank)
+		}
+	}
+	return nil
+}
+
+// verifyRevealedGameJSON はJSONで公開されたゲームの情報を検証する
+func verifyRevealedGameJSON(b []byte) error {
+	r := &revealedGame{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return err
+	}
+	return verifyRevealedGame(r)
+}
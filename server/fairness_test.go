@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitments(t *testing.T) {
+	t.Run("commitHands", func(t *testing.T) {
+		assert := assert.New(t)
+		c := commitHands("salt", []string{"rock", "paper"})
+
+		assert.Len(c, 64)
+		assert.Equal(c, commitHands("salt", []string{"rock", "paper"}))
+		assert.NotEqual(c, commitHands("other", []string{"rock", "paper"}))
+		assert.NotEqual(c, commitHands("salt", []string{"rock", "scissors"}))
+	})
+
+	t.Run("commitSeed", func(t *testing.T) {
+		assert := assert.New(t)
+		g := newGame(&gameImpl1{})
+
+		g.commitSeed()
+
+		assert.Len(g.Seed, secretLength*2)
+		assert.Equal(commitSeed(g.Seed), g.SeedCommitment)
+	})
+
+	t.Run("game.commitHands", func(t *testing.T) {
+		assert := assert.New(t)
+		g := newGame(&gameImpl1{})
+		g.UpdateHands("p1", []string{"rock", "paper"})
+
+		c := g.commitHands("p1")
+
+		p := g.GetParticipant("p1")
+		assert.Equal(c, p.Commitment)
+		assert.Equal(commitHands(p.Salt, p.CommittedHands), c)
+		assert.Equal([]string{"rock", "paper"}, p.CommittedHands[:2])
+		assert.Equal("", g.commitHands("unknown"))
+	})
+}
+
+func TestVerifyRevealedGame(t *testing.T) {
+	newFinishedGame := func(impl gameInterface) *game {
+		g := newGame(impl)
+		g.MaxRounds = 3
+		g.NumWinners = 2
+		g.commitSeed()
+		g.UpdateHands("p1", []string{"rock", "rock", "rock"})
+		g.UpdateHands("p2", []string{"rock"})
+		g.UpdateHands("p3", []string{})
+		g.UpdateHands("p4", []string{"rock", "rock"})
+		for _, p := range g.Participants {
+			g.commitHands(p.UserID)
+		}
+		g.getResult()
+		return g
+	}
+
+	for _, gameType := range gameTypeNames {
+		t.Run(gameType, func(t *testing.T) {
+			assert := assert.New(t)
+			g := newFinishedGame(newGameFuncMapping[gameType]())
+
+			b, err := json.Marshal(g.reveal())
+			assert.Nil(err)
+			assert.Nil(verifyRevealedGameJSON(b))
+		})
+	}
+
+	for name, tamper := range map[string]func(r *revealedGame){
+		"seed":  func(r *revealedGame) { r.Seed = "0123" },
+		"hands": func(r *revealedGame) { r.Participants[0].Hands[0] = "paper" },
+		"rank":  func(r *revealedGame) { r.Participants[0].Rank = 10 },
+		"type":  func(r *revealedGame) { r.GameType = "unknown" },
+	} {
+		t.Run("tampered "+name, func(t *testing.T) {
+			r := newFinishedGame(&gameImpl1{}).reveal()
+			tamper(r)
+			assert.NotNil(t, verifyRevealedGame(r))
+		})
+	}
+
+	t.Run("live game", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2", "p3")
+		b.commitSeed()
+		g.start(b)
+		g.play(b, "p1", "rock")
+		g.play(b, "p2", "rock")
+		g.fillMissingHands(b)
+		g.advance(b)
+		b.getResult()
+
+		assert.Nil(verifyRevealedGame(b.reveal()))
+	})
+}
@@ -13,6 +13,8 @@ func newGameImpl1() gameInterface {
 func (g *gameImpl1) getResult(game *game) []*participant {
 	startRound := 0 // Handsの利用開始番号
 	startRank := 1  // 順位の開始番号
+	g.base = game
+	game.Rounds = nil
 	result := g.nextRound(game.Participants, game.MaxRounds, startRound, startRank, nil)
 	return result
 }
@@ -57,7 +59,7 @@ func (g *gameImpl1) nextRound(participants []*participant, maxRounds, round, ran
 	}
 
 	// ジャンケンを1回実行
-	winner, loser, drawer := janken(participants, round)
+	winner, loser, drawer := g.janken(participants, round)
 
 	if drawer != nil {
 		// あいこの処理
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var (
+	bracketTitle = &i18n.Message{
+		ID:    "bracketTitle",
+		Other: "Bracket",
+	}
+	bracketRoundLabel = &i18n.Message{
+		ID:    "bracketRoundLabel",
+		Other: "Round {{.Round}}",
+	}
+	bracketFinalLabel = &i18n.Message{
+		ID:    "bracketFinalLabel",
+		Other: "Final",
+	}
+	bracketMatchMessage = &i18n.Message{
+		ID:    "bracketMatch",
+		Other: "@{{.Player1}} vs @{{.Player2}} → @{{.Winner}}",
+	}
+	bracketDrawMatchMessage = &i18n.Message{
+		ID:    "bracketDrawMatch",
+		Other: "@{{.Player1}} vs @{{.Player2}} → @{{.Winner}} (all rounds were drawn, the higher seed advances)",
+	}
+	bracketByeMatchMessage = &i18n.Message{
+		ID:    "bracketByeMatch",
+		Other: "@{{.Winner}} (bye)",
+	}
+)
+
+// bracketMatch はトーナメントの1試合
+type bracketMatch struct {
+	// 対戦者のUserID．不戦勝の場合は一方が空文字
+	Player1 string `json:"player1"`
+	Player2 string `json:"player2"`
+	// 勝者のUserID
+	Winner string `json:"winner"`
+	// 最大対戦回数まであいこだったか
+	Draw bool `json:"draw"`
+}
+
+// isBye は不戦勝の試合かを返す
+func (m *bracketMatch) isBye() bool {
+	return m.Player1 == "" || m.Player2 == ""
+}
+
+type gameImplBracket struct {
+	gameBase
+	// 各ラウンドの試合結果
+	Rounds [][]*bracketMatch `json:"rounds"`
+}
+
+func newGameImplBracket() gameInterface {
+	return &gameImplBracket{}
+}
+
+/*
+getResult はトーナメント形式のジャンケンの結果を返す．
+参加した順にシードを決め，1対1の試合を勝ち抜いた参加者が1位になる．
+同じラウンドで負けた参加者は同じ順位になる
+*/
+func (g *gameImplBracket) getResult(game *game) []*participant {
+	g.base = game
+	g.Rounds = nil
+	game.Rounds = nil
+
+	participants := game.Participants
+	if len(participants) == 0 {
+		return []*participant{}
+	}
+
+	// シード順に参加者を配置する．人数が足りない枠はnil(不戦勝)
+	seeds := bracketSeeds(len(participants))
+	players := make([]*participant, len(seeds))
+	for i, s := range seeds {
+		if s < len(participants) {
+			players[i] = participants[s]
+		}
+	}
+	// 参加者ごとのシード順．2回戦以降の対戦者の並びはシード順とは限らない
+	seedOf := make(map[*participant]int, len(participants))
+	for s, p := range participants {
+		seedOf[p] = s
+	}
+
+	// 決勝まで勝ち抜き戦を行う
+	for len(players) > 1 {
+		matches := make([]*bracketMatch, 0, len(players)/2)
+		winners := make([]*participant, 0, len(players)/2)
+		losers := make([]*participant, 0, len(players)/2)
+		for i := 0; i < len(players); i += 2 {
+			p1, p2 := players[i], players[i+1]
+			match := &bracketMatch{}
+			switch {
+			case p2 == nil:
+				match.Player1, match.Winner = p1.UserID, p1.UserID
+				winners = append(winners, p1)
+			case p1 == nil:
+				match.Player2, match.Winner = p2.UserID, p2.UserID
+				winners = append(winners, p2)
+			default:
+				match.Player1, match.Player2 = p1.UserID, p2.UserID
+				winner, loser := g.duel(p1, p2, game.MaxRounds)
+				if winner == nil {
+					// 最大対戦回数まであいこの場合はシードが上位の参加者が勝ち上がる
+					winner, loser = p1, p2
+					if seedOf[p2] < seedOf[p1] {
+						winner, loser = p2, p1
+					}
+					match.Draw = true
+				}
+				match.Winner = winner.UserID
+				winners = append(winners, winner)
+				losers = append(losers, loser)
+			}
+			matches = append(matches, match)
+		}
+
+		// このラウンドの敗者は勝ち上がった人数の次の順位
+		for _, p := range losers {
+			p.Rank = len(winners) + 1
+		}
+		g.Rounds = append(g.Rounds, matches)
+		players = winners
+	}
+	players[0].Rank = 1
+
+	result := make([]*participant, len(participants))
+	copy(result, participants)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Rank < result[j].Rank
+	})
+	return result
+}
+
+// getResultDetail はトーナメント表を返す
+func (g *gameImplBracket) getResultDetail(l *i18n.Localizer, usernames map[string]string) string {
+	lines := []string{fmt.Sprintf("**%s**", Localize(l, bracketTitle, nil))}
+	for i, matches := range g.Rounds {
+		label := Localize(l, bracketRoundLabel, map[string]interface{}{"Round": i + 1})
+		if i == len(g.Rounds)-1 {
+			label = Localize(l, bracketFinalLabel, nil)
+		}
+		lines = append(lines, label)
+
+		for _, m := range matches {
+			data := map[string]interface{}{
+				"Player1": usernames[m.Player1],
+				"Player2": usernames[m.Player2],
+				"Winner":  usernames[m.Winner],
+			}
+			message := bracketMatchMessage
+			switch {
+			case m.isBye():
+				message = bracketByeMatchMessage
+			case m.Draw:
+				message = bracketDrawMatchMessage
+			}
+			lines = append(lines, "- "+Localize(l, message, data))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+bracketSeeds はn人のトーナメントの枠にシード順(0始まり)を割り当てて返す．
+枠の数はn以上の最小の2の累乗で，n以上のシードは不戦勝の枠を表す．
+隣り合う2つの枠が1回戦の対戦になる
+*/
+func bracketSeeds(n int) []int {
+	seeds := []int{0}
+	for len(seeds) < n {
+		size := len(seeds) * 2
+		next := make([]int, 0, size)
+		for _, s := range seeds {
+			next = append(next, s, size-1-s)
+		}
+		seeds = next
+	}
+	return seeds
+}
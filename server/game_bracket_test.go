@@ -0,0 +1,157 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestGameImplBracket(t *testing.T) {
+	t.Run("newGameImplBracket", func(t *testing.T) {
+		expectedGame := &gameImplBracket{}
+		g := newGameImplBracket()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("getResult", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MaxRounds      int
+			participants   []*participant
+			ExpectedRanks  map[string]int
+			ExpectedRounds [][]*bracketMatch
+		}{
+			"4 participants": {
+				MaxRounds: 2,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock"}},
+					{UserID: "p2", Hands: []string{"paper", "rock"}},
+					{UserID: "p3", Hands: []string{"scissors", "scissors"}},
+					{UserID: "p4", Hands: []string{"rock", "paper"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 3, "p2": 3, "p3": 2, "p4": 1},
+				ExpectedRounds: [][]*bracketMatch{
+					{
+						{Player1: "p1", Player2: "p4", Winner: "p4"},
+						{Player1: "p2", Player2: "p3", Winner: "p3"},
+					},
+					{
+						{Player1: "p4", Player2: "p3", Winner: "p4"},
+					},
+				},
+			},
+			"3 participants with a bye": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"paper"}},
+					{UserID: "p3", Hands: []string{"scissors"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 3, "p3": 2},
+				ExpectedRounds: [][]*bracketMatch{
+					{
+						{Player1: "p1", Winner: "p1"},
+						{Player1: "p2", Player2: "p3", Winner: "p3"},
+					},
+					{
+						{Player1: "p1", Player2: "p3", Winner: "p1"},
+					},
+				},
+			},
+			"draws are won by the higher seed": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"rock"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 2},
+				ExpectedRounds: [][]*bracketMatch{
+					{
+						{Player1: "p1", Player2: "p2", Winner: "p1", Draw: true},
+					},
+				},
+			},
+			"a draw in the second round is won by the higher seed": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"scissors"}},
+					{UserID: "p2", Hands: []string{"rock"}},
+					{UserID: "p3", Hands: []string{"scissors"}},
+					{UserID: "p4", Hands: []string{"rock"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 3, "p2": 1, "p3": 3, "p4": 2},
+				ExpectedRounds: [][]*bracketMatch{
+					{
+						{Player1: "p1", Player2: "p4", Winner: "p4"},
+						{Player1: "p2", Player2: "p3", Winner: "p2"},
+					},
+					{
+						{Player1: "p4", Player2: "p2", Winner: "p2", Draw: true},
+					},
+				},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				g := &gameImplBracket{}
+				b := newGame(g)
+				b.MaxRounds = test.MaxRounds
+				b.Participants = test.participants
+
+				result := g.getResult(b)
+
+				assert.Len(result, len(test.participants))
+				for i, r := range result {
+					assert.Equal(test.ExpectedRanks[r.UserID], r.Rank)
+					if i > 0 {
+						assert.LessOrEqual(result[i-1].Rank, r.Rank)
+					}
+				}
+				assert.Equal(test.ExpectedRounds, g.Rounds)
+			})
+		}
+	})
+
+	t.Run("getResultDetail", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplBracket{
+			Rounds: [][]*bracketMatch{
+				{
+					{Player1: "p1", Winner: "p1"},
+					{Player1: "p2", Player2: "p3", Winner: "p3"},
+				},
+				{
+					{Player1: "p1", Player2: "p3", Winner: "p1", Draw: true},
+				},
+			},
+		}
+		l := i18n.NewLocalizer(i18n.NewBundle(language.English))
+		usernames := map[string]string{"p1": "user1", "p2": "user2", "p3": "user3"}
+
+		detail := g.getResultDetail(l, usernames)
+
+		assert.Equal(`**Bracket**
+Round 1
+- @user1 (bye)
+- @user2 vs @user3 → @user3
+Final
+- @user1 vs @user3 → @user1 (all rounds were drawn, the higher seed advances)`, detail)
+	})
+
+	t.Run("bracketSeeds", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			N             int
+			ExpectedSeeds []int
+		}{
+			"1 participant":  {N: 1, ExpectedSeeds: []int{0}},
+			"2 participants": {N: 2, ExpectedSeeds: []int{0, 1}},
+			"3 participants": {N: 3, ExpectedSeeds: []int{0, 3, 1, 2}},
+			"8 participants": {N: 8, ExpectedSeeds: []int{0, 7, 3, 4, 1, 6, 2, 5}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, test.ExpectedSeeds, bracketSeeds(test.N))
+			})
+		}
+	})
+}
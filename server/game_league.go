@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	leagueWinPoints  = 3
+	leagueDrawPoints = 1
+	leagueLossPoints = 0
+)
+
+var (
+	leagueTableTitle = &i18n.Message{
+		ID:    "leagueTableTitle",
+		Other: "League table",
+	}
+	leagueTableHeader = &i18n.Message{
+		ID:    "leagueTableHeader",
+		Other: "|Rank|Username|W|D|L|Points|",
+	}
+)
+
+// leagueRecord は総当たり戦の1人分の成績
+type leagueRecord struct {
+	UserID string `json:"user_id"`
+	Wins   int    `json:"wins"`
+	Draws  int    `json:"draws"`
+	Losses int    `json:"losses"`
+	Points int    `json:"points"`
+	Rank   int    `json:"rank"`
+}
+
+// leagueMatch は総当たり戦の1試合
+type leagueMatch struct {
+	Player1 string `json:"player1"`
+	Player2 string `json:"player2"`
+	// 勝者のUserID．引き分けの場合は空文字
+	Winner string `json:"winner"`
+}
+
+type gameImplLeague struct {
+	gameBase
+	// 順位表
+	Table []*leagueRecord `json:"table"`
+	// 全試合の結果
+	Matches []*leagueMatch `json:"matches"`
+}
+
+func newGameImplLeague() gameInterface {
+	return &gameImplLeague{}
+}
+
+/*
+getResult は総当たり戦の結果を返す．
+全員が他の全員と1対1で対戦し，勝ち3点，引き分け1点，負け0点の勝ち点で順位を決める．
+勝ち点が同じ場合は勝ち数，勝ち数も同じ場合は該当者同士の対戦成績で順位を決める
+*/
+func (g *gameImplLeague) getResult(game *game) []*participant {
+	g.base = game
+	g.Matches = nil
+	game.Rounds = nil
+
+	records := make(map[string]*leagueRecord)
+	g.Table = make([]*leagueRecord, 0, len(game.Participants))
+	for _, p := range game.Participants {
+		r := &leagueRecord{UserID: p.UserID}
+		records[p.UserID] = r
+		g.Table = append(g.Table, r)
+	}
+
+	// 全ての組み合わせで対戦する
+	for i, p1 := range game.Participants {
+		for _, p2 := range game.Participants[i+1:] {
+			match := &leagueMatch{Player1: p1.UserID, Player2: p2.UserID}
+			winner, loser := g.duel(p1, p2, game.MaxRounds)
+			if winner == nil {
+				records[p1.UserID].addDraw()
+				records[p2.UserID].addDraw()
+			} else {
+				match.Winner = winner.UserID
+				records[winner.UserID].addWin()
+				records[loser.UserID].addLoss()
+			}
+			g.Matches = append(g.Matches, match)
+		}
+	}
+
+	g.rankTable()
+
+	result := make([]*participant, len(game.Participants))
+	copy(result, game.Participants)
+	for _, p := range result {
+		p.Rank = records[p.UserID].Rank
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Rank < result[j].Rank
+	})
+	return result
+}
+
+func (r *leagueRecord) addWin() {
+	r.Wins++
+	r.Points += leagueWinPoints
+}
+
+func (r *leagueRecord) addDraw() {
+	r.Draws++
+	r.Points += leagueDrawPoints
+}
+
+func (r *leagueRecord) addLoss() {
+	r.Losses++
+	r.Points += leagueLossPoints
+}
+
+// rankTable は順位表を並べ替えて順位をつける
+func (g *gameImplLeague) rankTable() {
+	headToHead := g.headToHeadPoints()
+	sort.SliceStable(g.Table, func(i, j int) bool {
+		return g.compare(g.Table[i], g.Table[j], headToHead) < 0
+	})
+
+	for i, r := range g.Table {
+		if i > 0 && g.compare(g.Table[i-1], r, headToHead) == 0 {
+			r.Rank = g.Table[i-1].Rank
+		} else {
+			r.Rank = i + 1
+		}
+	}
+}
+
+/*
+compare は順位表の2人を比較する．aが上位なら負，bが上位なら正，同順位なら0を返す．
+勝ち点と勝ち数が同じ2人は直接対戦の結果で比較する
+*/
+func (g *gameImplLeague) compare(a, b *leagueRecord, headToHead map[string]map[string]int) int {
+	if a.Points != b.Points {
+		return b.Points - a.Points
+	}
+	if a.Wins != b.Wins {
+		return b.Wins - a.Wins
+	}
+
+	// 勝ち点と勝ち数が同じ参加者同士の対戦で得た勝ち点
+	tied := g.tiedWith(a)
+	pointsA, pointsB := 0, 0
+	for _, r := range tied {
+		pointsA += headToHead[a.UserID][r.UserID]
+		pointsB += headToHead[b.UserID][r.UserID]
+	}
+	return pointsB - pointsA
+}
+
+// tiedWith は指定した参加者と勝ち点と勝ち数が同じ参加者を返す
+func (g *gameImplLeague) tiedWith(target *leagueRecord) []*leagueRecord {
+	tied := []*leagueRecord{}
+	for _, r := range g.Table {
+		if r.Points == target.Points && r.Wins == target.Wins {
+			tied = append(tied, r)
+		}
+	}
+	return tied
+}
+
+// headToHeadPoints は各参加者が各対戦相手から得た勝ち点を返す
+func (g *gameImplLeague) headToHeadPoints() map[string]map[string]int {
+	points := make(map[string]map[string]int)
+	add := func(userID, opponentID string, p int) {
+		if points[userID] == nil {
+			points[userID] = make(map[string]int)
+		}
+		points[userID][opponentID] += p
+	}
+	for _, m := range g.Matches {
+		switch m.Winner {
+		case "":
+			add(m.Player1, m.Player2, leagueDrawPoints)
+			add(m.Player2, m.Player1, leagueDrawPoints)
+		case m.Player1:
+			add(m.Player1, m.Player2, leagueWinPoints)
+			add(m.Player2, m.Player1, leagueLossPoints)
+		case m.Player2:
+			add(m.Player2, m.Player1, leagueWinPoints)
+			add(m.Player1, m.Player2, leagueLossPoints)
+		}
+	}
+	return points
+}
+
+// getResultDetail は順位表を返す
+func (g *gameImplLeague) getResultDetail(l *i18n.Localizer, usernames map[string]string) string {
+	lines := []string{
+		fmt.Sprintf("**%s**", Localize(l, leagueTableTitle, nil)),
+		Localize(l, leagueTableHeader, nil),
+		"|:---:|:---|---:|---:|---:|---:|",
+	}
+	for _, r := range g.Table {
+		lines = append(lines, fmt.Sprintf("|%d|@%s|%d|%d|%d|%d|", r.Rank, usernames[r.UserID], r.Wins, r.Draws, r.Losses, r.Points))
+	}
+	return strings.Join(lines, "\n")
+}
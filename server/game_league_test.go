@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestGameImplLeague(t *testing.T) {
+	t.Run("newGameImplLeague", func(t *testing.T) {
+		expectedGame := &gameImplLeague{}
+		g := newGameImplLeague()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("getResult", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MaxRounds     int
+			participants  []*participant
+			ExpectedRanks map[string]int
+			ExpectedTable []*leagueRecord
+		}{
+			"ranked by points": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"scissors"}},
+					{UserID: "p3", Hands: []string{"scissors"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 2, "p3": 2},
+				ExpectedTable: []*leagueRecord{
+					{UserID: "p1", Wins: 2, Draws: 0, Losses: 0, Points: 6, Rank: 1},
+					{UserID: "p2", Wins: 0, Draws: 1, Losses: 1, Points: 1, Rank: 2},
+					{UserID: "p3", Wins: 0, Draws: 1, Losses: 1, Points: 1, Rank: 2},
+				},
+			},
+			"all matches are drawn": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"rock"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 1},
+				ExpectedTable: []*leagueRecord{
+					{UserID: "p1", Wins: 0, Draws: 1, Losses: 0, Points: 1, Rank: 1},
+					{UserID: "p2", Wins: 0, Draws: 1, Losses: 0, Points: 1, Rank: 1},
+				},
+			},
+			"cyclic results are not broken": {
+				MaxRounds: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"scissors"}},
+					{UserID: "p3", Hands: []string{"paper"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 1, "p3": 1},
+				ExpectedTable: []*leagueRecord{
+					{UserID: "p1", Wins: 1, Draws: 0, Losses: 1, Points: 3, Rank: 1},
+					{UserID: "p2", Wins: 1, Draws: 0, Losses: 1, Points: 3, Rank: 1},
+					{UserID: "p3", Wins: 1, Draws: 0, Losses: 1, Points: 3, Rank: 1},
+				},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				g := &gameImplLeague{}
+				b := newGame(g)
+				b.MaxRounds = test.MaxRounds
+				b.Participants = test.participants
+
+				result := g.getResult(b)
+
+				assert.Len(result, len(test.participants))
+				for _, r := range result {
+					assert.Equal(test.ExpectedRanks[r.UserID], r.Rank, r.UserID)
+				}
+				assert.Equal(test.ExpectedTable, g.Table)
+			})
+		}
+	})
+
+	t.Run("rankTable", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLeague{
+			Table: []*leagueRecord{
+				{UserID: "p1", Wins: 1, Losses: 2, Points: 3},
+				{UserID: "p2", Wins: 1, Losses: 2, Points: 3},
+				{UserID: "p3", Wins: 2, Losses: 1, Points: 6},
+				{UserID: "p4", Wins: 2, Losses: 1, Points: 6},
+			},
+			Matches: []*leagueMatch{
+				{Player1: "p1", Player2: "p2", Winner: "p2"},
+				{Player1: "p1", Player2: "p3", Winner: "p1"},
+				{Player1: "p1", Player2: "p4", Winner: "p4"},
+				{Player1: "p2", Player2: "p3", Winner: "p3"},
+				{Player1: "p2", Player2: "p4", Winner: "p4"},
+				{Player1: "p3", Player2: "p4", Winner: "p3"},
+			},
+		}
+
+		g.rankTable()
+
+		ranks := map[string]int{}
+		for _, r := range g.Table {
+			ranks[r.UserID] = r.Rank
+		}
+		// 勝ち点が同じp3とp4，p1とp2は直接対戦の結果で順位が決まる
+		assert.Equal(map[string]int{"p3": 1, "p4": 2, "p2": 3, "p1": 4}, ranks)
+		assert.Equal("p3", g.Table[0].UserID)
+		assert.Equal("p1", g.Table[3].UserID)
+	})
+
+	t.Run("getResultDetail", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLeague{
+			Table: []*leagueRecord{
+				{UserID: "p1", Wins: 1, Draws: 0, Losses: 0, Points: 3, Rank: 1},
+				{UserID: "p2", Wins: 0, Draws: 0, Losses: 1, Points: 0, Rank: 2},
+			},
+		}
+		l := i18n.NewLocalizer(i18n.NewBundle(language.English))
+		usernames := map[string]string{"p1": "user1", "p2": "user2"}
+
+		detail := g.getResultDetail(l, usernames)
+
+		assert.Equal(`**League table**
+|Rank|Username|W|D|L|Points|
+|:---:|:---|---:|---:|---:|---:|
+|1|@user1|1|0|0|3|
+|2|@user2|0|0|1|0|`, detail)
+	})
+}
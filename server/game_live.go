@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// liveRoundTimeoutSeconds はライブ対戦で1回のジャンケンの手を待つ秒数
+	liveRoundTimeoutSeconds int64 = 60
+)
+
+const (
+	liveStateWaiting  = ""
+	liveStatePlaying  = "playing"
+	liveStateFinished = "finished"
+)
+
+var (
+	errLiveNotPlaying     = errors.New("the game is not playing")
+	errLiveNotYourTurn    = errors.New("the user is not in the current group")
+	errLiveAlreadyPlayed  = errors.New("the user already played a hand in this round")
+	errLiveInvalidHand    = errors.New("the hand is not available")
+	errLiveAlreadyStarted = errors.New("the game is already started")
+)
+
+// liveGroup は同じ順位を争っている参加者のグループ
+type liveGroup struct {
+	// メンバーのUserID
+	Members []string `json:"members"`
+	// 何手目のジャンケンか
+	Round int `json:"round"`
+	// このグループの最上位の順位
+	Rank int `json:"rank"`
+}
+
+/*
+gameImplLive はライブ対戦のゲーム．
+事前に手を登録せず，1回のジャンケンごとに現在のグループのメンバーが手を出す．
+全員が手を出すか時間切れになるとジャンケンの結果を公開して次のグループに進む
+*/
+type gameImplLive struct {
+	gameBase
+	State string `json:"state"`
+	// 順位が決まっていないグループ（順位の昇順）
+	Groups []*liveGroup `json:"groups"`
+	// 現在のグループのジャンケンの開始日時
+	RoundStartedAt int64 `json:"round_started_at"`
+}
+
+func newGameImplLive() gameInterface {
+	return &gameImplLive{}
+}
+
+/*
+getResult はライブ対戦の結果を返す．
+まだ順位が決まっていない参加者がいる場合は，残りの手をランダムに出して最後まで進める
+*/
+func (g *gameImplLive) getResult(game *game) []*participant {
+	g.base = game
+	if g.State == liveStateWaiting {
+		g.start(game)
+	}
+	for g.State == liveStatePlaying {
+		g.fillMissingHands(game)
+		g.advance(game)
+	}
+
+	result := make([]*participant, len(game.Participants))
+	copy(result, game.Participants)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Rank < result[j].Rank
+	})
+	return result
+}
+
+// isPlaying はライブ対戦中かを返す
+func (g *gameImplLive) isPlaying() bool {
+	return g.State == liveStatePlaying
+}
+
+// isFinished は全員の順位が決まったかを返す
+func (g *gameImplLive) isFinished() bool {
+	return g.State == liveStateFinished
+}
+
+// start は参加者全員を1つのグループにして対戦を開始する
+func (g *gameImplLive) start(game *game) error {
+	if g.State != liveStateWaiting {
+		return errLiveAlreadyStarted
+	}
+	g.base = game
+	game.Rounds = nil
+
+	members := make([]string, 0, len(game.Participants))
+	for _, p := range game.Participants {
+		// 登録済みの手は使わないのでコミットメントも取り消す
+		p.growHands()
+		p.clearHandsAfter(0)
+		p.CommittedHands, p.Salt, p.Commitment = nil, "", ""
+		members = append(members, p.UserID)
+	}
+	g.Groups = []*liveGroup{{Members: members, Round: 0, Rank: 1}}
+	g.State = liveStatePlaying
+	g.settle(game)
+	g.RoundStartedAt = model.GetMillis()
+	return nil
+}
+
+// currentGroup は現在ジャンケンを行っているグループを返す
+func (g *gameImplLive) currentGroup() *liveGroup {
+	if !g.isPlaying() || len(g.Groups) == 0 {
+		return nil
+	}
+	return g.Groups[0]
+}
+
+/*
+members はグループのメンバーのうち参加者として残っている参加者を返す．
+開始後に参加を取り消したメンバーは参加者にいないので含めない
+*/
+func (g *gameImplLive) members(game *game, group *liveGroup) []*participant {
+	participants := make([]*participant, 0, len(group.Members))
+	for _, userID := range group.Members {
+		if p := game.GetParticipant(userID); p != nil {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+// waitingFor は現在のグループで手を出していないメンバーを返す
+func (g *gameImplLive) waitingFor(game *game) []string {
+	group := g.currentGroup()
+	if group == nil {
+		return nil
+	}
+	waiting := []string{}
+	for _, p := range g.members(game, group) {
+		if p.Hands[group.Round] == "" {
+			waiting = append(waiting, p.UserID)
+		}
+	}
+	return waiting
+}
+
+// play は現在のグループのメンバーが手を出す
+func (g *gameImplLive) play(game *game, userID, hand string) error {
+	group := g.currentGroup()
+	if group == nil {
+		return errLiveNotPlaying
+	}
+	if !game.getHandSet().contains(hand) {
+		return errLiveInvalidHand
+	}
+
+	for _, p := range g.members(game, group) {
+		if p.UserID != userID {
+			continue
+		}
+		if p.Hands[group.Round] != "" {
+			return errLiveAlreadyPlayed
+		}
+		p.Hands[group.Round] = hand
+		return nil
+	}
+	return errLiveNotYourTurn
+}
+
+// isTimedOut は現在のジャンケンの待ち時間を過ぎているかを返す
+func (g *gameImplLive) isTimedOut(now int64) bool {
+	return g.isPlaying() && now >= g.RoundStartedAt+liveRoundTimeoutSeconds*1000
+}
+
+// fillMissingHands は現在のグループで手を出していないメンバーの手をシードから決める
+func (g *gameImplLive) fillMissingHands(game *game) {
+	group := g.currentGroup()
+	if group == nil {
+		return
+	}
+	for _, p := range g.members(game, group) {
+		game.fillHand(p, group.Round)
+	}
+}
+
+/*
+advance は現在のグループの全員が手を出していればジャンケンを行い，次のグループに進む．
+Returns:
+    *roundEvent: 行ったジャンケンの結果．まだ手を出していないメンバーがいる場合はnil
+*/
+func (g *gameImplLive) advance(game *game) *roundEvent {
+	g.base = game
+	group := g.currentGroup()
+	if group == nil || len(g.waitingFor(game)) > 0 {
+		return nil
+	}
+
+	participants := g.members(game, group)
+	// 参加を取り消したメンバーを除くと対戦相手がいない場合はジャンケンせずに順位を決める
+	if len(participants) < 2 {
+		g.settle(game)
+		g.RoundStartedAt = model.GetMillis()
+		return nil
+	}
+
+	// ジャンケンを1回実行
+	winner, loser, drawer := g.janken(participants, group.Round)
+
+	if drawer != nil {
+		// あいこの処理
+		group.Round++
+	} else {
+		// 勝者と敗者のグループに分ける
+		winnerGroup := &liveGroup{Members: userIDs(winner), Round: group.Round + 1, Rank: group.Rank}
+		loserGroup := &liveGroup{Members: userIDs(loser), Round: group.Round + 1, Rank: group.Rank + len(winner)}
+		g.Groups = append([]*liveGroup{winnerGroup, loserGroup}, g.Groups[1:]...)
+	}
+
+	g.settle(game)
+	g.RoundStartedAt = model.GetMillis()
+	return game.Rounds[len(game.Rounds)-1]
+}
+
+/*
+settle は順位が決まったグループに順位をつけて取り除く．
+1人になったグループと最大対戦回数に達したグループは順位が決まる
+*/
+func (g *gameImplLive) settle(game *game) {
+	for len(g.Groups) > 0 {
+		group := g.Groups[0]
+		members := g.members(game, group)
+		if len(members) > 1 && group.Round < game.MaxRounds {
+			return
+		}
+		for _, p := range members {
+			p.Rank = group.Rank
+			p.clearHandsAfter(group.Round)
+		}
+		g.Groups = g.Groups[1:]
+	}
+	g.State = liveStateFinished
+}
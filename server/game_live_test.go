@@ -0,0 +1,198 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLiveTestGame(userIDs ...string) (*game, *gameImplLive) {
+	g := &gameImplLive{}
+	b := newGame(g)
+	for _, userID := range userIDs {
+		b.Participants = append(b.Participants, newParticipant(userID))
+	}
+	return b, g
+}
+
+func TestGameImplLive(t *testing.T) {
+	t.Run("newGameImplLive", func(t *testing.T) {
+		expectedGame := &gameImplLive{}
+		g := newGameImplLive()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("start", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2", "p3")
+		b.Participants[0].Hands[0] = "rock"
+
+		assert.Nil(g.start(b))
+		assert.True(g.isPlaying())
+		assert.Equal([]*liveGroup{{Members: []string{"p1", "p2", "p3"}, Round: 0, Rank: 1}}, g.Groups)
+		assert.Equal("", b.Participants[0].Hands[0])
+		assert.NotZero(g.RoundStartedAt)
+
+		assert.Equal(errLiveAlreadyStarted, g.start(b))
+	})
+
+	t.Run("start after joining again without hands", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2")
+		// ライブ対戦の参加ダイアログには手の欄がない
+		b.UpdateHands("p1", []string{})
+
+		assert.Nil(g.start(b))
+		assert.Nil(g.play(b, "p1", "rock"))
+	})
+
+	t.Run("play", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2")
+
+		assert.Equal(errLiveNotPlaying, g.play(b, "p1", "rock"))
+
+		g.start(b)
+		assert.Equal(errLiveInvalidHand, g.play(b, "p1", "lizard"))
+		assert.Equal(errLiveNotYourTurn, g.play(b, "p3", "rock"))
+		assert.Nil(g.play(b, "p1", "rock"))
+		assert.Equal(errLiveAlreadyPlayed, g.play(b, "p1", "paper"))
+		assert.Equal([]string{"p2"}, g.waitingFor(b))
+	})
+
+	t.Run("advance round by round", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2", "p3")
+		g.start(b)
+
+		// 全員が手を出すまでは進まない
+		g.play(b, "p1", "rock")
+		g.play(b, "p2", "scissors")
+		assert.Nil(g.advance(b))
+
+		g.play(b, "p3", "scissors")
+		assert.Equal(&roundEvent{
+			Round:   0,
+			Members: []string{"p1", "p2", "p3"},
+			Hands:   []string{"rock", "scissors", "scissors"},
+			Winners: []string{"p1"},
+			Losers:  []string{"p2", "p3"},
+		}, g.advance(b))
+		assert.Equal(1, b.GetParticipant("p1").Rank)
+		assert.Equal([]*liveGroup{{Members: []string{"p2", "p3"}, Round: 1, Rank: 2}}, g.Groups)
+
+		// 先に順位が決まった参加者は手を出せない
+		assert.Equal(errLiveNotYourTurn, g.play(b, "p1", "rock"))
+
+		g.play(b, "p2", "rock")
+		g.play(b, "p3", "rock")
+		assert.Equal(&roundEvent{
+			Round:   1,
+			Members: []string{"p2", "p3"},
+			Hands:   []string{"rock", "rock"},
+			Draw:    true,
+		}, g.advance(b))
+		assert.Equal(2, g.currentGroup().Round)
+
+		g.play(b, "p2", "paper")
+		g.play(b, "p3", "rock")
+		assert.Equal([]string{"p2"}, g.advance(b).Winners)
+		assert.True(g.isFinished())
+		assert.Nil(g.currentGroup())
+		assert.Len(b.Rounds, 3)
+
+		result := g.getResult(b)
+		ranks := map[string]int{}
+		for _, p := range result {
+			ranks[p.UserID] = p.Rank
+		}
+		assert.Equal(map[string]int{"p1": 1, "p2": 2, "p3": 3}, ranks)
+		assert.Equal([]string{"rock", "", ""}, b.GetParticipant("p1").Hands[:3])
+		assert.Equal([]string{"scissors", "rock", "paper"}, b.GetParticipant("p2").Hands[:3])
+	})
+
+	t.Run("settle at max rounds", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2")
+		b.MaxRounds = 1
+		g.start(b)
+
+		g.play(b, "p1", "rock")
+		g.play(b, "p2", "rock")
+		assert.True(g.advance(b).Draw)
+		assert.True(g.isFinished())
+		assert.Equal(1, b.GetParticipant("p1").Rank)
+		assert.Equal(1, b.GetParticipant("p2").Rank)
+	})
+
+	t.Run("fillMissingHands", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2")
+		g.start(b)
+		g.play(b, "p1", "rock")
+
+		g.fillMissingHands(b)
+
+		assert.Empty(g.waitingFor(b))
+		assert.Equal("rock", b.GetParticipant("p1").Hands[0])
+		assert.True(b.getHandSet().contains(b.GetParticipant("p2").Hands[0]))
+	})
+
+	t.Run("a participant removed after the start", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("a", "b", "c")
+		g.start(b)
+		b.RemoveParticipant("c")
+
+		assert.Equal([]string{"a", "b"}, g.waitingFor(b))
+		assert.Equal(errLiveNotYourTurn, g.play(b, "c", "rock"))
+		g.fillMissingHands(b)
+		assert.Empty(g.waitingFor(b))
+
+		result := g.getResult(b)
+
+		assert.True(g.isFinished())
+		assert.Len(result, 2)
+		for _, p := range result {
+			assert.NotZero(p.Rank)
+		}
+	})
+
+	t.Run("a group left with one participant", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("a", "b")
+		g.start(b)
+		b.RemoveParticipant("b")
+		assert.Nil(g.play(b, "a", "rock"))
+
+		assert.Nil(g.advance(b))
+		assert.True(g.isFinished())
+		assert.Equal(1, b.GetParticipant("a").Rank)
+	})
+
+	t.Run("isTimedOut", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLive{State: liveStatePlaying, RoundStartedAt: 1000}
+		assert.False(g.isTimedOut(1000 + liveRoundTimeoutSeconds*1000 - 1))
+		assert.True(g.isTimedOut(1000 + liveRoundTimeoutSeconds*1000))
+
+		g.State = liveStateFinished
+		assert.False(g.isTimedOut(1000 + liveRoundTimeoutSeconds*1000))
+	})
+
+	t.Run("getResult finishes the game with random hands", func(t *testing.T) {
+		assert := assert.New(t)
+		b, g := newLiveTestGame("p1", "p2", "p3", "p4")
+
+		result := g.getResult(b)
+
+		assert.True(g.isFinished())
+		assert.Len(result, 4)
+		for i, p := range result {
+			assert.NotZero(p.Rank)
+			if i > 0 {
+				assert.LessOrEqual(result[i-1].Rank, p.Rank)
+			}
+		}
+	})
+}
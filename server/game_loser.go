@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var (
+	loserResultMessage = &i18n.Message{
+		ID:    "loserResultMessage",
+		Other: "The loser is {{.Usernames}}. Everyone else survived.",
+	}
+	loserResultNote = &i18n.Message{
+		ID:    "loserResultNote",
+		Other: ":skull: Loser",
+	}
+)
+
+type gameImplLoser struct {
+	gameBase
+	// 負けが決まった参加者のUserID
+	Losers []string `json:"losers"`
+}
+
+func newGameImplLoser() gameInterface {
+	return &gameImplLoser{}
+}
+
+/*
+getResult は負けた1人が決まるまでジャンケンを行った結果を返す．
+各ジャンケンで勝った参加者は抜けて同率1位になり，負けた参加者だけでジャンケンを続ける．
+最大対戦回数に達した場合は残っている参加者全員が負けになる
+*/
+func (g *gameImplLoser) getResult(game *game) []*participant {
+	g.base = game
+	g.Losers = nil
+	game.Rounds = nil
+
+	startRound := 0 // Handsの利用開始番号
+	losers := g.nextRound(game.Participants, game.MaxRounds, startRound)
+
+	isLoser := make(map[string]bool)
+	for _, p := range losers {
+		isLoser[p.UserID] = true
+		g.Losers = append(g.Losers, p.UserID)
+	}
+	loserRank := len(game.Participants) - len(losers) + 1
+
+	result := make([]*participant, len(game.Participants))
+	copy(result, game.Participants)
+	for _, p := range result {
+		if isLoser[p.UserID] {
+			p.Rank = loserRank
+		} else {
+			p.Rank = 1
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Rank < result[j].Rank
+	})
+	return result
+}
+
+/*
+負けた参加者を決めるための再帰関数
+Args:
+    participants: 今評価中のジャンケンの参加者
+    maxRounds: 最大対戦回数
+    round: 現在のラウンド
+Returns:
+    []*participant: 負けた参加者
+*/
+func (g *gameImplLoser) nextRound(participants []*participant, maxRounds, round int) []*participant {
+	/*
+		終了条件1: 1人になった場合
+		残っている1人が負け
+	*/
+	if len(participants) == 1 {
+		participants[0].clearHandsAfter(round)
+		return participants
+	}
+
+	/*
+		終了条件2: 最大対戦回数に達した場合
+		残っている全員が負け
+	*/
+	if round >= maxRounds {
+		return participants
+	}
+
+	// ジャンケンを1回実行
+	winner, loser, drawer := g.janken(participants, round)
+
+	if drawer != nil {
+		// あいこの処理
+		return g.nextRound(drawer, maxRounds, round+1)
+	}
+	// 勝者はここで抜ける
+	for _, p := range winner {
+		p.clearHandsAfter(round + 1)
+	}
+	// 敗者の処理
+	return g.nextRound(loser, maxRounds, round+1)
+}
+
+// getResultDetail は負けた参加者を返す
+func (g *gameImplLoser) getResultDetail(l *i18n.Localizer, usernames map[string]string) string {
+	losers := make([]string, 0, len(g.Losers))
+	for _, userID := range g.Losers {
+		losers = append(losers, fmt.Sprintf("@%s", usernames[userID]))
+	}
+	return Localize(l, loserResultMessage, map[string]interface{}{
+		"Usernames": strings.Join(losers, ", "),
+	})
+}
+
+// getResultNote は負けた参加者に印をつける
+func (g *gameImplLoser) getResultNote(l *i18n.Localizer, p *participant) string {
+	for _, userID := range g.Losers {
+		if userID == p.UserID {
+			return Localize(l, loserResultNote, nil)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestGameImplLoser(t *testing.T) {
+	t.Run("newGameImplLoser", func(t *testing.T) {
+		expectedGame := &gameImplLoser{}
+		g := newGameImplLoser()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("getResult", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MaxRounds      int
+			participants   []*participant
+			ExpectedRanks  map[string]int
+			ExpectedLosers []string
+			ExpectedHands  map[string][]string
+		}{
+			"1 loser": {
+				MaxRounds: 3,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock", "rock"}},
+					{UserID: "p2", Hands: []string{"scissors", "rock", "paper"}},
+					{UserID: "p3", Hands: []string{"scissors", "scissors", "rock"}},
+					{UserID: "p4", Hands: []string{"rock", "paper", "paper"}},
+				},
+				ExpectedRanks:  map[string]int{"p1": 1, "p2": 1, "p3": 4, "p4": 1},
+				ExpectedLosers: []string{"p3"},
+				ExpectedHands: map[string][]string{
+					"p1": {"rock", "", ""},
+					"p2": {"scissors", "rock", ""},
+					"p3": {"scissors", "scissors", ""},
+					"p4": {"rock", "", ""},
+				},
+			},
+			"drawn until max rounds": {
+				MaxRounds: 2,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock"}},
+					{UserID: "p2", Hands: []string{"scissors", "paper"}},
+					{UserID: "p3", Hands: []string{"scissors", "paper"}},
+				},
+				ExpectedRanks:  map[string]int{"p1": 1, "p2": 2, "p3": 2},
+				ExpectedLosers: []string{"p2", "p3"},
+				ExpectedHands: map[string][]string{
+					"p1": {"rock", ""},
+					"p2": {"scissors", "paper"},
+					"p3": {"scissors", "paper"},
+				},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				g := &gameImplLoser{}
+				b := newGame(g)
+				b.MaxRounds = test.MaxRounds
+				b.Participants = test.participants
+
+				result := g.getResult(b)
+
+				assert.Len(result, len(test.participants))
+				for _, r := range result {
+					assert.Equal(test.ExpectedRanks[r.UserID], r.Rank, r.UserID)
+					assert.Equal(test.ExpectedHands[r.UserID], r.Hands, r.UserID)
+				}
+				assert.Equal(test.ExpectedLosers, g.Losers)
+			})
+		}
+	})
+
+	t.Run("getResultDetail and getResultNote", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLoser{Losers: []string{"p2"}}
+		l := i18n.NewLocalizer(i18n.NewBundle(language.English))
+		usernames := map[string]string{"p1": "user1", "p2": "user2"}
+
+		assert.Equal("The loser is @user2. Everyone else survived.", g.getResultDetail(l, usernames))
+		assert.Equal("", g.getResultNote(l, &participant{UserID: "p1"}))
+		assert.Equal(":skull: Loser", g.getResultNote(l, &participant{UserID: "p2"}))
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var (
+	lotteryResultNote = &i18n.Message{
+		ID:    "lotteryResultNote",
+		Other: ":tada: Winner",
+	}
+)
+
+type gameImplLottery struct {
+	gameBase
+	// 当選した参加者のUserID
+	Winners []string `json:"winners"`
+}
+
+func newGameImplLottery() gameInterface {
+	return &gameImplLottery{}
+}
+
+/*
+getResult は指定した人数の当選者が決まるまでジャンケンを行った結果を返す．
+当選者は同率1位，それ以外の参加者は順位なし(0)になる
+*/
+func (g *gameImplLottery) getResult(game *game) []*participant {
+	g.base = game
+	g.Winners = nil
+	game.Rounds = nil
+
+	startRound := 0 // Handsの利用開始番号
+	winners := g.nextRound(game.Participants, game.MaxRounds, startRound, game.NumWinners)
+
+	isWinner := make(map[string]bool)
+	for _, p := range winners {
+		isWinner[p.UserID] = true
+		g.Winners = append(g.Winners, p.UserID)
+	}
+
+	result := make([]*participant, len(game.Participants))
+	copy(result, game.Participants)
+	for _, p := range result {
+		if isWinner[p.UserID] {
+			p.Rank = 1
+		} else {
+			p.Rank = 0
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return isWinner[result[i].UserID] && !isWinner[result[j].UserID]
+	})
+	return result
+}
+
+/*
+当選者を決めるための再帰関数
+Args:
+    participants: 今評価中のジャンケンの参加者
+    maxRounds: 最大対戦回数
+    round: 現在のラウンド
+    numWinners: participantsから選ぶ当選者の人数
+Returns:
+    []*participant: 当選者
+*/
+func (g *gameImplLottery) nextRound(participants []*participant, maxRounds, round, numWinners int) []*participant {
+	/*
+		終了条件1: これ以上当選者を選ばない場合
+		残っている全員が落選
+	*/
+	if numWinners <= 0 {
+		for _, p := range participants {
+			p.clearHandsAfter(round)
+		}
+		return nil
+	}
+
+	/*
+		終了条件2: 残っている人数が当選者の人数以下の場合
+		残っている全員が当選
+	*/
+	if len(participants) <= numWinners {
+		for _, p := range participants {
+			p.clearHandsAfter(round)
+		}
+		return participants
+	}
+
+	/*
+		終了条件3: 最大対戦回数に達した場合
+		残っている参加者から抽選する
+	*/
+	if round >= maxRounds {
+		shuffled := make([]*participant, len(participants))
+		copy(shuffled, participants)
+		g.base.rand().shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return shuffled[:numWinners]
+	}
+
+	// ジャンケンを1回実行
+	winner, loser, drawer := g.janken(participants, round)
+
+	if drawer != nil {
+		// あいこの処理
+		return g.nextRound(drawer, maxRounds, round+1, numWinners)
+	}
+	if len(winner) <= numWinners {
+		// 勝者は全員当選，残りは敗者から選ぶ
+		result := g.nextRound(winner, maxRounds, round+1, len(winner))
+		return append(result, g.nextRound(loser, maxRounds, round+1, numWinners-len(winner))...)
+	}
+	// 勝者から選ぶ，敗者は全員落選
+	result := g.nextRound(winner, maxRounds, round+1, numWinners)
+	g.nextRound(loser, maxRounds, round+1, 0)
+	return result
+}
+
+// getResultNote は当選した参加者に印をつける
+func (g *gameImplLottery) getResultNote(l *i18n.Localizer, p *participant) string {
+	for _, userID := range g.Winners {
+		if userID == p.UserID {
+			return Localize(l, lotteryResultNote, nil)
+		}
+	}
+	return ""
+}
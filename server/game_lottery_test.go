@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestGameImplLottery(t *testing.T) {
+	t.Run("newGameImplLottery", func(t *testing.T) {
+		expectedGame := &gameImplLottery{}
+		g := newGameImplLottery()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("getResult", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MaxRounds       int
+			NumWinners      int
+			participants    []*participant
+			ExpectedWinners []string
+		}{
+			"winners of the first janken are just enough": {
+				MaxRounds:  2,
+				NumWinners: 2,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock"}},
+					{UserID: "p2", Hands: []string{"scissors", "rock"}},
+					{UserID: "p3", Hands: []string{"rock", "paper"}},
+				},
+				ExpectedWinners: []string{"p1", "p3"},
+			},
+			"winners are picked from the winners": {
+				MaxRounds:  2,
+				NumWinners: 1,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock"}},
+					{UserID: "p2", Hands: []string{"scissors", "rock"}},
+					{UserID: "p3", Hands: []string{"rock", "paper"}},
+				},
+				ExpectedWinners: []string{"p3"},
+			},
+			"winners are picked from the losers too": {
+				MaxRounds:  2,
+				NumWinners: 3,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock", "rock"}},
+					{UserID: "p2", Hands: []string{"scissors", "rock"}},
+					{UserID: "p3", Hands: []string{"scissors", "scissors"}},
+					{UserID: "p4", Hands: []string{"rock", "paper"}},
+				},
+				ExpectedWinners: []string{"p1", "p4", "p2"},
+			},
+			"more winners than participants": {
+				MaxRounds:  1,
+				NumWinners: 3,
+				participants: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"scissors"}},
+				},
+				ExpectedWinners: []string{"p1", "p2"},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				g := &gameImplLottery{}
+				b := newGame(g)
+				b.MaxRounds = test.MaxRounds
+				b.NumWinners = test.NumWinners
+				b.Participants = test.participants
+
+				result := g.getResult(b)
+
+				assert.Len(result, len(test.participants))
+				assert.Equal(test.ExpectedWinners, g.Winners)
+				for i, r := range result {
+					if i < len(test.ExpectedWinners) {
+						assert.Equal(1, r.Rank, r.UserID)
+					} else {
+						assert.Equal(0, r.Rank, r.UserID)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("getResult when all rounds are drawn", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLottery{}
+		b := newGame(g)
+		b.MaxRounds = 1
+		b.NumWinners = 2
+		b.Participants = []*participant{
+			{UserID: "p1", Hands: []string{"rock"}},
+			{UserID: "p2", Hands: []string{"rock"}},
+			{UserID: "p3", Hands: []string{"rock"}},
+		}
+
+		g.getResult(b)
+
+		// 最大対戦回数に達した場合は抽選で指定した人数が選ばれる
+		assert.Len(g.Winners, 2)
+	})
+
+	t.Run("getResultNote", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplLottery{Winners: []string{"p1"}}
+		l := i18n.NewLocalizer(i18n.NewBundle(language.English))
+
+		assert.Equal(":tada: Winner", g.getResultNote(l, &participant{UserID: "p1"}))
+		assert.Equal("", g.getResultNote(l, &participant{UserID: "p2"}))
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var (
+	teamTableTitle = &i18n.Message{
+		ID:    "teamTableTitle",
+		Other: "Teams",
+	}
+	teamTableHeader = &i18n.Message{
+		ID:    "teamTableHeader",
+		Other: "|Rank|Team|Hands|Members|",
+	}
+)
+
+// teamResult はチーム戦の1チーム分の結果
+type teamResult struct {
+	Name string `json:"name"`
+	Rank int    `json:"rank"`
+	// チームとして出した手
+	Hands []string `json:"hands"`
+	// メンバーのUserID
+	Members []string `json:"members"`
+}
+
+type gameImplTeam struct {
+	gameBase
+	// チームごとの結果
+	Teams []*teamResult `json:"teams"`
+}
+
+func newGameImplTeam() gameInterface {
+	return &gameImplTeam{}
+}
+
+/*
+getResult はチーム戦のジャンケンの結果を返す．
+各ラウンドでメンバーが出した手のうち最も多い手をチームの手として，チーム同士でジャンケンを行う．
+メンバーの順位は所属するチームの順位になる．チームに所属していない参加者は1人のチームとして扱う
+*/
+func (g *gameImplTeam) getResult(game *game) []*participant {
+	g.base = game
+	g.Teams = nil
+	game.Rounds = nil
+
+	teams := game.getTeams()
+
+	// チームをparticipantとしてジャンケンを行う
+	teamParticipants := make([]*participant, len(teams))
+	for i, members := range teams {
+		tp := &participant{
+			UserID: members[0].teamName(),
+			Hands:  make([]string, game.MaxRounds),
+		}
+		for round := 0; round < game.MaxRounds; round++ {
+			for _, p := range members {
+				game.fillHand(p, round)
+			}
+			tp.Hands[round] = majorityHand(members, round)
+		}
+		teamParticipants[i] = tp
+	}
+	ranker := &gameImpl1{}
+	ranker.base = game
+	ranker.nextRound(teamParticipants, game.MaxRounds, 0, 1, nil)
+
+	// チームの順位をメンバーに反映する
+	result := make([]*participant, 0, len(game.Participants))
+	for i, members := range teams {
+		tp := teamParticipants[i]
+		team := &teamResult{
+			Name:  tp.UserID,
+			Rank:  tp.Rank,
+			Hands: tp.Hands,
+		}
+		for _, p := range members {
+			p.Rank = tp.Rank
+			p.clearHandsAfter(playedRounds(tp.Hands))
+			team.Members = append(team.Members, p.UserID)
+			result = append(result, p)
+		}
+		g.Teams = append(g.Teams, team)
+	}
+
+	sort.SliceStable(g.Teams, func(i, j int) bool {
+		return g.Teams[i].Rank < g.Teams[j].Rank
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Rank < result[j].Rank
+	})
+	return result
+}
+
+/*
+majorityHand はメンバーがroundで出した手のうち最も多い手を返す．
+最も多い手が複数ある場合は先に参加したメンバーの手を優先する
+*/
+func majorityHand(members []*participant, round int) string {
+	counts := make(map[string]int)
+	for _, p := range members {
+		counts[p.Hands[round]]++
+	}
+
+	var hand string
+	for _, p := range members {
+		h := p.Hands[round]
+		if counts[h] > counts[hand] {
+			hand = h
+		}
+	}
+	return hand
+}
+
+// playedRounds はジャンケンで使われた手の数を返す
+func playedRounds(hands []string) int {
+	for i, h := range hands {
+		if h == "" {
+			return i
+		}
+	}
+	return len(hands)
+}
+
+// getResultDetail はチームごとの結果を返す
+func (g *gameImplTeam) getResultDetail(l *i18n.Localizer, usernames map[string]string) string {
+	hs := g.getHandSet()
+	lines := []string{
+		fmt.Sprintf("**%s**", Localize(l, teamTableTitle, nil)),
+		Localize(l, teamTableHeader, nil),
+		"|:---:|:---|:---|:---|",
+	}
+	for _, t := range g.Teams {
+		hands := make([]string, 0, len(t.Hands))
+		for _, h := range t.Hands {
+			hands = append(hands, hs.icon(h))
+		}
+		members := make([]string, 0, len(t.Members))
+		for _, userID := range t.Members {
+			members = append(members, fmt.Sprintf("@%s", usernames[userID]))
+		}
+		name := t.Name
+		if len(t.Members) == 1 && t.Members[0] == t.Name {
+			// チームに所属していない参加者
+			name = fmt.Sprintf("@%s", usernames[t.Name])
+		}
+		lines = append(lines, fmt.Sprintf("|%d|%s|%s|%s|", t.Rank, name, strings.TrimSpace(strings.Join(hands, " ")), strings.Join(members, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
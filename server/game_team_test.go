@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestGameImplTeam(t *testing.T) {
+	t.Run("newGameImplTeam", func(t *testing.T) {
+		expectedGame := &gameImplTeam{}
+		g := newGameImplTeam()
+		assert.Equal(t, expectedGame, g)
+	})
+
+	t.Run("getResult", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MaxRounds     int
+			participants  []*participant
+			ExpectedRanks map[string]int
+			ExpectedTeams []*teamResult
+		}{
+			"majority hands decide": {
+				MaxRounds: 2,
+				participants: []*participant{
+					{UserID: "p1", Team: "red", Hands: []string{"rock", "paper"}},
+					{UserID: "p2", Team: "blue", Hands: []string{"paper", "paper"}},
+					{UserID: "p3", Team: "red", Hands: []string{"rock", "scissors"}},
+					{UserID: "p4", Team: "blue", Hands: []string{"scissors", "rock"}},
+					{UserID: "p5", Team: "red", Hands: []string{"paper", "rock"}},
+					{UserID: "p6", Team: "blue", Hands: []string{"scissors", "rock"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 1, "p2": 2, "p3": 1, "p4": 2, "p5": 1, "p6": 2},
+				ExpectedTeams: []*teamResult{
+					{Name: "red", Rank: 1, Hands: []string{"rock", ""}, Members: []string{"p1", "p3", "p5"}},
+					{Name: "blue", Rank: 2, Hands: []string{"scissors", ""}, Members: []string{"p2", "p4", "p6"}},
+				},
+			},
+			"draw of majority hands and a participant without team": {
+				MaxRounds: 2,
+				participants: []*participant{
+					{UserID: "p1", Team: "red", Hands: []string{"rock", "paper"}},
+					{UserID: "p2", Team: "red", Hands: []string{"scissors", "rock"}},
+					{UserID: "p3", Hands: []string{"rock", "scissors"}},
+				},
+				ExpectedRanks: map[string]int{"p1": 2, "p2": 2, "p3": 1},
+				ExpectedTeams: []*teamResult{
+					{Name: "p3", Rank: 1, Hands: []string{"rock", "scissors"}, Members: []string{"p3"}},
+					{Name: "red", Rank: 2, Hands: []string{"rock", "paper"}, Members: []string{"p1", "p2"}},
+				},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				g := &gameImplTeam{}
+				b := newGame(g)
+				b.MaxRounds = test.MaxRounds
+				b.Participants = test.participants
+
+				result := g.getResult(b)
+
+				assert.Len(result, len(test.participants))
+				for _, r := range result {
+					assert.Equal(test.ExpectedRanks[r.UserID], r.Rank, r.UserID)
+				}
+				assert.Equal(test.ExpectedTeams, g.Teams)
+			})
+		}
+	})
+
+	t.Run("majorityHand", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			members      []*participant
+			ExpectedHand string
+		}{
+			"the most common hand": {
+				members: []*participant{
+					{UserID: "p1", Hands: []string{"rock"}},
+					{UserID: "p2", Hands: []string{"paper"}},
+					{UserID: "p3", Hands: []string{"paper"}},
+				},
+				ExpectedHand: "paper",
+			},
+			"the hand of the earlier member wins a tie": {
+				members: []*participant{
+					{UserID: "p1", Hands: []string{"scissors"}},
+					{UserID: "p2", Hands: []string{"paper"}},
+					{UserID: "p3", Hands: []string{"paper"}},
+					{UserID: "p4", Hands: []string{"scissors"}},
+				},
+				ExpectedHand: "scissors",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, test.ExpectedHand, majorityHand(test.members, 0))
+			})
+		}
+	})
+
+	t.Run("getResultDetail", func(t *testing.T) {
+		assert := assert.New(t)
+		g := &gameImplTeam{
+			Teams: []*teamResult{
+				{Name: "red", Rank: 1, Hands: []string{"rock", ""}, Members: []string{"p1", "p2"}},
+				{Name: "p3", Rank: 2, Hands: []string{"scissors", ""}, Members: []string{"p3"}},
+			},
+		}
+		l := i18n.NewLocalizer(i18n.NewBundle(language.English))
+		usernames := map[string]string{"p1": "user1", "p2": "user2", "p3": "user3"}
+
+		detail := g.getResultDetail(l, usernames)
+
+		assert.Equal(`**Teams**
+|Rank|Team|Hands|Members|
+|:---:|:---|:---|:---|
+|1|red|:fist_raised:|@user1, @user2|
+|2|@user3|:v:|@user3|`, detail)
+	})
+}
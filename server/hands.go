@@ -0,0 +1,174 @@
+package main
+
+import (
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	defaultHandSetName = "rps"
+)
+
+// handSet はジャンケンで使う手の組み合わせ
+type handSet struct {
+	// 識別名
+	Name string
+	// 出せる手（表示順）
+	Hands []string
+	// 各手が勝つ手
+	Beats map[string][]string
+	// emojiとの対応
+	Icons map[string]string
+	// 手の名前
+	Messages map[string]*i18n.Message
+	// 手の組み合わせの名前
+	Label *i18n.Message
+}
+
+var handMessages = map[string]*i18n.Message{
+	"rock": {
+		ID:    "joinDialogHandRock",
+		Other: "Rock",
+	},
+	"scissors": {
+		ID:    "joinDialogHandScissors",
+		Other: "Scissors",
+	},
+	"paper": {
+		ID:    "joinDialogHandPaper",
+		Other: "Paper",
+	},
+	"lizard": {
+		ID:    "joinDialogHandLizard",
+		Other: "Lizard",
+	},
+	"spock": {
+		ID:    "joinDialogHandSpock",
+		Other: "Spock",
+	},
+}
+
+var handIcons = map[string]string{
+	"rock":     ":fist_raised:",
+	"scissors": ":v:",
+	"paper":    ":hand:",
+	"lizard":   ":lizard:",
+	"spock":    ":vulcan_salute:",
+}
+
+var handSets = map[string]*handSet{
+	"rps": {
+		Name:  "rps",
+		Hands: []string{"rock", "scissors", "paper"},
+		Beats: map[string][]string{
+			"rock":     {"scissors"},
+			"scissors": {"paper"},
+			"paper":    {"rock"},
+		},
+		Icons:    handIcons,
+		Messages: handMessages,
+		Label: &i18n.Message{
+			ID:    "handSetRPSLabel",
+			Other: "Rock-paper-scissors",
+		},
+	},
+	"rpsls": {
+		Name:  "rpsls",
+		Hands: []string{"rock", "scissors", "paper", "lizard", "spock"},
+		Beats: map[string][]string{
+			"rock":     {"scissors", "lizard"},
+			"scissors": {"paper", "lizard"},
+			"paper":    {"rock", "spock"},
+			"lizard":   {"spock", "paper"},
+			"spock":    {"scissors", "rock"},
+		},
+		Icons:    handIcons,
+		Messages: handMessages,
+		Label: &i18n.Message{
+			ID:    "handSetRPSLSLabel",
+			Other: "Rock-paper-scissors-lizard-Spock",
+		},
+	},
+}
+
+// handSetNames は選択可能な手の組み合わせの識別名を表示順に返す
+var handSetNames = []string{"rps", "rpsls"}
+
+/*
+getHandSet は指定した名前の手の組み合わせを返す．
+存在しない名前の場合はデフォルトの手の組み合わせを返す
+*/
+func getHandSet(name string) *handSet {
+	if hs, ok := handSets[name]; ok {
+		return hs
+	}
+	return handSets[defaultHandSetName]
+}
+
+// isValidHandSet は指定した名前の手の組み合わせが存在するかを返す
+func isValidHandSet(name string) bool {
+	_, ok := handSets[name]
+	return ok
+}
+
+// contains は手の組み合わせに指定した手が含まれるかを返す
+func (hs *handSet) contains(hand string) bool {
+	for _, h := range hs.Hands {
+		if h == hand {
+			return true
+		}
+	}
+	return false
+}
+
+// beats は手aが手bに勝つかを返す
+func (hs *handSet) beats(a, b string) bool {
+	for _, h := range hs.Beats[a] {
+		if h == b {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+winningHands は出ている手の中から勝ちの手を返す．
+勝ちの手は出ている他のどの手にも負けない手．
+次のときは勝ちの手が決まらないのでnilを返す（あいこ）
+  - 出ている手が1種類のとき
+  - どの手も他のいずれかの手に負けるとき（すくみ）
+  - 出ている手のすべてが他のどの手にも負けないとき（勝ち負けのない組み合わせ）
+
+Args:
+    hands: 出ている手（重複なし）
+Returns:
+    []string: 勝ちの手
+*/
+func (hs *handSet) winningHands(hands []string) []string {
+	if len(hands) <= 1 {
+		return nil
+	}
+
+	var winHands []string
+	for _, h := range hands {
+		beaten := false
+		for _, other := range hands {
+			if hs.beats(other, h) {
+				beaten = true
+				break
+			}
+		}
+		if !beaten {
+			winHands = append(winHands, h)
+		}
+	}
+
+	if len(winHands) == 0 || len(winHands) == len(hands) {
+		return nil
+	}
+	return winHands
+}
+
+// icon は手に対応するemojiを返す．空の手には空文字を返す
+func (hs *handSet) icon(hand string) string {
+	return hs.Icons[hand]
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandSet(t *testing.T) {
+	t.Run("getHandSet", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			Name         string
+			ExpectedName string
+		}{
+			"rps":          {Name: "rps", ExpectedName: "rps"},
+			"rpsls":        {Name: "rpsls", ExpectedName: "rpsls"},
+			"empty name":   {Name: "", ExpectedName: "rps"},
+			"unknown name": {Name: "unknown", ExpectedName: "rps"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				hs := getHandSet(test.Name)
+
+				assert.Equal(test.ExpectedName, hs.Name)
+			})
+		}
+	})
+
+	t.Run("beats", func(t *testing.T) {
+		for _, name := range handSetNames {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				hs := getHandSet(name)
+
+				for _, a := range hs.Hands {
+					assert.NotEmpty(hs.icon(a))
+					assert.NotNil(hs.Messages[a])
+					for _, b := range hs.Hands {
+						if a == b {
+							assert.False(hs.beats(a, b))
+							continue
+						}
+						// 異なる手の組み合わせはどちらか一方だけが勝つ
+						assert.NotEqual(hs.beats(a, b), hs.beats(b, a), "%s vs %s", a, b)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("winningHands", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			HandSet          *handSet
+			Hands            []string
+			ExpectedWinHands []string
+		}{
+			"no hands": {
+				HandSet:          getHandSet("rps"),
+				Hands:            []string{},
+				ExpectedWinHands: nil,
+			},
+			"1 hand": {
+				HandSet:          getHandSet("rps"),
+				Hands:            []string{"rock"},
+				ExpectedWinHands: nil,
+			},
+			"2 hands in rps": {
+				HandSet:          getHandSet("rps"),
+				Hands:            []string{"paper", "scissors"},
+				ExpectedWinHands: []string{"scissors"},
+			},
+			"3 hands in rps": {
+				HandSet:          getHandSet("rps"),
+				Hands:            []string{"paper", "rock", "scissors"},
+				ExpectedWinHands: nil,
+			},
+			"3 hands with a winner in rpsls": {
+				HandSet:          getHandSet("rpsls"),
+				Hands:            []string{"paper", "rock", "spock"},
+				ExpectedWinHands: []string{"paper"},
+			},
+			"4 hands without a winner in rpsls": {
+				HandSet:          getHandSet("rpsls"),
+				Hands:            []string{"lizard", "paper", "rock", "spock"},
+				ExpectedWinHands: nil,
+			},
+			"4 hands with a winner in 7 hands": {
+				HandSet:          cyclicHandSet(7),
+				Hands:            []string{"h0", "h1", "h2", "h3"},
+				ExpectedWinHands: []string{"h0"},
+			},
+			"8 hands with a winner in 15 hands": {
+				HandSet:          cyclicHandSet(15),
+				Hands:            []string{"h0", "h1", "h2", "h3", "h4", "h5", "h6", "h7"},
+				ExpectedWinHands: []string{"h0"},
+			},
+			"3 hands without a winner in 15 hands": {
+				HandSet:          cyclicHandSet(15),
+				Hands:            []string{"h0", "h10", "h5"},
+				ExpectedWinHands: nil,
+			},
+			"hands which beat each other": {
+				HandSet: &handSet{
+					Hands: []string{"a", "b", "c"},
+					Beats: map[string][]string{"a": {"c"}},
+				},
+				Hands:            []string{"a", "b"},
+				ExpectedWinHands: nil,
+			},
+			"2 win hands": {
+				HandSet: &handSet{
+					Hands: []string{"a", "b", "c"},
+					Beats: map[string][]string{"a": {"c"}, "b": {"c"}},
+				},
+				Hands:            []string{"a", "b", "c"},
+				ExpectedWinHands: []string{"a", "b"},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				winHands := test.HandSet.winningHands(test.Hands)
+
+				assert.Equal(test.ExpectedWinHands, winHands)
+			})
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// subcommandHistory はチャンネルの結果を表示したゲームを一覧表示するサブコマンド
+	subcommandHistory = "history"
+
+	// defaultHistoryCount は一覧表示するゲームの数の初期値
+	defaultHistoryCount = 5
+	// maxHistoryCount は一覧表示できるゲームの数の上限
+	maxHistoryCount = 50
+)
+
+var (
+	historyTitle = &i18n.Message{
+		ID:    "historyTitle",
+		Other: "Latest finished janken games in this channel",
+	}
+	historySearchTitle = &i18n.Message{
+		ID:    "historySearchTitle",
+		Other: "Latest finished janken games in this channel matching \"{{.Query}}\"",
+	}
+	historyHeader = &i18n.Message{
+		ID: "historyHeader",
+		Other: `| ID | Game type | Title / Purpose | Age | Winners | Losers | Post |
+| --- | --- | --- | --- | --- | --- | --- |`,
+	}
+	historyEmptyMessage = &i18n.Message{
+		ID:    "historyEmptyMessage",
+		Other: "There are no finished janken games in this channel.",
+	}
+	historySearchEmptyMessage = &i18n.Message{
+		ID:    "historySearchEmptyMessage",
+		Other: "There are no finished janken games matching \"{{.Query}}\" in this channel.",
+	}
+	historyInvalidCountErrorMessage = &i18n.Message{
+		ID:    "historyInvalidCountErrorMessage",
+		Other: "The number of games must be between 1 and {{.Max}}.",
+	}
+)
+
+/*
+gameRecord は結果を表示したゲームの記録．
+後から過去の結果や参加者の成績を調べるために使う
+*/
+type gameRecord struct {
+	ID        string `json:"id"`
+	GameType  string `json:"game_type"`
+	HandSet   string `json:"hand_set"`
+	Creator   string `json:"creator"`
+	TeamID    string `json:"team_id"`
+	ChannelID string `json:"channel_id"`
+	PostID    string `json:"post_id"`
+	// タイトルと目的
+	Title   string `json:"title,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	// 当選者の人数
+	NumWinners int `json:"num_winners"`
+	// 作成日時と結果を表示した日時
+	CreatedAt  int64 `json:"created_at"`
+	FinishedAt int64 `json:"finished_at"`
+	// 結果の順に並べた参加者
+	Participants []*recordParticipant `json:"participants"`
+	// 行ったジャンケンの記録
+	Rounds []*roundEvent `json:"rounds,omitempty"`
+}
+
+// recordParticipant は記録した参加者の順位と手
+type recordParticipant struct {
+	UserID string   `json:"user_id"`
+	Team   string   `json:"team,omitempty"`
+	Rank   int      `json:"rank"`
+	Hands  []string `json:"hands"`
+}
+
+/*
+newGameRecord は結果を表示したゲームの記録を作る．
+Args:
+    game: 結果を表示したゲーム
+    result: getResultの結果
+*/
+func newGameRecord(game *game, result []*participant) *gameRecord {
+	r := &gameRecord{
+		ID:         game.ID,
+		GameType:   game.GameType,
+		HandSet:    game.HandSet,
+		Creator:    game.Creator,
+		TeamID:     game.TeamID,
+		ChannelID:  game.ChannelID,
+		PostID:     game.PostID,
+		Title:      game.Title,
+		Purpose:    game.Purpose,
+		NumWinners: game.NumWinners,
+		CreatedAt:  game.CreatedAt,
+		FinishedAt: model.GetMillis(),
+		Rounds:     game.Rounds,
+	}
+	for _, p := range result {
+		hands := make([]string, len(p.Hands))
+		copy(hands, p.Hands)
+		r.Participants = append(r.Participants, &recordParticipant{
+			UserID: p.UserID,
+			Team:   p.Team,
+			Rank:   p.Rank,
+			Hands:  hands,
+		})
+	}
+	return r
+}
+
+// winners は1位の参加者のUserIDを返す
+func (r *gameRecord) winners() []string {
+	winners := []string{}
+	for _, p := range r.Participants {
+		if p.Rank == 1 {
+			winners = append(winners, p.UserID)
+		}
+	}
+	return winners
+}
+
+/*
+losers は最下位の参加者のUserIDを返す．
+全員が1位の場合や順位なしの参加者しかいない場合は空になる
+*/
+func (r *gameRecord) losers() []string {
+	lowest := 1
+	for _, p := range r.Participants {
+		if p.Rank > lowest {
+			lowest = p.Rank
+		}
+	}
+	losers := []string{}
+	if lowest == 1 {
+		return losers
+	}
+	for _, p := range r.Participants {
+		if p.Rank == lowest {
+			losers = append(losers, p.UserID)
+		}
+	}
+	return losers
+}
+
+// matches はタイトルか目的に検索語が含まれるかを返す．大文字と小文字は区別しない
+func (r *gameRecord) matches(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(r.Title), query) || strings.Contains(strings.ToLower(r.Purpose), query)
+}
+
+// summary は履歴に表示するタイトルと目的を1行で返す
+func (r *gameRecord) summary() string {
+	texts := []string{}
+	for _, s := range []string{r.Title, r.Purpose} {
+		if s != "" {
+			texts = append(texts, strings.Join(strings.Fields(s), " "))
+		}
+	}
+	return escapeTableCell(strings.Join(texts, ": "))
+}
+
+/*
+saveGameRecord は結果を表示したゲームを履歴に保存して，参加者の成績・ランキング・レーティングに加える．
+Returns:
+    map[string]*ratingChange: UserIDごとのレーティングの変化
+*/
+func (p *Plugin) saveGameRecord(game *game, result []*participant) map[string]*ratingChange {
+	record := newGameRecord(game, result)
+	if err := p.store.historyStore.Save(record, p.getConfiguration().GetHistoryExpireInSeconds()); err != nil {
+		p.API.LogError("failed to save the game to the history", "id", game.ID, "error", err.Error())
+	}
+	p.updateUserStats(record)
+	p.updateLeaderboards(record)
+	return p.updateRatings(record)
+}
+
+/*
+executeHistoryCommand はチャンネルで最近結果を表示したゲームを新しい順に表示する．
+引数で表示する数とタイトルか目的に含まれる検索語を指定できる
+*/
+func (p *Plugin) executeHistoryCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	count, query, err := parseHistoryArgs(args.Command)
+	if err != nil {
+		return p.newUsageErrorResponse(siteURL, err)
+	}
+
+	var records []*gameRecord
+	if query == "" {
+		records, err = p.store.historyStore.ListByChannel(args.ChannelId, count)
+	} else {
+		records, err = p.store.historyStore.SearchByChannel(args.ChannelId, query, count)
+	}
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get the history.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+	if len(records) == 0 {
+		message := Localize(l, historyEmptyMessage, nil)
+		if query != "" {
+			message = Localize(l, historySearchEmptyMessage, map[string]interface{}{"Query": query})
+		}
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	teamName := ""
+	if team, appErr := p.API.GetTeam(args.TeamId); appErr == nil {
+		teamName = team.Name
+	}
+
+	mentions := func(ids []string) string {
+		m := make([]string, 0, len(ids))
+		for _, id := range ids {
+			m = append(m, "@"+p.getUsername(id))
+		}
+		return strings.Join(m, ", ")
+	}
+
+	now := model.GetMillis()
+	title := Localize(l, historyTitle, nil)
+	if query != "" {
+		title = Localize(l, historySearchTitle, map[string]interface{}{"Query": query})
+	}
+	lines := []string{
+		fmt.Sprintf("#### %s", title),
+		Localize(l, historyHeader, nil),
+	}
+	for _, r := range records {
+		link := ""
+		if r.PostID != "" {
+			link = fmt.Sprintf("[%s](%s/%s/pl/%s)", Localize(l, jankenListLinkLabel, nil), siteURL, teamName, r.PostID)
+		}
+		gameType := r.GameType
+		if m, ok := gameTypeMessages[r.GameType]; ok {
+			gameType = Localize(l, m, nil)
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s |",
+			r.ID[:7],
+			gameType,
+			r.summary(),
+			formatAge(now-r.FinishedAt),
+			mentions(r.winners()),
+			mentions(r.losers()),
+			link,
+		))
+	}
+	message := strings.Join(lines, "\n")
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+/*
+parseHistoryArgs はhistoryサブコマンドの表示する数と検索語を返す．
+表示する数は-nオプションで指定し，残りの引数を空白でつないだものを検索語とする
+Returns:
+    int: 表示する数．指定されていない場合は初期値
+    string: 検索語．指定されていない場合は空文字
+    error: 引数を解析できない場合や数が1から上限までの整数でない場合
+*/
+func parseHistoryArgs(command string) (int, string, error) {
+	fs := flag.NewFlagSet(subcommandHistory, flag.ContinueOnError)
+	fs.SetOutput(&strings.Builder{})
+	count := fs.Int("n", defaultHistoryCount, "Number of games")
+
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return 0, "", newFlagError(err)
+	}
+	if *count < 1 || *count > maxHistoryCount {
+		return 0, "", newUsageError(historyInvalidCountErrorMessage, map[string]interface{}{
+			"Max": maxHistoryCount,
+		})
+	}
+	return *count, strings.TrimSpace(strings.Join(fs.Args(), " ")), nil
+}
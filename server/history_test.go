@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameRecord(t *testing.T) {
+	t.Run("newGameRecord", func(t *testing.T) {
+		assert := assert.New(t)
+		g := newGame(&gameImpl1{})
+		g.Creator = "creator"
+		g.TeamID = "team"
+		g.ChannelID = "channel"
+		g.PostID = "post"
+		g.Title = "Friday demo"
+		g.Purpose = "Who presents?"
+		g.UpdateHands("p1", []string{"rock", "rock"})
+		g.UpdateHands("p2", []string{"scissors"})
+		result := g.getResult()
+
+		r := newGameRecord(g, result)
+
+		assert.Equal(g.ID, r.ID)
+		assert.Equal("gameImpl1", r.GameType)
+		assert.Equal("creator", r.Creator)
+		assert.Equal("team", r.TeamID)
+		assert.Equal("channel", r.ChannelID)
+		assert.Equal("post", r.PostID)
+		assert.Equal("Friday demo", r.Title)
+		assert.Equal("Who presents?", r.Purpose)
+		assert.Equal(g.CreatedAt, r.CreatedAt)
+		assert.NotZero(r.FinishedAt)
+		assert.Equal(g.Rounds, r.Rounds)
+		assert.Equal(&recordParticipant{UserID: "p1", Rank: 1, Hands: result[0].Hands}, r.Participants[0])
+		assert.Equal(&recordParticipant{UserID: "p2", Rank: 2, Hands: result[1].Hands}, r.Participants[1])
+	})
+
+	t.Run("matches and summary", func(t *testing.T) {
+		assert := assert.New(t)
+		r := &gameRecord{Title: "Friday demo", Purpose: "Who presents\nfirst? | second?"}
+
+		assert.True(r.matches("friday"))
+		assert.True(r.matches("PRESENTS"))
+		assert.False(r.matches("lunch"))
+		assert.Equal(`Friday demo: Who presents first? \| second?`, r.summary())
+		assert.Equal("", (&gameRecord{}).summary())
+	})
+
+	for name, test := range map[string]struct {
+		Ranks           map[string]int
+		ExpectedWinners []string
+		ExpectedLosers  []string
+	}{
+		"ranking": {
+			Ranks:           map[string]int{"p1": 1, "p2": 2, "p3": 3},
+			ExpectedWinners: []string{"p1"},
+			ExpectedLosers:  []string{"p3"},
+		},
+		"tied losers": {
+			Ranks:           map[string]int{"p1": 1, "p2": 2, "p3": 2},
+			ExpectedWinners: []string{"p1"},
+			ExpectedLosers:  []string{"p2", "p3"},
+		},
+		"all tied": {
+			Ranks:           map[string]int{"p1": 1, "p2": 1},
+			ExpectedWinners: []string{"p1", "p2"},
+			ExpectedLosers:  []string{},
+		},
+		"unranked": {
+			Ranks:           map[string]int{"p1": 1, "p2": 0, "p3": 0},
+			ExpectedWinners: []string{"p1"},
+			ExpectedLosers:  []string{},
+		},
+	} {
+		t.Run("winners and losers "+name, func(t *testing.T) {
+			assert := assert.New(t)
+			r := &gameRecord{}
+			for _, id := range []string{"p1", "p2", "p3"} {
+				if rank, ok := test.Ranks[id]; ok {
+					r.Participants = append(r.Participants, &recordParticipant{UserID: id, Rank: rank})
+				}
+			}
+
+			assert.Equal(test.ExpectedWinners, r.winners())
+			assert.Equal(test.ExpectedLosers, r.losers())
+		})
+	}
+}
+
+func TestParseHistoryArgs(t *testing.T) {
+	for name, test := range map[string]struct {
+		Command       string
+		Expected      int
+		ExpectedQuery string
+		ShouldError   bool
+	}{
+		"default":         {Command: "/janken history", Expected: defaultHistoryCount},
+		"count":           {Command: "/janken history -n 10", Expected: 10},
+		"zero":            {Command: "/janken history -n 0", ShouldError: true},
+		"too many":        {Command: "/janken history -n 51", ShouldError: true},
+		"invalid count":   {Command: "/janken history -n ten", ShouldError: true},
+		"unknown option":  {Command: "/janken history -x", ShouldError: true},
+		"query":           {Command: "/janken history demo", Expected: defaultHistoryCount, ExpectedQuery: "demo"},
+		"count and query": {Command: "/janken history -n 3 Friday demo", Expected: 3, ExpectedQuery: "Friday demo"},
+		"quoted query":    {Command: `/janken history "Friday  demo"`, Expected: defaultHistoryCount, ExpectedQuery: "Friday  demo"},
+		"number in query": {Command: "/janken history 2024 offsite", Expected: defaultHistoryCount, ExpectedQuery: "2024 offsite"},
+		"invalid quoting": {Command: `/janken history "demo`, ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			count, query, err := parseHistoryArgs(test.Command)
+			assert.Equal(test.Expected, count)
+			assert.Equal(test.ExpectedQuery, query)
+			if test.ShouldError {
+				assert.NotNil(err)
+			} else {
+				assert.Nil(err)
+			}
+		})
+	}
+}
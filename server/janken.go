@@ -4,38 +4,80 @@ package main
 import (
 	"encoding/json"
 	"errors"
-	"math/rand"
 	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"golang.org/x/text/language"
 )
 
 const (
-	maxHands         = 10
-	defaultMaxRounds = 5
+	maxHands          = 10
+	defaultMaxRounds  = 5
+	defaultNumWinners = 1
+	// 結果を表示するのに必要な参加人数の初期値
+	defaultMinParticipants = 2
+	maxTeamNameLength      = 64
 )
 
-var handNames = []string{"rock", "scissors", "paper"}
-
-// 英語名の手と日本語名の手の対応
-var handNamesMap = map[string]string{
-	"rock":     "Rock",
-	"scissors": "Scissors",
-	"paper":    "Paper",
+var newGameFuncMapping = map[string](func() gameInterface){
+	"gameImpl1":       newGameImpl1,
+	"gameImplBracket": newGameImplBracket,
+	"gameImplLeague":  newGameImplLeague,
+	"gameImplLoser":   newGameImplLoser,
+	"gameImplLottery": newGameImplLottery,
+	"gameImplTeam":    newGameImplTeam,
+	"gameImplLive":    newGameImplLive,
 }
 
-// emojiとの対応
-var handIcons = map[string]string{
-	"rock":     ":fist_raised:",
-	"scissors": ":v:",
-	"paper":    ":hand:",
+// gameTypeNames は選択可能なゲームの種類を表示順に返す
+var gameTypeNames = []string{"gameImpl1", "gameImplBracket", "gameImplLeague", "gameImplLoser", "gameImplLottery", "gameImplTeam", "gameImplLive"}
+
+// gameTypeOptionNames は-typeオプションで指定するゲームの種類の名前をgameTypeNamesと同じ順に返す
+var gameTypeOptionNames = []string{"ranking", "bracket", "league", "loser", "lottery", "team", "live"}
+
+// getGameTypeByOptionName は-typeオプションの名前に対応するゲームの種類を返す．存在しない場合は空文字
+func getGameTypeByOptionName(name string) string {
+	for i, n := range gameTypeOptionNames {
+		if n == name {
+			return gameTypeNames[i]
+		}
+	}
+	return ""
 }
 
-var newGameFuncMapping = map[string](func() gameInterface){
-	"gameImpl1": newGameImpl1,
+// ゲームの種類の名前
+var gameTypeMessages = map[string]*i18n.Message{
+	"gameImpl1": {
+		ID:    "gameTypeGameImpl1Label",
+		Other: "Ranking",
+	},
+	"gameImplBracket": {
+		ID:    "gameTypeGameImplBracketLabel",
+		Other: "Tournament bracket",
+	},
+	"gameImplLeague": {
+		ID:    "gameTypeGameImplLeagueLabel",
+		Other: "Round-robin league",
+	},
+	"gameImplLoser": {
+		ID:    "gameTypeGameImplLoserLabel",
+		Other: "Find the one loser",
+	},
+	"gameImplLottery": {
+		ID:    "gameTypeGameImplLotteryLabel",
+		Other: "Pick winners",
+	},
+	"gameImplTeam": {
+		ID:    "gameTypeGameImplTeamLabel",
+		Other: "Team janken",
+	},
+	"gameImplLive": {
+		ID:    "gameTypeGameImplLiveLabel",
+		Other: "Live (play hands round by round)",
+	},
 }
 
 type participant struct {
@@ -45,6 +87,12 @@ type participant struct {
 	Hands []string `json:"hands"`
 	// 順位
 	Rank int `json:"rank"`
+	// 所属するチーム名
+	Team string `json:"team,omitempty"`
+	// 参加時に登録した手とそのコミットメント
+	CommittedHands []string `json:"committed_hands,omitempty"`
+	Salt           string   `json:"salt,omitempty"`
+	Commitment     string   `json:"commitment,omitempty"`
 }
 
 func newParticipant(userID string) *participant {
@@ -55,6 +103,23 @@ func newParticipant(userID string) *participant {
 	}
 }
 
+// teamName はチーム名を返す．チームに所属していない場合はUserIDを返す
+func (p *participant) teamName() string {
+	if p.Team == "" {
+		return p.UserID
+	}
+	return p.Team
+}
+
+// growHands はHandsの長さをmaxHandsまで伸ばす
+func (p *participant) growHands() {
+	if len(p.Hands) < maxHands {
+		hands := make([]string, maxHands)
+		copy(hands, p.Hands)
+		p.Hands = hands
+	}
+}
+
 func (p *participant) setHands(hands []string) {
 	copy(p.Hands, hands)
 }
@@ -66,27 +131,85 @@ func (p *participant) clearHandsAfter(i int) {
 	}
 }
 
+type gameInterface interface {
+	getResult(g *game) []*participant
+}
+
 /*
-GetHandはiで指定した手を返す．
-未設定の場合はランダムな手を返す．このときの取得した値は保存される
+gameResultDetailer は結果の表の後に詳細を表示するゲームが実装する．
+usernamesはUserIDとユーザー名の対応
 */
-func (p *participant) getHand(i int) string {
-	hand := p.Hands[i]
-	if hand == "" {
-		hand = handNames[rand.Intn(len(handNames))]
-		p.Hands[i] = hand
-	}
-	return hand
+type gameResultDetailer interface {
+	getResultDetail(l *i18n.Localizer, usernames map[string]string) string
 }
 
-type gameInterface interface {
-	getResult(g *game) []*participant
+// gameResultNoter は結果の表の各行に備考を表示するゲームが実装する
+type gameResultNoter interface {
+	getResultNote(l *i18n.Localizer, p *participant) string
+}
+
+// roundEvent はジャンケン1回分の記録
+type roundEvent struct {
+	// 何手目のジャンケンか
+	Round int `json:"round"`
+	// ジャンケンの参加者のUserID
+	Members []string `json:"members"`
+	// Membersの各参加者が出した手
+	Hands   []string `json:"hands"`
+	Winners []string `json:"winners,omitempty"`
+	Losers  []string `json:"losers,omitempty"`
+	Draw    bool     `json:"draw,omitempty"`
 }
 
 type gameBase struct {
 	base *game
 }
 
+// getHandSet はゲームで使う手の組み合わせを返す
+func (g *gameBase) getHandSet() *handSet {
+	if g.base == nil {
+		return getHandSet(defaultHandSetName)
+	}
+	return g.base.getHandSet()
+}
+
+/*
+janken はジャンケンを1回行い，その結果をゲームの記録に追加する．
+未登録の手はゲームの乱数で決める．
+Returns:
+    janken関数と同じ
+*/
+func (g *gameBase) janken(participants []*participant, round int) ([]*participant, []*participant, []*participant) {
+	if g.base == nil {
+		return janken(participants, round, g.getHandSet())
+	}
+	for _, p := range participants {
+		g.base.fillHand(p, round)
+	}
+	winners, losers, drawers := janken(participants, round, g.getHandSet())
+	g.base.recordRound(participants, round, winners, losers, drawers)
+	return winners, losers, drawers
+}
+
+/*
+duel は1対1のジャンケンを勝敗が決まるまで行う．
+Args:
+    p1, p2: 対戦者
+    maxRounds: 最大対戦回数
+Returns:
+    *participant: 勝者．最大対戦回数まであいこの場合はnil
+    *participant: 敗者．最大対戦回数まであいこの場合はnil
+*/
+func (g *gameBase) duel(p1, p2 *participant, maxRounds int) (*participant, *participant) {
+	for round := 0; round < maxRounds; round++ {
+		winners, losers, _ := g.janken([]*participant{p1, p2}, round)
+		if winners != nil {
+			return winners[0], losers[0]
+		}
+	}
+	return nil, nil
+}
+
 func gameFromBytes(b []byte) (*game, error) {
 	var tmp interface{}
 	json.Unmarshal(b, &tmp)
@@ -114,29 +237,72 @@ type game struct {
 	PostID string `json:"post_id"`
 	// 作成者
 	Creator string `json:"creator"`
+	// タイトル．空の場合は既定のタイトルを表示する
+	Title string `json:"title,omitempty"`
+	// 結果を表示するまで参加者を表示しない
+	Anonymous bool `json:"anonymous,omitempty"`
+	// 目的．ゲームと結果に表示して，履歴から検索できる
+	Purpose string `json:"purpose,omitempty"`
+	// 順位ごとの賞品や罰ゲーム
+	Outcomes []*rankOutcome `json:"outcomes,omitempty"`
+	// 結果の表示方法．空の場合はプラグインの設定を使う
+	ResultPublishMode string `json:"result_publish_mode,omitempty"`
+	// 作成したチームとチャンネル
+	TeamID    string `json:"team_id"`
+	ChannelID string `json:"channel_id"`
 	// 最大対戦回数
 	MaxRounds int `json:"max_rounds"`
-	// 最大参加人数
+	// 最大参加人数．0の場合は制限しない
 	MaxParticipants int `json:"max_participants"`
+	// 参加人数が最大参加人数に達したときに自動で結果を表示する
+	AutoResult bool `json:"auto_result,omitempty"`
+	// 結果を表示するのに必要な参加人数
+	MinParticipants int `json:"min_participants,omitempty"`
+	// 招待したユーザーのUserID．空でない場合は招待したユーザーと作成者だけが参加できる
+	Invited []string `json:"invited,omitempty"`
+	// 自動で結果を表示する日時(ミリ秒)．0の場合は自動で結果を表示しない
+	Deadline int64 `json:"deadline,omitempty"`
 	// 参加者
 	Participants []*participant `json:"participants"`
 	Language     string         `json:"language"`
 	GameType     string         `json:"game_type"`
-	Impl         gameInterface  `json:"impl"`
+	// 手の組み合わせ
+	HandSet string `json:"hand_set"`
+	// 当選者の人数
+	NumWinners int `json:"num_winners"`
+	// 未登録の手を決めるための乱数のアルゴリズムとシード，シードのコミットメント
+	RandAlgorithm  string `json:"rand_algorithm"`
+	Seed           string `json:"seed"`
+	SeedCommitment string `json:"seed_commitment"`
+	// 行ったジャンケンの記録
+	Rounds []*roundEvent `json:"rounds,omitempty"`
+	Impl   gameInterface `json:"impl"`
+
+	// ゲームで使う乱数．RandAlgorithmとSeedから作る
+	rng gameRand
 }
 
 func newGame(impl gameInterface) *game {
 	g := &game{
-		ID:           model.NewId(),
-		CreatedAt:    model.GetMillis(),
-		Creator:      "",
-		MaxRounds:    defaultMaxRounds,
-		Participants: make([]*participant, 0),
-		Language:     language.English.String(),
+		ID:              model.NewId(),
+		CreatedAt:       model.GetMillis(),
+		Creator:         "",
+		MaxRounds:       defaultMaxRounds,
+		Participants:    make([]*participant, 0),
+		Language:        language.English.String(),
+		HandSet:         defaultHandSetName,
+		NumWinners:      defaultNumWinners,
+		MinParticipants: defaultMinParticipants,
+		RandAlgorithm:   defaultRandAlgorithm,
 	}
+	g.setImpl(impl)
+	return g
+}
+
+// setImpl はゲームの種類を変更する
+func (g *game) setImpl(impl gameInterface) {
 	g.Impl = impl
 	g.setGameType(impl)
-	return g
 }
 
 func (g *game) setGameType(impl gameInterface) {
@@ -146,10 +312,94 @@ func (g *game) setGameType(impl gameInterface) {
 	g.GameType = splitType[len(splitType)-1]
 }
 
+// getHandSet はゲームで使う手の組み合わせを返す
+func (g *game) getHandSet() *handSet {
+	return getHandSet(g.HandSet)
+}
+
+/*
+setHandSet は手の組み合わせを変更する．
+変更後の手の組み合わせに含まれない登録済みの手は未設定に戻す
+*/
+func (g *game) setHandSet(name string) {
+	g.HandSet = name
+	hs := g.getHandSet()
+	for _, p := range g.Participants {
+		for i, h := range p.Hands {
+			if h != "" && !hs.contains(h) {
+				p.Hands[i] = ""
+			}
+		}
+	}
+}
+
+// rand はゲームで使う乱数を返す
+func (g *game) rand() gameRand {
+	if g.rng == nil {
+		g.rng = newGameRand(g.RandAlgorithm, g.Seed)
+	}
+	return g.rng
+}
+
+// setSeed は乱数のシードを変更する
+func (g *game) setSeed(seed string) {
+	g.Seed = seed
+	g.rng = nil
+}
+
+// fillHand は未登録の手を乱数で決めて返す．このときの値は保存される
+func (g *game) fillHand(p *participant, round int) string {
+	if round >= len(p.Hands) {
+		p.growHands()
+	}
+	if p.Hands[round] == "" {
+		p.Hands[round] = g.rand().hand(p.UserID, round, g.getHandSet())
+	}
+	return p.Hands[round]
+}
+
+// fillHands は全参加者の最大対戦回数までの未登録の手を乱数で決める
+func (g *game) fillHands() {
+	for _, p := range g.Participants {
+		for round := 0; round < g.MaxRounds; round++ {
+			g.fillHand(p, round)
+		}
+	}
+}
+
+// getResult は未登録の手を乱数で決めて結果を返す
 func (g *game) getResult() []*participant {
+	g.fillHands()
 	return g.Impl.getResult(g)
 }
 
+// userIDs はparticipantのUserIDを返す
+func userIDs(participants []*participant) []string {
+	ids := make([]string, 0, len(participants))
+	for _, p := range participants {
+		ids = append(ids, p.UserID)
+	}
+	return ids
+}
+
+// recordRound はジャンケン1回分の結果を記録する
+func (g *game) recordRound(participants []*participant, round int, winners, losers, drawers []*participant) {
+	event := &roundEvent{
+		Round:   round,
+		Members: userIDs(participants),
+		Hands:   make([]string, 0, len(participants)),
+		Draw:    drawers != nil,
+	}
+	for _, p := range participants {
+		event.Hands = append(event.Hands, p.Hands[round])
+	}
+	if !event.Draw {
+		event.Winners = userIDs(winners)
+		event.Losers = userIDs(losers)
+	}
+	g.Rounds = append(g.Rounds, event)
+}
+
 // ToBytes returns byte slice of a game.
 func (g *game) ToBytes() ([]byte, error) {
 	b, err := json.Marshal(g)
@@ -176,6 +426,54 @@ func (g *game) GetParticipant(userID string) *participant {
 	return nil
 }
 
+// isFull は参加人数が最大参加人数に達しているかを返す
+func (g *game) isFull() bool {
+	return g.MaxParticipants > 0 && len(g.Participants) >= g.MaxParticipants
+}
+
+/*
+canJoin は指定したuserIDのユーザーが参加できるかを返す．
+参加済みのユーザーは最大参加人数に達していても手を変更できる
+*/
+func (g *game) canJoin(userID string) bool {
+	return g.GetParticipant(userID) != nil || !g.isFull()
+}
+
+/*
+minParticipants は結果を表示するのに必要な参加人数を返す．
+この設定がない古いゲームや2人未満の設定の場合は2人
+*/
+func (g *game) minParticipants() int {
+	if g.MinParticipants < defaultMinParticipants {
+		return defaultMinParticipants
+	}
+	return g.MinParticipants
+}
+
+// hasEnoughParticipants は結果を表示するのに必要な人数が参加しているかを返す
+func (g *game) hasEnoughParticipants() bool {
+	return len(g.Participants) >= g.minParticipants()
+}
+
+// isInvited は指定したuserIDのユーザーが招待されているかを返す．招待制でない場合と作成者は常にtrue
+func (g *game) isInvited(userID string) bool {
+	if len(g.Invited) == 0 || userID == g.Creator {
+		return true
+	}
+	return containsString(g.Invited, userID)
+}
+
+/*
+shouldAutoResult は参加人数が最大参加人数に達したため自動で結果を表示するかを返す．
+ライブ対戦は開始ボタンで始めるので対象外
+*/
+func (g *game) shouldAutoResult() bool {
+	if _, ok := g.Impl.(*gameImplLive); ok {
+		return false
+	}
+	return g.AutoResult && g.isFull()
+}
+
 // RemoveParticipant は指定したuserIDのparticipantを削除する
 func (g *game) RemoveParticipant(userID string) {
 	participants := make([]*participant, 0)
@@ -194,7 +492,9 @@ UpdateHands はparticipantのHandsを更新する．
 func (g *game) UpdateHands(userID string, hands []string) {
 	for _, p := range g.Participants {
 		if p.UserID == userID {
-			p.Hands = hands
+			// 手のない参加(ライブ対戦の参加ダイアログ)でも全ラウンド分の手の枠を残す
+			p.growHands()
+			p.setHands(hands)
 			return
 		}
 	}
@@ -204,57 +504,89 @@ func (g *game) UpdateHands(userID string, hands []string) {
 	g.Participants = append(g.Participants, p)
 }
 
+// UpdateTeam は指定したuserIDのparticipantのチームを更新する
+func (g *game) UpdateTeam(userID, team string) {
+	if p := g.GetParticipant(userID); p != nil {
+		p.Team = team
+	}
+}
+
+// getTeams は参加者をチームごとに参加した順で返す
+func (g *game) getTeams() [][]*participant {
+	teams := [][]*participant{}
+	index := make(map[string]int)
+	for _, p := range g.Participants {
+		name := p.teamName()
+		i, ok := index[name]
+		if !ok {
+			i = len(teams)
+			index[name] = i
+			teams = append(teams, []*participant{})
+		}
+		teams[i] = append(teams[i], p)
+	}
+	return teams
+}
+
+// getTeamNames はチーム名を参加した順で返す．チームに所属していない参加者は含まない
+func (g *game) getTeamNames() []string {
+	names := []string{}
+	for _, members := range g.getTeams() {
+		if team := members[0].Team; team != "" {
+			names = append(names, team)
+		}
+	}
+	return names
+}
+
 /*
-ジャンケン1回の勝敗を判定する．
+ジャンケン1回の勝敗を判定する．参加者の手は事前に決めておく．
 Args:
     participants: 参加者
     round: 何手目で勝負するか
+    hs: 手の組み合わせ
 Returns:
     []participant: 勝者
     []participant: 敗者
     []participant: あいこ
 */
-func janken(participants []*participant, round int) ([]*participant, []*participant, []*participant) {
+func janken(participants []*participant, round int, hs *handSet) ([]*participant, []*participant, []*participant) {
 	// 手の種類とparticipantのmapを作る
 	set := make(map[string][]*participant)
 	for _, p := range participants {
-		hand := p.getHand(round)
+		hand := p.Hands[round]
 		if set[hand] == nil {
 			set[hand] = []*participant{}
 		}
 		set[hand] = append(set[hand], p)
 	}
 
-	// 手の種類数が1か3のときはあいこ
-	if len(set) == 1 || len(set) == 3 {
-		// 参加者全員あいことして返す
-		return nil, nil, participants
-	}
-
-	// それ以外(手の種類数==2)のとき
-	// 手の種類を抽出してソートしておく
+	// 出ている手の種類を抽出してソートしておく
 	hands := []string{}
 	for h := range set {
 		hands = append(hands, h)
 	}
-	sort.Slice(hands, func(i, j int) bool {
-		return hands[i] < hands[j]
-	})
+	sort.Strings(hands)
 
-	// 勝ちの手と負けの手を取得
-	var win, lose string
-	switch {
-	case hands[0] == "rock" && hands[1] == "scissors":
-		win, lose = "rock", "scissors"
-	case hands[0] == "paper" && hands[1] == "scissors":
-		win, lose = "scissors", "paper"
-	case hands[0] == "paper" && hands[1] == "rock":
-		win, lose = "paper", "rock"
+	// 勝ちの手を取得
+	winHands := hs.winningHands(hands)
+	if winHands == nil {
+		// 勝ちの手が決まらないときは参加者全員あいことして返す
+		return nil, nil, participants
 	}
 
-	// 勝者と敗者を返す
+	// 勝ちの手を出した参加者が勝者，それ以外が敗者
+	isWinHand := make(map[string]bool)
+	for _, h := range winHands {
+		isWinHand[h] = true
+	}
 	var winners, losers []*participant
-	winners = set[win]
-	losers = set[lose]
+	for _, p := range participants {
+		if isWinHand[p.Hands[round]] {
+			winners = append(winners, p)
+		} else {
+			losers = append(losers, p)
+		}
+	}
 	return winners, losers, nil
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 
 	"bou.ke/monkey"
@@ -24,6 +25,28 @@ func (g *TestGameImpl) getResult(game *game) []*participant {
 	}
 }
 
+/*
+cyclicHandSet はn種類（nは奇数）の手を持つ手の組み合わせを返す．
+各手は自分の次から(n-1)/2個の手に勝つ
+*/
+func cyclicHandSet(n int) *handSet {
+	hands := make([]string, n)
+	for i := range hands {
+		hands[i] = fmt.Sprintf("h%d", i)
+	}
+	beats := make(map[string][]string)
+	for i, h := range hands {
+		for j := 1; j <= (n-1)/2; j++ {
+			beats[h] = append(beats[h], hands[(i+j)%n])
+		}
+	}
+	return &handSet{
+		Name:  fmt.Sprintf("cyclic%d", n),
+		Hands: hands,
+		Beats: beats,
+	}
+}
+
 func TestParticipant(t *testing.T) {
 	t.Run("NewParticipant", func(t *testing.T) {
 		for name, test := range map[string]struct {
@@ -49,7 +72,7 @@ func TestParticipant(t *testing.T) {
 		}
 	})
 
-	t.Run("GetHand", func(t *testing.T) {
+	t.Run("fillHand", func(t *testing.T) {
 		for name, test := range map[string]struct {
 			Index         int
 			participant   *participant
@@ -74,11 +97,13 @@ func TestParticipant(t *testing.T) {
 		} {
 			t.Run(name, func(t *testing.T) {
 				p := test.participant
+				g := newGame(&gameImpl1{})
 
-				h := p.getHand(test.Index)
+				h := g.fillHand(p, test.Index)
 
 				assert := assert.New(t)
 				assert.Contains(test.ExpectedHands, h)
+				assert.Equal(h, p.Hands[test.Index])
 			})
 		}
 	})
@@ -96,32 +121,35 @@ func TestGame(t *testing.T) {
 			assert.Equal(make([]*participant, 0), g.Participants)
 			assert.Equal("en", g.Language)
 			assert.Equal("TestGameImpl", g.GameType)
+			assert.Equal("rps", g.HandSet)
 		})
 	})
 
 	t.Run("gameFromBytes", func(t *testing.T) {
 		for name, test := range map[string]struct {
-			Bytes              []byte
+			Bytes        []byte
 			ExpectedGame *game
-			ShouldError        bool
+			ShouldError  bool
 		}{
 			"successfully": {
-				Bytes:              []byte(`{"game_type":"TestGameImpl"}`),
+				Bytes:        []byte(`{"game_type":"TestGameImpl"}`),
 				ExpectedGame: &game{Impl: newTestGameImpl()},
-				ShouldError:        false,
+				ShouldError:  false,
 			},
 			"game_type missing": {
-				Bytes:              []byte("{}"),
+				Bytes:        []byte("{}"),
 				ExpectedGame: nil,
-				ShouldError:        true,
+				ShouldError:  true,
 			},
 			"Invalid game_type": {
-				Bytes:              []byte(`{"game_type":"InvalidGameType"}`),
+				Bytes:        []byte(`{"game_type":"InvalidGameType"}`),
 				ExpectedGame: nil,
-				ShouldError:        true,
+				ShouldError:  true,
 			},
 		} {
 			t.Run(name, func(t *testing.T) {
+				mapping := newGameFuncMapping
+				defer func() { newGameFuncMapping = mapping }()
 				newGameFuncMapping = map[string](func() gameInterface){
 					"TestGameImpl": newTestGameImpl,
 				}
@@ -211,6 +239,31 @@ func TestGame(t *testing.T) {
 		}
 	})
 
+	t.Run("setHandSet", func(t *testing.T) {
+		assert := assert.New(t)
+
+		g := newGame(&TestGameImpl{})
+		g.HandSet = "rpsls"
+		g.Participants = []*participant{
+			{UserID: "p1", Hands: []string{"rock", "lizard", "spock", ""}},
+		}
+
+		g.setHandSet("rps")
+
+		assert.Equal("rps", g.HandSet)
+		assert.Equal([]string{"rock", "", "", ""}, g.Participants[0].Hands)
+	})
+
+	t.Run("setImpl", func(t *testing.T) {
+		assert := assert.New(t)
+
+		g := newGame(&TestGameImpl{})
+		g.setImpl(&gameImplBracket{})
+
+		assert.Equal(&gameImplBracket{}, g.Impl)
+		assert.Equal("gameImplBracket", g.GameType)
+	})
+
 	t.Run("getShortID", func(t *testing.T) {
 		assert := assert.New(t)
 
@@ -300,6 +353,92 @@ func TestGame(t *testing.T) {
 		}
 	})
 
+	t.Run("max participants", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			Impl                     gameInterface
+			MaxParticipants          int
+			AutoResult               bool
+			ExpectedFull             bool
+			ExpectedNewUserCanJoin   bool
+			ExpectedShouldAutoResult bool
+		}{
+			"no limit": {
+				Impl: &gameImpl1{}, MaxParticipants: 0, AutoResult: true,
+				ExpectedFull: false, ExpectedNewUserCanJoin: true, ExpectedShouldAutoResult: false,
+			},
+			"not full": {
+				Impl: &gameImpl1{}, MaxParticipants: 3, AutoResult: true,
+				ExpectedFull: false, ExpectedNewUserCanJoin: true, ExpectedShouldAutoResult: false,
+			},
+			"full": {
+				Impl: &gameImpl1{}, MaxParticipants: 2, AutoResult: true,
+				ExpectedFull: true, ExpectedNewUserCanJoin: false, ExpectedShouldAutoResult: true,
+			},
+			"full without auto result": {
+				Impl: &gameImpl1{}, MaxParticipants: 2, AutoResult: false,
+				ExpectedFull: true, ExpectedNewUserCanJoin: false, ExpectedShouldAutoResult: false,
+			},
+			"full live game": {
+				Impl: &gameImplLive{}, MaxParticipants: 2, AutoResult: true,
+				ExpectedFull: true, ExpectedNewUserCanJoin: false, ExpectedShouldAutoResult: false,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				g := newGame(test.Impl)
+				g.MaxParticipants = test.MaxParticipants
+				g.AutoResult = test.AutoResult
+				g.Participants = []*participant{{UserID: "p1"}, {UserID: "p2"}}
+
+				assert := assert.New(t)
+				assert.Equal(test.ExpectedFull, g.isFull())
+				assert.Equal(test.ExpectedNewUserCanJoin, g.canJoin("p3"))
+				// 参加済みのユーザーは手を変更できる
+				assert.True(g.canJoin("p1"))
+				assert.Equal(test.ExpectedShouldAutoResult, g.shouldAutoResult())
+			})
+		}
+	})
+
+	t.Run("min participants and invited users", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			MinParticipants          int
+			Invited                  []string
+			ExpectedMinParticipants  int
+			ExpectedEnough           bool
+			ExpectedInvitedUserJoins bool
+			ExpectedOtherUserJoins   bool
+		}{
+			"default": {
+				MinParticipants: 0, Invited: nil,
+				ExpectedMinParticipants: 2, ExpectedEnough: true, ExpectedInvitedUserJoins: true, ExpectedOtherUserJoins: true,
+			},
+			"not enough": {
+				MinParticipants: 3, Invited: nil,
+				ExpectedMinParticipants: 3, ExpectedEnough: false, ExpectedInvitedUserJoins: true, ExpectedOtherUserJoins: true,
+			},
+			"invite only": {
+				MinParticipants: 2, Invited: []string{"p1", "p2", "p3"},
+				ExpectedMinParticipants: 2, ExpectedEnough: true, ExpectedInvitedUserJoins: true, ExpectedOtherUserJoins: false,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				g := newGame(&gameImpl1{})
+				g.Creator = "creator"
+				g.MinParticipants = test.MinParticipants
+				g.Invited = test.Invited
+				g.Participants = []*participant{{UserID: "p1"}, {UserID: "p2"}}
+
+				assert := assert.New(t)
+				assert.Equal(test.ExpectedMinParticipants, g.minParticipants())
+				assert.Equal(test.ExpectedEnough, g.hasEnoughParticipants())
+				assert.Equal(test.ExpectedInvitedUserJoins, g.isInvited("p3"))
+				assert.Equal(test.ExpectedOtherUserJoins, g.isInvited("p4"))
+				// 作成者は招待されていなくても参加できる
+				assert.True(g.isInvited("creator"))
+			})
+		}
+	})
+
 	t.Run("UpdateHands", func(t *testing.T) {
 		for name, test := range map[string]struct {
 			UserID               string
@@ -317,7 +456,7 @@ func TestGame(t *testing.T) {
 				},
 				ExpectedParticipants: []*participant{
 					{UserID: "p1", Hands: []string{"rock", "scissors"}},
-					{UserID: "p2", Hands: []string{"paper", "scissors"}},
+					{UserID: "p2", Hands: []string{"paper", "scissors", "", "", "", "", "", "", "", ""}},
 					{UserID: "p3", Hands: []string{"rock", "scissors"}},
 				},
 			},
@@ -331,7 +470,7 @@ func TestGame(t *testing.T) {
 				},
 				ExpectedParticipants: []*participant{
 					{UserID: "p1", Hands: []string{"rock", "scissors"}},
-					{UserID: "p2", Hands: []string{"paper"}},
+					{UserID: "p2", Hands: []string{"paper", "scissors", "", "", "", "", "", "", "", ""}},
 					{UserID: "p3", Hands: []string{"rock", "scissors"}},
 				},
 			},
@@ -345,10 +484,20 @@ func TestGame(t *testing.T) {
 				},
 				ExpectedParticipants: []*participant{
 					{UserID: "p1", Hands: []string{"rock", "scissors"}},
-					{UserID: "p2", Hands: []string{"paper", "scissors", "rock"}},
+					{UserID: "p2", Hands: []string{"paper", "scissors", "rock", "", "", "", "", "", "", ""}},
 					{UserID: "p3", Hands: []string{"rock", "scissors"}},
 				},
 			},
+			"update without hands keeps the hands of all rounds": {
+				UserID: "p2",
+				Hands:  []string{},
+				participants: []*participant{
+					{UserID: "p2", Hands: []string{}},
+				},
+				ExpectedParticipants: []*participant{
+					{UserID: "p2", Hands: []string{"", "", "", "", "", "", "", "", "", ""}},
+				},
+			},
 			"add new participant": {
 				UserID: "p2",
 				Hands:  []string{"paper", "scissors"},
@@ -374,8 +523,70 @@ func TestGame(t *testing.T) {
 	})
 }
 
+func TestGameReproducible(t *testing.T) {
+	for _, gameType := range gameTypeNames {
+		t.Run(gameType, func(t *testing.T) {
+			assert := assert.New(t)
+			// 手を登録していない参加者だけのゲーム
+			g := newGame(newGameFuncMapping[gameType]())
+			g.setSeed("seed")
+			for _, userID := range []string{"p1", "p2", "p3", "p4", "p5"} {
+				g.UpdateHands(userID, []string{})
+			}
+			b, err := g.ToBytes()
+			assert.Nil(err)
+
+			// 保存したゲームからは同じ結果が得られる
+			stored, err := gameFromBytes(b)
+			assert.Nil(err)
+			g.getResult()
+			stored.getResult()
+
+			for i, p := range g.Participants {
+				assert.Equal(p.Rank, stored.Participants[i].Rank, p.UserID)
+				assert.Equal(p.Hands, stored.Participants[i].Hands, p.UserID)
+			}
+			assert.Equal(g.Rounds, stored.Rounds)
+		})
+	}
+}
+
+func TestTeams(t *testing.T) {
+	t.Run("UpdateTeam", func(t *testing.T) {
+		assert := assert.New(t)
+		g := game{}
+		g.Participants = []*participant{{UserID: "p1"}, {UserID: "p2"}}
+
+		g.UpdateTeam("p2", "red")
+		g.UpdateTeam("unknown", "blue")
+
+		assert.Equal([]*participant{{UserID: "p1"}, {UserID: "p2", Team: "red"}}, g.Participants)
+	})
+
+	t.Run("getTeams", func(t *testing.T) {
+		assert := assert.New(t)
+		g := game{}
+		g.Participants = []*participant{
+			{UserID: "p1", Team: "red"},
+			{UserID: "p2", Team: "blue"},
+			{UserID: "p3"},
+			{UserID: "p4", Team: "red"},
+		}
+
+		teams := g.getTeams()
+
+		assert.Equal([][]*participant{
+			{{UserID: "p1", Team: "red"}, {UserID: "p4", Team: "red"}},
+			{{UserID: "p2", Team: "blue"}},
+			{{UserID: "p3"}},
+		}, teams)
+		assert.Equal([]string{"red", "blue"}, g.getTeamNames())
+	})
+}
+
 func TestJanken(t *testing.T) {
 	for name, test := range map[string]struct {
+		HandSet         *handSet
 		Round           int
 		participants    []*participant
 		ExpectedWinners []*participant
@@ -441,11 +652,118 @@ func TestJanken(t *testing.T) {
 			},
 			ExpectedDrawers: nil,
 		},
+		"1 winner and 1 loser with rpsls": {
+			HandSet: getHandSet("rpsls"),
+			Round:   0,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"lizard"}},
+				{UserID: "p2", Hands: []string{"spock"}},
+			},
+			ExpectedWinners: []*participant{
+				{UserID: "p1", Hands: []string{"lizard"}},
+			},
+			ExpectedLosers: []*participant{
+				{UserID: "p2", Hands: []string{"spock"}},
+			},
+			ExpectedDrawers: nil,
+		},
+		"1 winner and 2 losers with 3 different hands in rpsls": {
+			HandSet: getHandSet("rpsls"),
+			Round:   0,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"scissors"}},
+				{UserID: "p2", Hands: []string{"rock"}},
+				{UserID: "p3", Hands: []string{"lizard"}},
+			},
+			ExpectedWinners: []*participant{
+				{UserID: "p2", Hands: []string{"rock"}},
+			},
+			ExpectedLosers: []*participant{
+				{UserID: "p1", Hands: []string{"scissors"}},
+				{UserID: "p3", Hands: []string{"lizard"}},
+			},
+			ExpectedDrawers: nil,
+		},
+		"drawers with cyclic 3 different hands in rpsls": {
+			HandSet: getHandSet("rpsls"),
+			Round:   0,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"rock"}},
+				{UserID: "p2", Hands: []string{"paper"}},
+				{UserID: "p3", Hands: []string{"lizard"}},
+			},
+			ExpectedWinners: nil,
+			ExpectedLosers:  nil,
+			ExpectedDrawers: []*participant{
+				{UserID: "p1", Hands: []string{"rock"}},
+				{UserID: "p2", Hands: []string{"paper"}},
+				{UserID: "p3", Hands: []string{"lizard"}},
+			},
+		},
+		"drawers with all 5 hands in rpsls": {
+			HandSet: getHandSet("rpsls"),
+			Round:   0,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"rock"}},
+				{UserID: "p2", Hands: []string{"paper"}},
+				{UserID: "p3", Hands: []string{"scissors"}},
+				{UserID: "p4", Hands: []string{"lizard"}},
+				{UserID: "p5", Hands: []string{"spock"}},
+			},
+			ExpectedWinners: nil,
+			ExpectedLosers:  nil,
+			ExpectedDrawers: []*participant{
+				{UserID: "p1", Hands: []string{"rock"}},
+				{UserID: "p2", Hands: []string{"paper"}},
+				{UserID: "p3", Hands: []string{"scissors"}},
+				{UserID: "p4", Hands: []string{"lizard"}},
+				{UserID: "p5", Hands: []string{"spock"}},
+			},
+		},
+		"winners keep the order of participants with 7 hands": {
+			HandSet: cyclicHandSet(7),
+			Round:   1,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"", "h3"}},
+				{UserID: "p2", Hands: []string{"", "h1"}},
+				{UserID: "p3", Hands: []string{"", "h2"}},
+				{UserID: "p4", Hands: []string{"", "h1"}},
+			},
+			ExpectedWinners: []*participant{
+				{UserID: "p2", Hands: []string{"", "h1"}},
+				{UserID: "p4", Hands: []string{"", "h1"}},
+			},
+			ExpectedLosers: []*participant{
+				{UserID: "p1", Hands: []string{"", "h3"}},
+				{UserID: "p3", Hands: []string{"", "h2"}},
+			},
+			ExpectedDrawers: nil,
+		},
+		"drawers with no winner group in 15 hands": {
+			HandSet: cyclicHandSet(15),
+			Round:   0,
+			participants: []*participant{
+				{UserID: "p1", Hands: []string{"h0"}},
+				{UserID: "p2", Hands: []string{"h5"}},
+				{UserID: "p3", Hands: []string{"h10"}},
+			},
+			ExpectedWinners: nil,
+			ExpectedLosers:  nil,
+			ExpectedDrawers: []*participant{
+				{UserID: "p1", Hands: []string{"h0"}},
+				{UserID: "p2", Hands: []string{"h5"}},
+				{UserID: "p3", Hands: []string{"h10"}},
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
+			hs := test.HandSet
+			if hs == nil {
+				hs = getHandSet(defaultHandSetName)
+			}
 
-			winners, losers, drawers := janken(test.participants, test.Round)
+			winners, losers, drawers := janken(test.participants, test.Round, hs)
 
 			assert.Equal(test.ExpectedWinners, winners)
 			assert.Equal(test.ExpectedLosers, losers)
@@ -453,3 +771,74 @@ func TestJanken(t *testing.T) {
 		})
 	}
 }
+
+func TestDuel(t *testing.T) {
+	for name, test := range map[string]struct {
+		MaxRounds      int
+		p1             *participant
+		p2             *participant
+		ExpectedWinner string
+		ExpectedLoser  string
+	}{
+		"p1 wins": {
+			MaxRounds:      2,
+			p1:             &participant{UserID: "p1", Hands: []string{"rock", "rock"}},
+			p2:             &participant{UserID: "p2", Hands: []string{"rock", "scissors"}},
+			ExpectedWinner: "p1",
+			ExpectedLoser:  "p2",
+		},
+		"p2 wins": {
+			MaxRounds:      2,
+			p1:             &participant{UserID: "p1", Hands: []string{"rock", "rock"}},
+			p2:             &participant{UserID: "p2", Hands: []string{"paper", "scissors"}},
+			ExpectedWinner: "p2",
+			ExpectedLoser:  "p1",
+		},
+		"all rounds are drawn": {
+			MaxRounds:      2,
+			p1:             &participant{UserID: "p1", Hands: []string{"rock", "paper", "scissors"}},
+			p2:             &participant{UserID: "p2", Hands: []string{"rock", "paper", "rock"}},
+			ExpectedWinner: "",
+			ExpectedLoser:  "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			winner, loser := (&gameBase{}).duel(test.p1, test.p2, test.MaxRounds)
+
+			if test.ExpectedWinner == "" {
+				assert.Nil(winner)
+				assert.Nil(loser)
+				return
+			}
+			assert.Equal(test.ExpectedWinner, winner.UserID)
+			assert.Equal(test.ExpectedLoser, loser.UserID)
+		})
+	}
+}
+
+func TestRecordRound(t *testing.T) {
+	assert := assert.New(t)
+	b := newGame(&gameImpl1{})
+	g := &gameBase{base: b}
+	p1 := &participant{UserID: "p1", Hands: []string{"rock", "rock"}}
+	p2 := &participant{UserID: "p2", Hands: []string{"rock", "scissors"}}
+
+	winner, loser := g.duel(p1, p2, 2)
+
+	assert.Equal(p1, winner)
+	assert.Equal(p2, loser)
+	assert.Equal([]*roundEvent{
+		{Round: 0, Members: []string{"p1", "p2"}, Hands: []string{"rock", "rock"}, Draw: true},
+		{Round: 1, Members: []string{"p1", "p2"}, Hands: []string{"rock", "scissors"}, Winners: []string{"p1"}, Losers: []string{"p2"}},
+	}, b.Rounds)
+}
+
+func TestGetGameTypeByOptionName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Len(gameTypeOptionNames, len(gameTypeNames))
+	assert.Equal("gameImpl1", getGameTypeByOptionName("ranking"))
+	assert.Equal("gameImplLive", getGameTypeByOptionName("live"))
+	assert.Equal("", getGameTypeByOptionName("gameImpl1"))
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// subcommandLeaderboard はチャンネルやチームのランキングを表示するサブコマンド
+	subcommandLeaderboard = "leaderboard"
+
+	// ランキングの集計範囲
+	leaderboardScopeChannel = "channel"
+	leaderboardScopeTeam    = "team"
+
+	// ランキングの集計期間．週と月はUTCの暦の週(月曜日から)と月で区切る
+	leaderboardPeriodWeek  = "week"
+	leaderboardPeriodMonth = "month"
+	leaderboardPeriodAll   = "all"
+
+	// ランキングの順位を決めるスコア
+	leaderboardScoreWins = "wins"
+	leaderboardScoreRank = "rank"
+	leaderboardScoreElo  = "elo"
+
+	defaultLeaderboardScore = leaderboardScoreWins
+
+	// leaderboardSize はランキングに表示する人数
+	leaderboardSize = 10
+)
+
+var leaderboardPeriods = []string{leaderboardPeriodWeek, leaderboardPeriodMonth, leaderboardPeriodAll}
+
+var (
+	leaderboardTitle = &i18n.Message{
+		ID:    "leaderboardTitle",
+		Other: "Janken leaderboard of this {{.Scope}} ({{.Period}}, by {{.Score}})",
+	}
+	leaderboardHeader = &i18n.Message{
+		ID: "leaderboardHeader",
+		Other: `| Rank | User | Games | Wins | Win rate | Average normalized rank | Elo |
+| --- | --- | --- | --- | --- | --- | --- |`,
+	}
+	leaderboardScopeConflictErrorMessage = &i18n.Message{
+		ID:    "leaderboardScopeConflictErrorMessage",
+		Other: "Specify either --channel or --team.",
+	}
+	leaderboardEmptyMessage = &i18n.Message{
+		ID:    "leaderboardEmptyMessage",
+		Other: "There are no finished janken games in this {{.Scope}} for this period.",
+	}
+	leaderboardScopeMessages = map[string]*i18n.Message{
+		leaderboardScopeChannel: {
+			ID:    "leaderboardScopeChannel",
+			Other: "channel",
+		},
+		leaderboardScopeTeam: {
+			ID:    "leaderboardScopeTeam",
+			Other: "team",
+		},
+	}
+	leaderboardPeriodMessages = map[string]*i18n.Message{
+		leaderboardPeriodWeek: {
+			ID:    "leaderboardPeriodWeek",
+			Other: "this week",
+		},
+		leaderboardPeriodMonth: {
+			ID:    "leaderboardPeriodMonth",
+			Other: "this month",
+		},
+		leaderboardPeriodAll: {
+			ID:    "leaderboardPeriodAll",
+			Other: "all time",
+		},
+	}
+	leaderboardScoreMessages = map[string]*i18n.Message{
+		leaderboardScoreWins: {
+			ID:    "leaderboardScoreWins",
+			Other: "wins",
+		},
+		leaderboardScoreRank: {
+			ID:    "leaderboardScoreRank",
+			Other: "average normalized rank",
+		},
+		leaderboardScoreElo: {
+			ID:    "leaderboardScoreElo",
+			Other: "Elo",
+		},
+	}
+)
+
+/*
+leaderboard はチャンネルまたはチームの期間ごとの参加者の成績の集計．
+ゲームの結果を表示するたびに更新する
+*/
+type leaderboard struct {
+	Entries map[string]*leaderboardEntry `json:"entries"`
+}
+
+// leaderboardEntry はランキングの参加者1人分の成績
+type leaderboardEntry struct {
+	// 参加したゲームの数と1位になったゲームの数
+	Games int `json:"games"`
+	Wins  int `json:"wins"`
+	// 順位がついたゲームの数と正規化した順位の合計
+	RankedGames       int     `json:"ranked_games"`
+	NormalizedRankSum float64 `json:"normalized_rank_sum"`
+	// 集計範囲と期間の中でのEloレーティング
+	Rating float64 `json:"rating"`
+}
+
+// leaderboardRow はランキングに表示する1行
+type leaderboardRow struct {
+	UserID string
+	Entry  *leaderboardEntry
+	Score  float64
+}
+
+func newLeaderboard() *leaderboard {
+	return &leaderboard{Entries: map[string]*leaderboardEntry{}}
+}
+
+// entry は参加者の成績を返す．まだ成績がない場合は作る
+func (b *leaderboard) entry(userID string) *leaderboardEntry {
+	e := b.Entries[userID]
+	if e == nil {
+		e = &leaderboardEntry{Rating: eloInitialRating}
+		b.Entries[userID] = e
+	}
+	return e
+}
+
+/*
+add はゲームの結果を集計に加える．
+正規化した順位は1位を1，最下位を0とし，順位がついた参加者が2人以上のゲームだけで集計する
+*/
+func (b *leaderboard) add(record *gameRecord) {
+	ranked := []*recordParticipant{}
+	for _, p := range record.Participants {
+		e := b.entry(p.UserID)
+		e.Games++
+		if p.Rank == 1 {
+			e.Wins++
+		}
+		if p.Rank > 0 {
+			ranked = append(ranked, p)
+		}
+	}
+	if len(ranked) < 2 {
+		return
+	}
+
+	n := float64(len(ranked))
+	for _, p := range ranked {
+		e := b.entry(p.UserID)
+		e.RankedGames++
+		e.NormalizedRankSum += math.Max(0, (n-float64(p.Rank))/(n-1))
+	}
+
+	ratings := map[string]float64{}
+	for _, p := range ranked {
+		ratings[p.UserID] = b.entry(p.UserID).Rating
+	}
+	for userID, delta := range eloDeltas(ranked, ratings) {
+		b.entry(userID).Rating += delta
+	}
+}
+
+// averageNormalizedRank は正規化した順位の平均を返す．順位がついたゲームがない場合は0
+func (e *leaderboardEntry) averageNormalizedRank() float64 {
+	if e.RankedGames == 0 {
+		return 0
+	}
+	return e.NormalizedRankSum / float64(e.RankedGames)
+}
+
+// score は指定したスコアの値を返す
+func (e *leaderboardEntry) score(score string) float64 {
+	switch score {
+	case leaderboardScoreRank:
+		return e.averageNormalizedRank()
+	case leaderboardScoreElo:
+		return e.Rating
+	default:
+		return float64(e.Wins)
+	}
+}
+
+/*
+rows はスコアの高い順に並べたランキングを返す．
+スコアが同じ場合は参加したゲームが多い順，UserIDの順に並べる
+*/
+func (b *leaderboard) rows(score string) []*leaderboardRow {
+	rows := make([]*leaderboardRow, 0, len(b.Entries))
+	for userID, e := range b.Entries {
+		rows = append(rows, &leaderboardRow{UserID: userID, Entry: e, Score: e.score(score)})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Score != rows[j].Score {
+			return rows[i].Score > rows[j].Score
+		}
+		if rows[i].Entry.Games != rows[j].Entry.Games {
+			return rows[i].Entry.Games > rows[j].Entry.Games
+		}
+		return rows[i].UserID < rows[j].UserID
+	})
+	return rows
+}
+
+/*
+leaderboardBucket は日時が含まれる集計期間の識別子を返す．
+Returns:
+    string: 週は"2006-W01"，月は"2006-01"，全期間は"all"
+*/
+func leaderboardBucket(period string, millis int64) string {
+	t := time.Unix(0, millis*int64(time.Millisecond)).UTC()
+	switch period {
+	case leaderboardPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case leaderboardPeriodMonth:
+		return t.Format("2006-01")
+	default:
+		return leaderboardPeriodAll
+	}
+}
+
+/*
+leaderboardExpireInSeconds は集計期間の集計を保存する秒数を返す．
+表示するのは現在の期間の集計だけなので，週と月の集計は期間が終わった後に削除する
+*/
+func leaderboardExpireInSeconds(period string) int64 {
+	day := int64(24 * 60 * 60)
+	switch period {
+	case leaderboardPeriodWeek:
+		return 8 * day
+	case leaderboardPeriodMonth:
+		return 32 * day
+	default:
+		return 0
+	}
+}
+
+// updateLeaderboards はゲームのチャンネルとチームの全ての期間の集計に結果を加える
+func (p *Plugin) updateLeaderboards(record *gameRecord) {
+	scopes := map[string]string{
+		leaderboardScopeChannel: record.ChannelID,
+		leaderboardScopeTeam:    record.TeamID,
+	}
+	for scope, scopeID := range scopes {
+		if scopeID == "" {
+			continue
+		}
+		for _, period := range leaderboardPeriods {
+			bucket := leaderboardBucket(period, record.FinishedAt)
+			err := p.store.leaderboardStore.Update(scope, scopeID, bucket, leaderboardExpireInSeconds(period), func(b *leaderboard) {
+				b.add(record)
+			})
+			if err != nil {
+				p.API.LogError("failed to update the leaderboard", "scope", scope, "id", scopeID, "bucket", bucket, "error", err.Error())
+			}
+		}
+	}
+}
+
+type parsedLeaderboardArgs struct {
+	Scope  string
+	Period string
+}
+
+// parseLeaderboardArgs はleaderboardサブコマンドの引数を解析する
+func parseLeaderboardArgs(command string) (*parsedLeaderboardArgs, error) {
+	fs := flag.NewFlagSet(subcommandLeaderboard, flag.ContinueOnError)
+	fs.SetOutput(&strings.Builder{})
+	channel := fs.Bool("channel", false, "Rank the users in this channel.")
+	team := fs.Bool("team", false, "Rank the users in this team.")
+	period := fs.String("period", leaderboardPeriodAll, `Period option. Available values are "week", "month" or "all".`)
+
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return nil, newFlagError(err)
+	}
+	if len(fs.Args()) > 0 {
+		return nil, newUsageError(usageUnexpectedArgumentsErrorMessage, map[string]interface{}{
+			"Arguments": strings.Join(fs.Args(), " "),
+		})
+	}
+	if *channel && *team {
+		return nil, newUsageError(leaderboardScopeConflictErrorMessage, nil)
+	}
+
+	parsed := &parsedLeaderboardArgs{Scope: leaderboardScopeChannel, Period: *period}
+	if *team {
+		parsed.Scope = leaderboardScopeTeam
+	}
+	if _, ok := leaderboardPeriodMessages[parsed.Period]; !ok {
+		return nil, newUsageError(usageInvalidChoiceErrorMessage, map[string]interface{}{
+			"Value":  parsed.Period,
+			"Option": "period",
+			"Values": strings.Join(leaderboardPeriods, ", "),
+		})
+	}
+	return parsed, nil
+}
+
+// executeLeaderboardCommand はチャンネルまたはチームのランキングを表示する
+func (p *Plugin) executeLeaderboardCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	parsed, err := parseLeaderboardArgs(args.Command)
+	if err != nil {
+		return p.newUsageErrorResponse(siteURL, err)
+	}
+
+	scopeID := args.ChannelId
+	if parsed.Scope == leaderboardScopeTeam {
+		scopeID = args.TeamId
+	}
+	bucket := leaderboardBucket(parsed.Period, model.GetMillis())
+	board, err := p.store.leaderboardStore.Get(parsed.Scope, scopeID, bucket)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get the leaderboard.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+
+	scopeLabel := Localize(l, leaderboardScopeMessages[parsed.Scope], nil)
+	if len(board.Entries) == 0 {
+		message := Localize(l, leaderboardEmptyMessage, map[string]interface{}{
+			"Scope": scopeLabel,
+		})
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	score := p.getConfiguration().GetLeaderboardScore()
+	lines := []string{
+		fmt.Sprintf("#### %s", Localize(l, leaderboardTitle, map[string]interface{}{
+			"Scope":  scopeLabel,
+			"Period": Localize(l, leaderboardPeriodMessages[parsed.Period], nil),
+			"Score":  Localize(l, leaderboardScoreMessages[score], nil),
+		})),
+		Localize(l, leaderboardHeader, nil),
+	}
+	rows := board.rows(score)
+	rank := 0
+	for i, row := range rows {
+		if i >= leaderboardSize {
+			break
+		}
+		// スコアが同じ場合は同じ順位にする
+		if i == 0 || row.Score != rows[i-1].Score {
+			rank = i + 1
+		}
+		e := row.Entry
+		lines = append(lines, fmt.Sprintf("| %d | @%s | %d | %d | %.0f%% | %.2f | %.0f |",
+			rank,
+			p.getUsername(row.UserID),
+			e.Games,
+			e.Wins,
+			float64(e.Wins)/float64(e.Games)*100,
+			e.averageNormalizedRank(),
+			e.Rating,
+		))
+	}
+	message := strings.Join(lines, "\n")
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
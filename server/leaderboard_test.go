@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	newRecord := func(ranks ...int) *gameRecord {
+		r := &gameRecord{}
+		for i, rank := range ranks {
+			r.Participants = append(r.Participants, &recordParticipant{UserID: []string{"p1", "p2", "p3"}[i], Rank: rank})
+		}
+		return r
+	}
+
+	t.Run("add", func(t *testing.T) {
+		assert := assert.New(t)
+		b := newLeaderboard()
+
+		b.add(newRecord(1, 2, 3))
+
+		assert.Equal(1, b.Entries["p1"].Wins)
+		assert.Equal(1.0, b.Entries["p1"].NormalizedRankSum)
+		assert.Equal(0.5, b.Entries["p2"].NormalizedRankSum)
+		assert.Equal(0.0, b.Entries["p3"].NormalizedRankSum)
+		// 同じレーティングからの対戦では真ん中の順位のレーティングは変わらない
+		assert.InDelta(eloInitialRating+eloK/2, b.Entries["p1"].Rating, 0.001)
+		assert.InDelta(eloInitialRating, b.Entries["p2"].Rating, 0.001)
+		assert.InDelta(eloInitialRating-eloK/2, b.Entries["p3"].Rating, 0.001)
+
+		// 順位なしの参加者は参加数だけ数える
+		b.add(newRecord(1, 0, 0))
+		assert.Equal(2, b.Entries["p3"].Games)
+		assert.Equal(1, b.Entries["p3"].RankedGames)
+		assert.InDelta(eloInitialRating-eloK/2, b.Entries["p3"].Rating, 0.001)
+		assert.Equal(2, b.Entries["p1"].Wins)
+	})
+
+	t.Run("rows", func(t *testing.T) {
+		b := newLeaderboard()
+		b.Entries["p1"] = &leaderboardEntry{Games: 4, Wins: 1, RankedGames: 4, NormalizedRankSum: 3, Rating: 1490}
+		b.Entries["p2"] = &leaderboardEntry{Games: 2, Wins: 2, RankedGames: 2, NormalizedRankSum: 2, Rating: 1520}
+		b.Entries["p3"] = &leaderboardEntry{Games: 3, Wins: 2, RankedGames: 3, NormalizedRankSum: 2, Rating: 1510}
+
+		for score, expected := range map[string][]string{
+			leaderboardScoreWins: {"p3", "p2", "p1"},
+			leaderboardScoreRank: {"p2", "p1", "p3"},
+			leaderboardScoreElo:  {"p2", "p3", "p1"},
+		} {
+			t.Run(score, func(t *testing.T) {
+				ids := []string{}
+				for _, row := range b.rows(score) {
+					ids = append(ids, row.UserID)
+				}
+				assert.Equal(t, expected, ids)
+			})
+		}
+	})
+
+	t.Run("leaderboardBucket", func(t *testing.T) {
+		assert := assert.New(t)
+		millis := time.Date(2021, 1, 3, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+		// 2021-01-03はISO週では2020年の第53週
+		assert.Equal("2020-W53", leaderboardBucket(leaderboardPeriodWeek, millis))
+		assert.Equal("2021-01", leaderboardBucket(leaderboardPeriodMonth, millis))
+		assert.Equal("all", leaderboardBucket(leaderboardPeriodAll, millis))
+	})
+}
+
+func TestParseLeaderboardArgs(t *testing.T) {
+	for name, test := range map[string]struct {
+		Command     string
+		Expected    *parsedLeaderboardArgs
+		ShouldError bool
+	}{
+		"default": {
+			Command:  "/janken leaderboard",
+			Expected: &parsedLeaderboardArgs{Scope: leaderboardScopeChannel, Period: leaderboardPeriodAll},
+		},
+		"team and week": {
+			Command:  "/janken leaderboard --team --period week",
+			Expected: &parsedLeaderboardArgs{Scope: leaderboardScopeTeam, Period: leaderboardPeriodWeek},
+		},
+		"channel and month": {
+			Command:  "/janken leaderboard -channel -period=month",
+			Expected: &parsedLeaderboardArgs{Scope: leaderboardScopeChannel, Period: leaderboardPeriodMonth},
+		},
+		"channel and team": {
+			Command:     "/janken leaderboard --channel --team",
+			ShouldError: true,
+		},
+		"invalid period": {
+			Command:     "/janken leaderboard --period year",
+			ShouldError: true,
+		},
+		"unknown flag": {
+			Command:     "/janken leaderboard --global",
+			ShouldError: true,
+		},
+		"extra argument": {
+			Command:     "/janken leaderboard week",
+			ShouldError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			parsed, err := parseLeaderboardArgs(test.Command)
+			assert.Equal(test.Expected, parsed)
+			if test.ShouldError {
+				assert.IsType(&usageError{}, err)
+			} else {
+				assert.Nil(err)
+			}
+		})
+	}
+}
@@ -1,9 +1,32 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
 	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
 func main() {
+	// "verify <file>" verifies a revealed game without the Mattermost server.
+	if len(os.Args) == 3 && os.Args[1] == "verify" {
+		os.Exit(verify(os.Args[2]))
+	}
 	plugin.ClientMain(&Plugin{})
 }
+
+// verify recomputes the result of a revealed game saved to a file.
+func verify(filename string) int {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := verifyRevealedGameJSON(b); err != nil {
+		fmt.Fprintln(os.Stderr, "NG:", err)
+		return 1
+	}
+	fmt.Println("OK: the result matches the commitments")
+	return 0
+}
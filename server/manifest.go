@@ -16,7 +16,7 @@ const manifestStr = `
   "name": "Janken",
   "description": "This plugin provide /janken command.",
   "version": "0.0.2",
-  "min_server_version": "5.12.0",
+  "min_server_version": "5.24.0",
   "server": {
     "executables": {
       "linux-amd64": "server/dist/plugin-linux-amd64",
@@ -54,6 +54,58 @@ const manifestStr = `
             "value": "ja"
           }
         ]
+      },
+      {
+        "key": "historyRetentionDays",
+        "display_name": "HistoryRetentionDays",
+        "type": "text",
+        "help_text": "Number of days to keep finished games in the history. 0 keeps them forever (default to 90)",
+        "placeholder": "",
+        "default": "90"
+      },
+      {
+        "key": "leaderboardScore",
+        "display_name": "LeaderboardScore",
+        "type": "dropdown",
+        "help_text": "Score to rank users in /janken leaderboard (default to \"Wins\")",
+        "placeholder": "",
+        "default": "wins",
+        "options": [
+          {
+            "display_name": "Wins",
+            "value": "wins"
+          },
+          {
+            "display_name": "Average normalized rank",
+            "value": "rank"
+          },
+          {
+            "display_name": "Elo",
+            "value": "elo"
+          }
+        ]
+      },
+      {
+        "key": "resultPublishMode",
+        "display_name": "ResultPublishMode",
+        "type": "dropdown",
+        "help_text": "How to show the result of a game unless the game sets it. New messages and thread replies notify the participants (default to \"Add to the game post\")",
+        "placeholder": "",
+        "default": "inplace",
+        "options": [
+          {
+            "display_name": "Add to the game post",
+            "value": "inplace"
+          },
+          {
+            "display_name": "Post as a new message",
+            "value": "post"
+          },
+          {
+            "display_name": "Reply in the thread",
+            "value": "thread"
+          }
+        ]
       }
     ]
   }
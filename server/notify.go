@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// subcommandNotify は結果のダイレクトメッセージを受け取るかを切り替えるサブコマンド
+	subcommandNotify = "notify"
+
+	notifyOn  = "on"
+	notifyOff = "off"
+
+	// permalinkRedirectTeam はチームが分からない場合にpostへのリンクで使うチーム名．Mattermostが所属するチームへ転送する
+	permalinkRedirectTeam = "_redirect"
+)
+
+// notifyValues はnotifyサブコマンドの引数の候補
+var notifyValues = []string{notifyOn, notifyOff}
+
+var (
+	subcommandNotifyDescription = &i18n.Message{
+		ID:    "subcommandNotifyDescription",
+		Other: "Turn on or off the direct messages of your results. Without an argument, show the current setting",
+	}
+	notifyOnMessage = &i18n.Message{
+		ID:    "notifyOnMessage",
+		Other: "Direct messages of your janken results are on.",
+	}
+	notifyOffMessage = &i18n.Message{
+		ID:    "notifyOffMessage",
+		Other: "Direct messages of your janken results are off. Run `/{{.Trigger}} notify on` to turn them on.",
+	}
+	notifyInvalidArgumentErrorMessage = &i18n.Message{
+		ID:    "notifyInvalidArgumentErrorMessage",
+		Other: "Specify on or off: {{.Value}}",
+	}
+	notificationTitle = &i18n.Message{
+		ID:    "NotificationTitle",
+		Other: "The result of janken game ({{.ID}}) is ready.",
+	}
+	notificationCustomTitle = &i18n.Message{
+		ID:    "NotificationCustomTitle",
+		Other: "The result of **{{.Title}}** ({{.ID}}) is ready.",
+	}
+	notificationRankMessage = &i18n.Message{
+		ID:    "NotificationRankMessage",
+		Other: "Your rank: **{{.Rank}}** of {{.Participants}}",
+	}
+	notificationUnrankedMessage = &i18n.Message{
+		ID:    "NotificationUnrankedMessage",
+		Other: "Your rank: not ranked",
+	}
+	notificationHandsMessage = &i18n.Message{
+		ID:    "NotificationHandsMessage",
+		Other: "Your hands: {{.Hands}}",
+	}
+	notificationOutcomeMessage = &i18n.Message{
+		ID:    "NotificationOutcomeMessage",
+		Other: "Your outcome: {{.Outcome}}",
+	}
+	notificationLinkMessage = &i18n.Message{
+		ID:    "NotificationLinkMessage",
+		Other: "[Open the result]({{.Link}})",
+	}
+	notificationOptOutMessage = &i18n.Message{
+		ID:    "NotificationOptOutMessage",
+		Other: "Run `/{{.Trigger}} notify off` to stop these messages.",
+	}
+)
+
+/*
+notifyParticipants は参加者それぞれに順位と手と結果へのリンクをボットのダイレクトメッセージで送る．
+ダイレクトメッセージを止めた参加者には送らない．ボットがない場合は何もしない
+Args:
+    game: 結果を表示したゲーム
+    result: getResultの結果
+    postID: 結果を表示したpostのID
+*/
+func (p *Plugin) notifyParticipants(game *game, result []*participant, postID string) {
+	if p.botUserID == "" {
+		return
+	}
+	link := p.getPermalink(game.TeamID, postID)
+	outcomes := assignOutcomes(game.Outcomes, result)
+	for _, participant := range result {
+		disabled, err := p.store.notificationStore.IsDisabled(participant.UserID)
+		if err != nil {
+			p.API.LogError("failed to get the notification setting", "user_id", participant.UserID, "error", err.Error())
+			continue
+		}
+		if disabled {
+			continue
+		}
+
+		channel, appErr := p.API.GetDirectChannel(p.botUserID, participant.UserID)
+		if appErr != nil {
+			p.API.LogError("failed to get the direct channel", "user_id", participant.UserID, "error", appErr.Error())
+			continue
+		}
+		post := &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: channel.Id,
+			Message:   p.getNotificationMessage(game, participant, len(result), outcomes[participant.UserID], link),
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogError("failed to send the result", "user_id", participant.UserID, "error", appErr.Error())
+		}
+	}
+}
+
+/*
+getNotificationMessage は参加者に送るダイレクトメッセージを返す
+Args:
+    game: 結果を表示したゲーム
+    participant: メッセージを送る参加者
+    count: 参加人数
+    outcomes: 参加者に割り当てられた結果
+    link: 結果を表示したpostへのリンク．空文字の場合は表示しない
+*/
+func (p *Plugin) getNotificationMessage(game *game, participant *participant, count int, outcomes []string, link string) string {
+	l := p.getLocalizer(game.Language)
+
+	title := Localize(l, notificationTitle, map[string]interface{}{
+		"ID": game.getShortID(),
+	})
+	if game.Title != "" {
+		title = Localize(l, notificationCustomTitle, map[string]interface{}{
+			"Title": game.Title,
+			"ID":    game.getShortID(),
+		})
+	}
+	lines := []string{title}
+
+	// 順位なしの参加者は順位の代わりに順位なしと表示する
+	if participant.Rank > 0 {
+		lines = append(lines, Localize(l, notificationRankMessage, map[string]interface{}{
+			"Rank":         participant.Rank,
+			"Participants": count,
+		}))
+	} else {
+		lines = append(lines, Localize(l, notificationUnrankedMessage, nil))
+	}
+
+	hs := game.getHandSet()
+	hands := make([]string, 0, len(participant.Hands))
+	for _, h := range participant.Hands {
+		hands = append(hands, hs.icon(h))
+	}
+	if len(hands) > 0 {
+		lines = append(lines, Localize(l, notificationHandsMessage, map[string]interface{}{
+			"Hands": strings.Join(hands, " "),
+		}))
+	}
+
+	if len(outcomes) > 0 {
+		lines = append(lines, Localize(l, notificationOutcomeMessage, map[string]interface{}{
+			"Outcome": strings.Join(outcomes, ", "),
+		}))
+	}
+	if link != "" {
+		lines = append(lines, Localize(l, notificationLinkMessage, map[string]interface{}{
+			"Link": link,
+		}))
+	}
+	lines = append(lines, "", Localize(l, notificationOptOutMessage, map[string]interface{}{
+		"Trigger": p.configuration.Trigger,
+	}))
+	return strings.Join(lines, "\n")
+}
+
+// getPermalink はpostへのリンクを返す．チームが分からない場合はMattermostにチームを選ばせる
+func (p *Plugin) getPermalink(teamID, postID string) string {
+	if postID == "" {
+		return ""
+	}
+	teamName := permalinkRedirectTeam
+	if teamID != "" {
+		if team, appErr := p.API.GetTeam(teamID); appErr == nil {
+			teamName = team.Name
+		}
+	}
+	return fmt.Sprintf("%s/%s/pl/%s", *p.ServerConfig.ServiceSettings.SiteURL, teamName, postID)
+}
+
+// executeNotifyCommand は実行したユーザーが結果のダイレクトメッセージを受け取るかを切り替える．引数がない場合は現在の設定を表示する
+func (p *Plugin) executeNotifyCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	value, err := parseNotifyArgs(args.Command)
+	if err != nil {
+		return p.newUsageErrorResponse(siteURL, err)
+	}
+
+	var disabled bool
+	if value == "" {
+		disabled, err = p.store.notificationStore.IsDisabled(args.UserId)
+		if err != nil {
+			errmsg := fmt.Sprintf("Failed to get the notification setting.: %s", err.Error())
+			return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+		}
+	} else {
+		disabled = value == notifyOff
+		if err := p.store.notificationStore.SetDisabled(args.UserId, disabled); err != nil {
+			errmsg := fmt.Sprintf("Failed to save the notification setting.: %s", err.Error())
+			return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+		}
+	}
+
+	message := Localize(l, notifyOnMessage, nil)
+	if disabled {
+		message = Localize(l, notifyOffMessage, map[string]interface{}{
+			"Trigger": p.configuration.Trigger,
+		})
+	}
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+/*
+parseNotifyArgs はnotifyサブコマンドの引数を返す
+Returns:
+    string: "on"か"off"．引数がない場合は空文字
+    error: 引数が不正な場合
+*/
+func parseNotifyArgs(command string) (string, error) {
+	fields, err := shellquote.Split(command)
+	if err != nil {
+		return "", err
+	}
+	fields = fields[2:]
+	if len(fields) == 0 {
+		return "", nil
+	}
+	value := strings.ToLower(fields[0])
+	if len(fields) > 1 || !containsString(notifyValues, value) {
+		return "", newUsageError(notifyInvalidArgumentErrorMessage, map[string]interface{}{
+			"Value": strings.Join(fields, " "),
+		})
+	}
+	return value, nil
+}
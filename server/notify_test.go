@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/text/language"
+)
+
+func TestNotify(t *testing.T) {
+	newPlugin := func() (*Plugin, map[string]*model.Post) {
+		api, _ := newAtomicKVAPI()
+		sent := map[string]*model.Post{}
+		api.On("GetDirectChannel", "bot", mock.AnythingOfType("string")).Return(func(botID, userID string) *model.Channel {
+			return &model.Channel{Id: "dm_" + userID}
+		}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			sent[post.ChannelId] = post
+			return post
+		}, nil)
+		api.On("GetTeam", "team").Return(&model.Team{Name: "myteam"}, nil)
+		siteURL := dummySiteURL
+		p := &Plugin{
+			configuration: &pluginConfig{Trigger: "janken", DefaultLanguage: "en"},
+			ServerConfig:  &model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}},
+			bundle:        i18n.NewBundle(language.English),
+			botUserID:     "bot",
+		}
+		p.SetAPI(api)
+		p.store = NewStore(api)
+		return p, sent
+	}
+
+	t.Run("notifyParticipants", func(t *testing.T) {
+		assert := assert.New(t)
+		p, sent := newPlugin()
+		assert.Nil(p.store.notificationStore.SetDisabled("p2", true))
+
+		g := newGame(&gameImpl1{})
+		g.TeamID = "team"
+		g.Title = "Who buys coffee?"
+		g.Outcomes = []*rankOutcome{{Rank: lastRank, Text: "buys coffee"}}
+		result := []*participant{
+			{UserID: "p1", Rank: 1, Hands: []string{"rock"}},
+			{UserID: "p2", Rank: 1, Hands: []string{"rock"}},
+			{UserID: "p3", Rank: 2, Hands: []string{"scissors"}},
+		}
+
+		p.notifyParticipants(g, result, "result_post")
+
+		// ダイレクトメッセージを止めた参加者には送らない
+		assert.Len(sent, 2)
+		assert.NotContains(sent, "dm_p2")
+		assert.Equal("bot", sent["dm_p1"].UserId)
+		assert.Contains(sent["dm_p1"].Message, "Your rank: **1** of 3")
+		assert.NotContains(sent["dm_p1"].Message, "Your outcome")
+		message := sent["dm_p3"].Message
+		assert.Contains(message, "The result of **Who buys coffee?**")
+		assert.Contains(message, "Your rank: **2** of 3")
+		assert.Contains(message, "Your outcome: buys coffee")
+		assert.Contains(message, "("+dummySiteURL+"/myteam/pl/result_post)")
+		assert.Contains(message, "`/janken notify off`")
+	})
+
+	t.Run("notifyParticipants without the bot", func(t *testing.T) {
+		assert := assert.New(t)
+		p, sent := newPlugin()
+		p.botUserID = ""
+
+		p.notifyParticipants(newGame(&gameImpl1{}), []*participant{{UserID: "p1", Rank: 1}}, "result_post")
+
+		assert.Empty(sent)
+	})
+
+	t.Run("getNotificationMessage of an unranked participant", func(t *testing.T) {
+		assert := assert.New(t)
+		p, _ := newPlugin()
+
+		message := p.getNotificationMessage(newGame(&gameImpl1{}), &participant{UserID: "p1"}, 3, nil, "")
+
+		assert.Contains(message, "The result of janken game")
+		assert.Contains(message, "Your rank: not ranked")
+		assert.NotContains(message, "Your hands")
+		assert.NotContains(message, "Open the result")
+	})
+
+	t.Run("getPermalink", func(t *testing.T) {
+		assert := assert.New(t)
+		p, _ := newPlugin()
+
+		assert.Equal(dummySiteURL+"/myteam/pl/post", p.getPermalink("team", "post"))
+		assert.Equal(dummySiteURL+"/_redirect/pl/post", p.getPermalink("", "post"))
+		assert.Equal("", p.getPermalink("team", ""))
+	})
+
+	t.Run("executeNotifyCommand", func(t *testing.T) {
+		assert := assert.New(t)
+		p, _ := newPlugin()
+		execute := func(command string) string {
+			return p.executeNotifyCommand(dummySiteURL, &model.CommandArgs{Command: command, UserId: "p1"}).Text
+		}
+
+		assert.Equal("Direct messages of your janken results are on.", execute("/janken notify"))
+		assert.Contains(execute("/janken notify off"), "are off")
+		disabled, _ := p.store.notificationStore.IsDisabled("p1")
+		assert.True(disabled)
+		assert.Contains(execute("/janken notify"), "are off")
+		assert.Contains(execute("/janken notify ON"), "are on")
+		disabled, _ = p.store.notificationStore.IsDisabled("p1")
+		assert.False(disabled)
+		assert.Contains(execute("/janken notify maybe"), "Specify on or off: maybe")
+	})
+}
+
+func TestParseNotifyArgs(t *testing.T) {
+	for name, test := range map[string]struct {
+		Command       string
+		ExpectedValue string
+		ShouldError   bool
+	}{
+		"no argument":        {Command: "/janken notify", ExpectedValue: ""},
+		"on":                 {Command: "/janken notify on", ExpectedValue: notifyOn},
+		"off in upper case":  {Command: "/janken notify OFF", ExpectedValue: notifyOff},
+		"unknown value":      {Command: "/janken notify yes", ShouldError: true},
+		"too many arguments": {Command: "/janken notify on off", ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			value, err := parseNotifyArgs(test.Command)
+			if test.ShouldError {
+				assert.IsType(&usageError{}, err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.ExpectedValue, value)
+		})
+	}
+}
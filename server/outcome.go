@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// lastRank は最下位を表す順位
+	lastRank = -1
+	// outcomeLastName は設定ダイアログで最下位を表す名前
+	outcomeLastName = "last"
+	// maxOutcomes は設定できる結果の数の上限
+	maxOutcomes = 10
+	// maxOutcomeLength は1つの結果の最大文字数
+	maxOutcomeLength = 100
+)
+
+var (
+	jankenGameOutcomesDescription = &i18n.Message{
+		ID:    "gameOutcomesDescription",
+		Other: "Outcomes: {{.Outcomes}}",
+	}
+	resultTableOutcomeLabel = &i18n.Message{
+		ID:    "ResultTableOutcomeLabel",
+		Other: "Outcome",
+	}
+	outcomeNotificationMessage = &i18n.Message{
+		ID:    "OutcomeNotificationMessage",
+		Other: "**Outcomes of janken game ({{.ID}})**\n{{.Outcomes}}",
+	}
+	outcomeRankLabel = &i18n.Message{
+		ID:    "outcomeRankLabel",
+		Other: "#{{.Rank}}",
+	}
+	outcomeLastRankLabel = &i18n.Message{
+		ID:    "outcomeLastRankLabel",
+		Other: "last",
+	}
+)
+
+// outcomeLinePattern は設定ダイアログの結果の1行．"1: picks lunch place"や"1st: ..."や"last: ..."
+var outcomeLinePattern = regexp.MustCompile(`(?i)^\s*(\d+|last)(?:st|nd|rd|th)?\s*:\s*(.+?)\s*$`)
+
+// rankOutcome は順位に割り当てた賞品や罰ゲーム
+type rankOutcome struct {
+	// 順位．lastRankの場合は最下位
+	Rank int    `json:"rank"`
+	Text string `json:"text"`
+}
+
+/*
+parseOutcomes は設定ダイアログに1行ずつ入力された結果を返す．
+空行は無視する．同じ順位が複数ある場合は後の行で上書きする
+Args:
+    s: "1: picks lunch place\nlast: buys coffee"のような文字列
+Returns:
+    []*rankOutcome: 順位の順に並べた結果．最下位は最後
+    error: 解析できない行がある場合や数や長さが上限を超える場合
+*/
+func parseOutcomes(s string) ([]*rankOutcome, error) {
+	byRank := map[int]*rankOutcome{}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := outcomeLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("invalid outcome: %s", line)
+		}
+		rank := lastRank
+		if !strings.EqualFold(m[1], outcomeLastName) {
+			n, err := strconv.Atoi(m[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid rank: %s", m[1])
+			}
+			rank = n
+		}
+		if utf8.RuneCountInString(m[2]) > maxOutcomeLength {
+			return nil, fmt.Errorf("too long outcome: %s", line)
+		}
+		byRank[rank] = &rankOutcome{Rank: rank, Text: m[2]}
+	}
+	if len(byRank) > maxOutcomes {
+		return nil, errors.New("too many outcomes")
+	}
+
+	outcomes := []*rankOutcome{}
+	for _, o := range byRank {
+		outcomes = append(outcomes, o)
+	}
+	sortOutcomes(outcomes)
+	return outcomes, nil
+}
+
+// sortOutcomes は結果を順位の順に並べる．最下位は最後にする
+func sortOutcomes(outcomes []*rankOutcome) {
+	sort.Slice(outcomes, func(i, j int) bool {
+		if outcomes[i].Rank == lastRank || outcomes[j].Rank == lastRank {
+			return outcomes[j].Rank == lastRank && outcomes[i].Rank != lastRank
+		}
+		return outcomes[i].Rank < outcomes[j].Rank
+	})
+}
+
+// formatOutcomes は設定ダイアログの初期値にする文字列を返す
+func formatOutcomes(outcomes []*rankOutcome) string {
+	lines := make([]string, 0, len(outcomes))
+	for _, o := range outcomes {
+		rank := outcomeLastName
+		if o.Rank != lastRank {
+			rank = strconv.Itoa(o.Rank)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", rank, o.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// localizeOutcomeRank は結果の順位を表示する文字列を返す
+func localizeOutcomeRank(l *i18n.Localizer, rank int) string {
+	if rank == lastRank {
+		return Localize(l, outcomeLastRankLabel, nil)
+	}
+	return Localize(l, outcomeRankLabel, map[string]interface{}{"Rank": rank})
+}
+
+// getOutcomesDescription はゲームのpostに表示する結果の一覧を返す
+func getOutcomesDescription(l *i18n.Localizer, outcomes []*rankOutcome) string {
+	texts := make([]string, 0, len(outcomes))
+	for _, o := range outcomes {
+		texts = append(texts, fmt.Sprintf("%s: %s", localizeOutcomeRank(l, o.Rank), o.Text))
+	}
+	return Localize(l, jankenGameOutcomesDescription, map[string]interface{}{
+		"Outcomes": strings.Join(texts, ", "),
+	})
+}
+
+/*
+assignOutcomes は参加者ごとに順位に対応する結果を返す．
+最下位の結果は全員が1位の場合には割り当てない．順位なしの参加者には割り当てない．
+1つの順位に最下位と順位の両方の結果がある場合は両方を割り当てる
+Args:
+    outcomes: 順位ごとの結果
+    result: getResultの結果
+Returns:
+    map[string][]string: UserIDごとの結果
+*/
+func assignOutcomes(outcomes []*rankOutcome, result []*participant) map[string][]string {
+	lowest := 0
+	for _, p := range result {
+		if p.Rank > lowest {
+			lowest = p.Rank
+		}
+	}
+
+	assigned := map[string][]string{}
+	for _, p := range result {
+		if p.Rank < 1 {
+			continue
+		}
+		for _, o := range outcomes {
+			if o.Rank == p.Rank || (o.Rank == lastRank && p.Rank == lowest && lowest > 1) {
+				assigned[p.UserID] = append(assigned[p.UserID], o.Text)
+			}
+		}
+	}
+	return assigned
+}
+
+/*
+getOutcomeNotification は結果が割り当てられた参加者へのメンションと結果を返す．
+結果が割り当てられた参加者がいない場合は空文字
+*/
+func (p *Plugin) getOutcomeNotification(game *game, result []*participant) string {
+	assigned := assignOutcomes(game.Outcomes, result)
+	lines := []string{}
+	for _, participant := range result {
+		texts, ok := assigned[participant.UserID]
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", p.getMention(participant.UserID), strings.Join(texts, ", ")))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	l := p.getLocalizer(game.Language)
+	return Localize(l, outcomeNotificationMessage, map[string]interface{}{
+		"ID":       game.getShortID(),
+		"Outcomes": strings.Join(lines, "\n"),
+	})
+}
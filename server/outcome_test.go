@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutcomes(t *testing.T) {
+	for name, test := range map[string]struct {
+		Input       string
+		Expected    []*rankOutcome
+		ShouldError bool
+	}{
+		"empty": {Input: "", Expected: []*rankOutcome{}},
+		"ranks and last": {
+			Input: "last: buys coffee\n\n1st: picks lunch place\n 2 : nothing ",
+			Expected: []*rankOutcome{
+				{Rank: 1, Text: "picks lunch place"},
+				{Rank: 2, Text: "nothing"},
+				{Rank: lastRank, Text: "buys coffee"},
+			},
+		},
+		"later line overrides": {
+			Input:    "1: a\n1: b",
+			Expected: []*rankOutcome{{Rank: 1, Text: "b"}},
+		},
+		"case-insensitive last": {
+			Input:    "LAST: buys coffee",
+			Expected: []*rankOutcome{{Rank: lastRank, Text: "buys coffee"}},
+		},
+		"no rank":       {Input: "buys coffee", ShouldError: true},
+		"rank of zero":  {Input: "0: buys coffee", ShouldError: true},
+		"no text":       {Input: "1:", ShouldError: true},
+		"too long text": {Input: "1: " + strings.Repeat("a", maxOutcomeLength+1), ShouldError: true},
+		"too many":      {Input: "1: a\n2: b\n3: c\n4: d\n5: e\n6: f\n7: g\n8: h\n9: i\n10: j\n11: k", ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			outcomes, err := parseOutcomes(test.Input)
+
+			if test.ShouldError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.Expected, outcomes)
+			// 設定ダイアログの初期値から同じ結果に戻せる
+			parsed, _ := parseOutcomes(formatOutcomes(outcomes))
+			assert.Equal(outcomes, parsed)
+		})
+	}
+}
+
+func TestAssignOutcomes(t *testing.T) {
+	outcomes := []*rankOutcome{
+		{Rank: 1, Text: "picks lunch place"},
+		{Rank: 3, Text: "cleans up"},
+		{Rank: lastRank, Text: "buys coffee"},
+	}
+	for name, test := range map[string]struct {
+		Ranks    map[string]int
+		Expected map[string][]string
+	}{
+		"ranking": {
+			Ranks: map[string]int{"p1": 1, "p2": 2, "p3": 3},
+			Expected: map[string][]string{
+				"p1": {"picks lunch place"},
+				"p3": {"cleans up", "buys coffee"},
+			},
+		},
+		"tied last": {
+			Ranks: map[string]int{"p1": 1, "p2": 2, "p3": 2},
+			Expected: map[string][]string{
+				"p1": {"picks lunch place"},
+				"p2": {"buys coffee"},
+				"p3": {"buys coffee"},
+			},
+		},
+		"all tied": {
+			Ranks: map[string]int{"p1": 1, "p2": 1},
+			Expected: map[string][]string{
+				"p1": {"picks lunch place"},
+				"p2": {"picks lunch place"},
+			},
+		},
+		"unranked": {
+			Ranks: map[string]int{"p1": 1, "p2": 0},
+			Expected: map[string][]string{
+				"p1": {"picks lunch place"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := []*participant{}
+			for _, id := range []string{"p1", "p2", "p3"} {
+				if rank, ok := test.Ranks[id]; ok {
+					result = append(result, &participant{UserID: id, Rank: rank})
+				}
+			}
+
+			assert.Equal(t, test.Expected, assignOutcomes(outcomes, result))
+		})
+	}
+}
@@ -2,9 +2,8 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
+	"path/filepath"
 	"sync"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -25,26 +24,49 @@ type Plugin struct {
 
 	store *Store
 
+	// 結果をダイレクトメッセージで送るボットのUserID
+	botUserID string
+
+	// 締め切りを過ぎたゲームの結果を表示するジョブ
+	deadlineJob *deadlineJob
+
 	bundle *i18n.Bundle
 }
 
 const (
 	// PluginID is a mattermost plugin id
 	PluginID = "com.github.yiwkr.mattermost-plugin-janken"
+
+	botUsername    = "janken"
+	botDisplayName = "Janken"
+	botDescription = "Sends the results of janken games to the participants."
 )
 
-// OnActivate registers the plugin command
+// OnActivate ensures the bot account and starts the deadline job
 func (p *Plugin) OnActivate() error {
+	botUserID, err := p.Helpers.EnsureBot(&model.Bot{
+		Username:    botUsername,
+		DisplayName: botDisplayName,
+		Description: botDescription,
+	}, plugin.ProfileImagePath(filepath.Join("assets", iconFilename)))
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure the bot")
+	}
+	p.botUserID = botUserID
+
 	p.router = p.initAPI()
 	p.store = NewStore(p.API)
-
-	rand.Seed(time.Now().UnixNano())
+	p.deadlineJob = p.startDeadlineJob()
 
 	return nil
 }
 
-// OnDeactivate unregister the plugin command
+// OnDeactivate stops the deadline job and unregister the plugin command
 func (p *Plugin) OnDeactivate() error {
+	if p.deadlineJob != nil {
+		p.deadlineJob.close()
+		p.deadlineJob = nil
+	}
 	if err := p.API.UnregisterCommand("", p.getConfiguration().Trigger); err != nil {
 		return errors.Wrap(err, "failed to deactivate command")
 	}
@@ -20,8 +20,10 @@ const (
 func TestPlugin(t *testing.T) {
 	t.Run("OnActivate", func(t *testing.T) {
 		for name, test := range map[string]struct {
-			SetupPatch  func() *monkey.PatchGuard
-			ShouldError bool
+			SetupPatch    func() *monkey.PatchGuard
+			SetupHelpers  func() *plugintest.Helpers
+			ShouldError   bool
+			ExpectedBotID string
 		}{
 			"successfully": {
 				SetupPatch: func() *monkey.PatchGuard {
@@ -29,7 +31,27 @@ func TestPlugin(t *testing.T) {
 					return monkey.PatchInstanceMethod(reflect.TypeOf(p), "InitBundle",
 						func(*Plugin) (*i18n.Bundle, error) { return nil, nil })
 				},
-				ShouldError: false,
+				SetupHelpers: func() *plugintest.Helpers {
+					helpers := &plugintest.Helpers{}
+					helpers.On("EnsureBot", mock.AnythingOfType("*model.Bot"), mock.Anything).Return("bot_id", nil)
+					return helpers
+				},
+				ShouldError:   false,
+				ExpectedBotID: "bot_id",
+			},
+			"failed because EnsureBot returns an error": {
+				SetupPatch: func() *monkey.PatchGuard {
+					var p *Plugin
+					return monkey.PatchInstanceMethod(reflect.TypeOf(p), "InitBundle",
+						func(*Plugin) (*i18n.Bundle, error) { return nil, nil })
+				},
+				SetupHelpers: func() *plugintest.Helpers {
+					helpers := &plugintest.Helpers{}
+					helpers.On("EnsureBot", mock.AnythingOfType("*model.Bot"), mock.Anything).Return("", errors.New("failed to ensure bot"))
+					return helpers
+				},
+				ShouldError:   true,
+				ExpectedBotID: "",
 			},
 		} {
 			t.Run(name, func(t *testing.T) {
@@ -39,13 +61,18 @@ func TestPlugin(t *testing.T) {
 				defer patch.Unpatch()
 
 				p := &Plugin{}
+				p.SetHelpers(test.SetupHelpers())
 				err := p.OnActivate()
+				if p.deadlineJob != nil {
+					p.deadlineJob.close()
+				}
 
 				if test.ShouldError {
 					assert.NotNil(err)
 				} else {
 					assert.Nil(err)
 				}
+				assert.Equal(test.ExpectedBotID, p.botUserID)
 			})
 		}
 	})
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// resultPublishInPlace はゲームのpostに結果を追加する
+	resultPublishInPlace = "inplace"
+	// resultPublishNewPost はチャンネルに新しいpostとして結果を投稿する
+	resultPublishNewPost = "post"
+	// resultPublishThread はゲームのpostのスレッドに返信として結果を投稿する
+	resultPublishThread = "thread"
+
+	defaultResultPublishMode = resultPublishInPlace
+
+	// resultPublishDefault は設定ダイアログでプラグインの設定を使うことを表す値
+	resultPublishDefault = "default"
+)
+
+// resultPublishModes は結果の表示方法を選択肢の表示順に返す
+var resultPublishModes = []string{resultPublishInPlace, resultPublishNewPost, resultPublishThread}
+
+var (
+	resultPublishModeMessages = map[string]*i18n.Message{
+		resultPublishInPlace: {
+			ID:    "resultPublishInPlace",
+			Other: "Add to the game post",
+		},
+		resultPublishNewPost: {
+			ID:    "resultPublishNewPost",
+			Other: "Post as a new message",
+		},
+		resultPublishThread: {
+			ID:    "resultPublishThread",
+			Other: "Reply in the thread",
+		},
+	}
+	resultPublishDefaultMessage = &i18n.Message{
+		ID:    "resultPublishDefault",
+		Other: "Plugin setting ({{.Mode}})",
+	}
+	resultPublishedNewPostMessage = &i18n.Message{
+		ID:    "ResultPublishedNewPostMessage",
+		Other: "The result is posted as a new message.",
+	}
+	resultPublishedThreadMessage = &i18n.Message{
+		ID:    "ResultPublishedThreadMessage",
+		Other: "The result is posted in the thread.",
+	}
+)
+
+// isValidResultPublishMode は結果の表示方法が存在するかを返す
+func isValidResultPublishMode(mode string) bool {
+	_, ok := resultPublishModeMessages[mode]
+	return ok
+}
+
+// getResultPublishMode はゲームの結果の表示方法を返す．ゲームで指定していない場合はプラグインの設定を使う
+func (p *Plugin) getResultPublishMode(game *game) string {
+	if isValidResultPublishMode(game.ResultPublishMode) {
+		return game.ResultPublishMode
+	}
+	return p.getConfiguration().GetResultPublishMode()
+}
+
+/*
+publishResult は結果の表示方法に従って結果とジャンケンの経過を表示する．
+ゲームのpostのボタンは表示方法によらず取り除く．新しいpostやスレッドに投稿する場合は
+結果の表に含まれる参加者へのメンションで通知が届き，ゲームのpostには投稿先を追記する
+Args:
+    game: 結果を表示するゲーム
+    post: ゲームのpost．呼び出し側で更新する
+    message: 結果のメッセージ
+    attachments: ジャンケンの経過のAttachment．経過がない場合はnil
+Returns:
+    string: 結果を表示したpostのID
+*/
+func (p *Plugin) publishResult(game *game, post *model.Post, message string, attachments []*model.SlackAttachment) string {
+	model.ParseSlackAttachment(post, nil)
+
+	mode := p.getResultPublishMode(game)
+	if mode == resultPublishInPlace {
+		appendMessage(post, message)
+		model.ParseSlackAttachment(post, attachments)
+		return post.Id
+	}
+
+	// ゲームのpostと同じ名前とアイコンで投稿する
+	resultPost := newGamePost(*p.ServerConfig.ServiceSettings.SiteURL, post.UserId, post.ChannelId)
+	resultPost.Message = message
+	published := resultPublishedNewPostMessage
+	if mode == resultPublishThread {
+		resultPost.RootId = post.Id
+		published = resultPublishedThreadMessage
+	}
+	model.ParseSlackAttachment(resultPost, attachments)
+	created, appErr := p.API.CreatePost(resultPost)
+	if appErr != nil {
+		// 投稿できない場合は結果が失われないようにゲームのpostに追加する
+		p.API.LogError("failed to post the result", "id", game.ID, "error", appErr.Error())
+		appendMessage(post, message)
+		model.ParseSlackAttachment(post, attachments)
+		return post.Id
+	}
+
+	l := p.getLocalizer(game.Language)
+	appendMessage(post, Localize(l, published, nil))
+	return created.Id
+}
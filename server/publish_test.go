@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/text/language"
+)
+
+func TestPublishResult(t *testing.T) {
+	attachments := []*model.SlackAttachment{{Title: "replay"}}
+	for name, test := range map[string]struct {
+		PluginMode         string
+		GameMode           string
+		CreatePostError    *model.AppError
+		ExpectedCreated    bool
+		ExpectedRootID     string
+		ExpectedPostSuffix string
+		ExpectedPostID     string
+	}{
+		"in place by default": {
+			ExpectedPostSuffix: "result",
+			ExpectedPostID:     "game_post",
+		},
+		"new post from the plugin setting": {
+			PluginMode:         resultPublishNewPost,
+			ExpectedCreated:    true,
+			ExpectedPostSuffix: "The result is posted as a new message.",
+			ExpectedPostID:     "result_post",
+		},
+		"thread reply from the game": {
+			PluginMode:         resultPublishNewPost,
+			GameMode:           resultPublishThread,
+			ExpectedCreated:    true,
+			ExpectedRootID:     "game_post",
+			ExpectedPostSuffix: "The result is posted in the thread.",
+			ExpectedPostID:     "result_post",
+		},
+		"in place from the game": {
+			PluginMode:         resultPublishThread,
+			GameMode:           resultPublishInPlace,
+			ExpectedPostSuffix: "result",
+			ExpectedPostID:     "game_post",
+		},
+		"in place when the post can't be created": {
+			PluginMode:         resultPublishNewPost,
+			CreatePostError:    model.NewAppError("CreatePost", "id", nil, "error", 500),
+			ExpectedCreated:    true,
+			ExpectedPostSuffix: "result",
+			ExpectedPostID:     "game_post",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var created *model.Post
+			api := &plugintest.API{}
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				created = post
+				return &model.Post{Id: "result_post"}
+			}, test.CreatePostError)
+			api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+			siteURL := dummySiteURL
+			p := &Plugin{
+				configuration: &pluginConfig{ResultPublishMode: test.PluginMode},
+				ServerConfig:  &model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}},
+				bundle:        i18n.NewBundle(language.English),
+			}
+			p.SetAPI(api)
+
+			g := newGame(&gameImpl1{})
+			g.ResultPublishMode = test.GameMode
+			post := newGamePost(dummySiteURL, "creator", "channel")
+			post.Id = "game_post"
+			post.Message = "game"
+			model.ParseSlackAttachment(post, []*model.SlackAttachment{{Title: "buttons"}})
+
+			postID := p.publishResult(g, post, "result", attachments)
+
+			assert.Equal(test.ExpectedPostID, postID)
+			assert.Equal("game\n"+test.ExpectedPostSuffix, post.Message)
+			if !test.ExpectedCreated {
+				assert.Nil(created)
+				assert.Equal(attachments, post.Attachments())
+				return
+			}
+			assert.Equal("result", created.Message)
+			assert.Equal("channel", created.ChannelId)
+			// ゲームのpostと同じ名前とアイコンで投稿する
+			for _, key := range []string{"from_webhook", "override_username", "override_icon_url"} {
+				assert.Equal(post.GetProp(key), created.GetProp(key))
+			}
+			assert.Equal("creator", created.UserId)
+			assert.Equal(commandResponseUsername, created.GetProp("override_username"))
+			assert.Equal(test.ExpectedRootID, created.RootId)
+			assert.Equal(attachments, created.Attachments())
+			if test.CreatePostError != nil {
+				assert.Equal(attachments, post.Attachments())
+			} else {
+				// ゲームのpostのボタンは取り除く
+				assert.Empty(post.Attachments())
+			}
+		})
+	}
+}
+
+func TestGetResultPublishMode(t *testing.T) {
+	p := &Plugin{configuration: &pluginConfig{ResultPublishMode: resultPublishThread}}
+	g := newGame(&gameImpl1{})
+
+	assert.Equal(t, resultPublishThread, p.getResultPublishMode(g))
+	g.ResultPublishMode = resultPublishNewPost
+	assert.Equal(t, resultPublishNewPost, p.getResultPublishMode(g))
+	g.ResultPublishMode = "email"
+	assert.Equal(t, resultPublishThread, p.getResultPublishMode(g))
+}
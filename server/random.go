@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+)
+
+const (
+	// randAlgorithmSHA256 はシード・UserID・何手目かのハッシュから手を決める
+	randAlgorithmSHA256 = "sha256"
+	// randAlgorithmMathRand はシードで初期化したmath/randの乱数列から順に手を決める
+	randAlgorithmMathRand = "math/rand"
+
+	defaultRandAlgorithm = randAlgorithmSHA256
+)
+
+// gameRand はゲームで使う乱数
+type gameRand interface {
+	// hand はuserIDの参加者がround手目に出す手を返す
+	hand(userID string, round int, hs *handSet) string
+	// shuffle はn個の要素をswapで並べ替える
+	shuffle(n int, swap func(i, j int))
+}
+
+var newGameRandFuncMapping = map[string](func(seed string) gameRand){
+	randAlgorithmSHA256:   newSHA256Rand,
+	randAlgorithmMathRand: newMathRand,
+}
+
+/*
+newGameRand は指定したアルゴリズムとシードの乱数を返す．
+アルゴリズムが存在しない場合はデフォルトのアルゴリズムを使う
+*/
+func newGameRand(algorithm, seed string) gameRand {
+	f := newGameRandFuncMapping[algorithm]
+	if f == nil {
+		f = newGameRandFuncMapping[defaultRandAlgorithm]
+	}
+	return f(seed)
+}
+
+// seedSource はシードから作ったmath/randのSourceを返す
+func seedSource(seed string) rand.Source {
+	sum := sha256.Sum256([]byte(seed))
+	return rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8])))
+}
+
+/*
+sha256Rand は手を決める順番によらず，シード・UserID・何手目かだけで手が決まる乱数．
+並べ替えにはシードで初期化したmath/randを使う
+*/
+type sha256Rand struct {
+	seed   string
+	stream *rand.Rand
+}
+
+func newSHA256Rand(seed string) gameRand {
+	return &sha256Rand{
+		seed:   seed,
+		stream: rand.New(seedSource(seed)),
+	}
+}
+
+func (r *sha256Rand) hand(userID string, round int, hs *handSet) string {
+	sum := sha256.Sum256([]byte(r.seed + ":" + userID + ":" + strconv.Itoa(round)))
+	n := binary.BigEndian.Uint64(sum[:8])
+	return hs.Hands[n%uint64(len(hs.Hands))]
+}
+
+func (r *sha256Rand) shuffle(n int, swap func(i, j int)) {
+	r.stream.Shuffle(n, swap)
+}
+
+// mathRand はシードで初期化したmath/randの乱数列を順に使う乱数
+type mathRand struct {
+	stream *rand.Rand
+}
+
+func newMathRand(seed string) gameRand {
+	return &mathRand{stream: rand.New(seedSource(seed))}
+}
+
+func (r *mathRand) hand(userID string, round int, hs *handSet) string {
+	return hs.Hands[r.stream.Intn(len(hs.Hands))]
+}
+
+func (r *mathRand) shuffle(n int, swap func(i, j int)) {
+	r.stream.Shuffle(n, swap)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameRand(t *testing.T) {
+	t.Run("newGameRand", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.IsType(&sha256Rand{}, newGameRand(randAlgorithmSHA256, "seed"))
+		assert.IsType(&mathRand{}, newGameRand(randAlgorithmMathRand, "seed"))
+		assert.IsType(&sha256Rand{}, newGameRand("unknown", "seed"))
+	})
+
+	for _, algorithm := range []string{randAlgorithmSHA256, randAlgorithmMathRand} {
+		t.Run(algorithm, func(t *testing.T) {
+			assert := assert.New(t)
+			hs := getHandSet("rpsls")
+			r1 := newGameRand(algorithm, "seed")
+			r2 := newGameRand(algorithm, "seed")
+
+			// 同じシードからは同じ手と並べ替えが得られる
+			for round := 0; round < maxHands; round++ {
+				h := r1.hand("p1", round, hs)
+				assert.True(hs.contains(h))
+				assert.Equal(h, r2.hand("p1", round, hs))
+			}
+			s1 := []int{0, 1, 2, 3, 4, 5, 6, 7}
+			s2 := []int{0, 1, 2, 3, 4, 5, 6, 7}
+			r1.shuffle(len(s1), func(i, j int) { s1[i], s1[j] = s1[j], s1[i] })
+			r2.shuffle(len(s2), func(i, j int) { s2[i], s2[j] = s2[j], s2[i] })
+			assert.Equal(s1, s2)
+		})
+	}
+
+	t.Run("sha256 does not depend on the order", func(t *testing.T) {
+		assert := assert.New(t)
+		hs := getHandSet("rps")
+		r1 := newSHA256Rand("seed")
+		r2 := newSHA256Rand("seed")
+
+		h1 := r1.hand("p1", 1, hs)
+		r2.hand("p2", 0, hs)
+		assert.Equal(h1, r2.hand("p1", 1, hs))
+	})
+}
+
+func TestFillHands(t *testing.T) {
+	assert := assert.New(t)
+	g := newGame(&gameImpl1{})
+	g.setSeed("seed")
+	g.UpdateHands("p1", []string{"rock"})
+
+	g.fillHands()
+
+	p := g.GetParticipant("p1")
+	r := newGameRand(g.RandAlgorithm, "seed")
+	assert.Equal("rock", p.Hands[0])
+	for round := 1; round < g.MaxRounds; round++ {
+		assert.Equal(r.hand("p1", round, g.getHandSet()), p.Hands[round])
+	}
+	assert.Equal("", p.Hands[g.MaxRounds])
+
+	// シードを変えると乱数も作り直す
+	g.setSeed("other")
+	assert.Equal(newGameRand(g.RandAlgorithm, "other"), g.rand())
+}
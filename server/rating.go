@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// subcommandRating は参加者のレーティングを表示するサブコマンド
+	subcommandRating = "rating"
+
+	// eloInitialRating はEloレーティングの初期値
+	eloInitialRating = 1500.0
+	// eloK はEloレーティングの1ゲームあたりの最大変動幅
+	eloK = 32.0
+
+	// maxRatingHistory は保存するレーティングの履歴の数
+	maxRatingHistory = 100
+	// ratingHistorySize はratingサブコマンドで表示するレーティングの履歴の数
+	ratingHistorySize = 10
+)
+
+var (
+	ratingTitle = &i18n.Message{
+		ID:    "ratingTitle",
+		Other: "Janken rating of @{{.Username}}: {{.Rating}}",
+	}
+	ratingSummary = &i18n.Message{
+		ID:    "ratingSummary",
+		Other: "Rated games: {{.Games}}, Peak: {{.Peak}}",
+	}
+	ratingHistoryHeader = &i18n.Message{
+		ID: "ratingHistoryHeader",
+		Other: `| Game | Age | Rank | Change | Rating |
+| --- | --- | --- | --- | --- |`,
+	}
+	ratingEmptyMessage = &i18n.Message{
+		ID:    "ratingEmptyMessage",
+		Other: "@{{.Username}} has no rating yet. Finish a ranked janken game to get one.",
+	}
+	resultTableRatingLabel = &i18n.Message{
+		ID:    "resultTableRatingLabel",
+		Other: "Rating",
+	}
+)
+
+/*
+userRating は参加者のEloレーティング．
+順位がついた参加者が2人以上いるゲームの結果を表示するたびに更新する
+*/
+type userRating struct {
+	UserID string  `json:"user_id"`
+	Rating float64 `json:"rating"`
+	// レーティングを更新したゲームの数
+	Games int `json:"games"`
+	// 最高レーティング
+	Peak float64 `json:"peak"`
+	// 新しい順に並べたレーティングの変化
+	History []*ratingChange `json:"history"`
+}
+
+// ratingChange は1ゲームでのレーティングの変化
+type ratingChange struct {
+	GameID     string  `json:"game_id"`
+	FinishedAt int64   `json:"finished_at"`
+	Rank       int     `json:"rank"`
+	Before     float64 `json:"before"`
+	After      float64 `json:"after"`
+}
+
+func newUserRating(userID string) *userRating {
+	return &userRating{
+		UserID:  userID,
+		Rating:  eloInitialRating,
+		Peak:    eloInitialRating,
+		History: []*ratingChange{},
+	}
+}
+
+/*
+eloDeltas は順位がついた参加者同士の全ての組み合わせを1対1の対戦とみなしたEloレーティングの変化を返す．
+1ゲームで変化する幅が参加人数によらないように，1対戦あたりの変動幅はeloK/(人数-1)にする
+Args:
+    ranked: 順位がついた参加者
+    ratings: ゲーム前の参加者のレーティング
+Returns:
+    map[string]float64: UserIDごとのレーティングの変化
+*/
+func eloDeltas(ranked []*recordParticipant, ratings map[string]float64) map[string]float64 {
+	deltas := map[string]float64{}
+	if len(ranked) < 2 {
+		return deltas
+	}
+	for _, p1 := range ranked {
+		deltas[p1.UserID] = eloDelta(p1, ratings[p1.UserID], ranked, ratings)
+	}
+	return deltas
+}
+
+/*
+eloDelta は順位がついた参加者1人のEloレーティングの変化を返す
+Args:
+    p1: レーティングを変化させる参加者
+    rating: p1のゲーム前のレーティング
+    ranked: 順位がついた参加者
+    ratings: ゲーム前の参加者のレーティング．p1のレーティングは使わない
+*/
+func eloDelta(p1 *recordParticipant, rating float64, ranked []*recordParticipant, ratings map[string]float64) float64 {
+	if len(ranked) < 2 {
+		return 0
+	}
+	k := eloK / float64(len(ranked)-1)
+	delta := 0.0
+	for _, p2 := range ranked {
+		if p1 == p2 {
+			continue
+		}
+		delta += k * (eloActualScore(p1.Rank, p2.Rank) - eloExpectedScore(rating, ratings[p2.UserID]))
+	}
+	return delta
+}
+
+// eloExpectedScore はレーティングr1の参加者がレーティングr2の参加者に勝つ期待値を返す
+func eloExpectedScore(r1, r2 float64) float64 {
+	return 1 / (1 + math.Pow(10, (r2-r1)/400))
+}
+
+// eloActualScore は順位rank1の参加者の順位rank2の参加者に対する結果を返す．勝ちは1，引き分けは0.5，負けは0
+func eloActualScore(rank1, rank2 int) float64 {
+	switch {
+	case rank1 < rank2:
+		return 1
+	case rank1 == rank2:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// apply はレーティングを変化させて履歴に追加する
+func (r *userRating) apply(record *gameRecord, rank int, delta float64) *ratingChange {
+	change := &ratingChange{
+		GameID:     record.ID,
+		FinishedAt: record.FinishedAt,
+		Rank:       rank,
+		Before:     r.Rating,
+		After:      r.Rating + delta,
+	}
+	r.Rating = change.After
+	r.Games++
+	if r.Rating > r.Peak {
+		r.Peak = r.Rating
+	}
+	r.History = append([]*ratingChange{change}, r.History...)
+	if len(r.History) > maxRatingHistory {
+		r.History = r.History[:maxRatingHistory]
+	}
+	return change
+}
+
+/*
+updateRatings はゲームの順位がついた参加者のレーティングを更新する．
+参加者それぞれの変化は，更新するときに読んだ最新のレーティングと，ゲーム前に読んだ他の参加者のレーティングから計算する．
+他のゲームが同時にレーティングを更新しても，その変化を上書きせず，BeforeとAfterの差が変化と一致する
+Returns:
+    map[string]*ratingChange: UserIDごとのレーティングの変化．順位がついた参加者が2人未満の場合は空
+*/
+func (p *Plugin) updateRatings(record *gameRecord) map[string]*ratingChange {
+	ranked := []*recordParticipant{}
+	for _, rp := range record.Participants {
+		if rp.Rank > 0 {
+			ranked = append(ranked, rp)
+		}
+	}
+	changes := map[string]*ratingChange{}
+	if len(ranked) < 2 {
+		return changes
+	}
+
+	ratings := map[string]float64{}
+	for _, rp := range ranked {
+		r, err := p.store.ratingStore.Get(rp.UserID)
+		if err != nil {
+			p.API.LogError("failed to get the rating", "user_id", rp.UserID, "error", err.Error())
+			return changes
+		}
+		ratings[rp.UserID] = r.Rating
+	}
+
+	for _, rp := range ranked {
+		rp := rp
+		var change *ratingChange
+		_, err := p.store.ratingStore.Update(rp.UserID, func(r *userRating) {
+			change = r.apply(record, rp.Rank, eloDelta(rp, r.Rating, ranked, ratings))
+		})
+		if err != nil {
+			p.API.LogError("failed to update the rating", "user_id", rp.UserID, "error", err.Error())
+			continue
+		}
+		changes[rp.UserID] = change
+	}
+	return changes
+}
+
+// formatRatingChange は結果の表に表示するレーティングと変化を返す
+func formatRatingChange(change *ratingChange) string {
+	if change == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f (%+.0f)", change.After, change.After-change.Before)
+}
+
+/*
+executeRatingCommand は参加者のレーティングと最近の変化を表示する．
+引数で@usernameを指定しない場合はコマンドを実行したユーザーのレーティングを表示する
+*/
+func (p *Plugin) executeRatingCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	user, response := p.getCommandTargetUser(siteURL, args)
+	if response != nil {
+		return response
+	}
+
+	rating, err := p.store.ratingStore.Get(user.Id)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get the rating.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+	if rating.Games == 0 {
+		message := Localize(l, ratingEmptyMessage, map[string]interface{}{
+			"Username": user.Username,
+		})
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	now := model.GetMillis()
+	lines := []string{
+		fmt.Sprintf("#### %s", Localize(l, ratingTitle, map[string]interface{}{
+			"Username": user.Username,
+			"Rating":   fmt.Sprintf("%.0f", rating.Rating),
+		})),
+		Localize(l, ratingSummary, map[string]interface{}{
+			"Games": rating.Games,
+			"Peak":  fmt.Sprintf("%.0f", rating.Peak),
+		}),
+		"",
+		Localize(l, ratingHistoryHeader, nil),
+	}
+	for i, change := range rating.History {
+		if i >= ratingHistorySize {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %s | %d | %+.0f | %.0f |",
+			change.GameID[:7],
+			formatAge(now-change.FinishedAt),
+			change.Rank,
+			change.After-change.Before,
+			change.After,
+		))
+	}
+	message := strings.Join(lines, "\n")
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
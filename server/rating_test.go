@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRating(t *testing.T) {
+	t.Run("eloDeltas", func(t *testing.T) {
+		assert := assert.New(t)
+		ranked := []*recordParticipant{
+			{UserID: "p1", Rank: 1},
+			{UserID: "p2", Rank: 2},
+		}
+
+		// 同じレーティング同士では勝者がeloK/2増えて敗者が同じだけ減る
+		deltas := eloDeltas(ranked, map[string]float64{"p1": 1500, "p2": 1500})
+		assert.InDelta(eloK/2, deltas["p1"], 0.001)
+		assert.InDelta(-eloK/2, deltas["p2"], 0.001)
+
+		// レーティングが高い方が勝った場合の変化は小さい
+		deltas = eloDeltas(ranked, map[string]float64{"p1": 1700, "p2": 1500})
+		assert.True(deltas["p1"] < eloK/2)
+		assert.InDelta(0, deltas["p1"]+deltas["p2"], 0.001)
+
+		// 引き分けは高い方が下がる
+		ranked[1].Rank = 1
+		deltas = eloDeltas(ranked, map[string]float64{"p1": 1700, "p2": 1500})
+		assert.True(deltas["p1"] < 0)
+
+		assert.Empty(eloDeltas(ranked[:1], map[string]float64{"p1": 1500}))
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		assert := assert.New(t)
+		r := newUserRating("p1")
+
+		change := r.apply(&gameRecord{ID: "g1", FinishedAt: 10}, 1, 16)
+		assert.Equal(&ratingChange{GameID: "g1", FinishedAt: 10, Rank: 1, Before: 1500, After: 1516}, change)
+		r.apply(&gameRecord{ID: "g2", FinishedAt: 20}, 2, -20)
+
+		assert.Equal(1496.0, r.Rating)
+		assert.Equal(1516.0, r.Peak)
+		assert.Equal(2, r.Games)
+		assert.Equal("g2", r.History[0].GameID)
+
+		for i := 0; i < maxRatingHistory; i++ {
+			r.apply(&gameRecord{ID: "g"}, 1, 0)
+		}
+		assert.Len(r.History, maxRatingHistory)
+	})
+
+	t.Run("formatRatingChange", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("1516 (+16)", formatRatingChange(&ratingChange{Before: 1500, After: 1516}))
+		assert.Equal("1484 (-16)", formatRatingChange(&ratingChange{Before: 1500, After: 1484}))
+		assert.Equal("-", formatRatingChange(nil))
+	})
+
+	t.Run("updateRatings", func(t *testing.T) {
+		assert := assert.New(t)
+		api, _ := newAtomicKVAPI()
+		p := &Plugin{}
+		p.API = api
+		p.store = NewStore(api)
+		record := &gameRecord{
+			ID: "g1",
+			Participants: []*recordParticipant{
+				{UserID: "p1", Rank: 1},
+				{UserID: "p2", Rank: 2},
+				{UserID: "p3", Rank: 0},
+			},
+		}
+
+		changes := p.updateRatings(record)
+
+		assert.Len(changes, 2)
+		assert.InDelta(1500+eloK/2, changes["p1"].After, 0.001)
+		r, _ := p.store.ratingStore.Get("p2")
+		assert.InDelta(1500-eloK/2, r.Rating, 0.001)
+		assert.Equal(1, r.Games)
+		r, _ = p.store.ratingStore.Get("p3")
+		assert.Equal(0, r.Games)
+
+		// 順位がついた参加者が1人だけの場合は更新しない
+		record.Participants[1].Rank = 0
+		assert.Empty(p.updateRatings(record))
+	})
+
+	t.Run("updateRatings with a concurrent update", func(t *testing.T) {
+		assert := assert.New(t)
+		api, _ := newAtomicKVAPI()
+		p := &Plugin{}
+		p.API = api
+		p.store = NewStore(api)
+		store := p.store.ratingStore
+		// p1のレーティングを読んだ後に他のゲームがp1のレーティングを更新する
+		p.store.ratingStore = &racingRatingStore{
+			ratingStoreInterface: store,
+			race: func() {
+				_, err := store.Update("p1", func(r *userRating) { r.Rating = 1700 })
+				assert.Nil(err)
+			},
+		}
+		record := &gameRecord{
+			ID: "g1",
+			Participants: []*recordParticipant{
+				{UserID: "p1", Rank: 1},
+				{UserID: "p2", Rank: 2},
+			},
+		}
+
+		changes := p.updateRatings(record)
+
+		expected := eloDeltas(record.Participants, map[string]float64{"p1": 1700, "p2": 1500})
+		assert.InDelta(1700, changes["p1"].Before, 0.001)
+		assert.InDelta(1700+expected["p1"], changes["p1"].After, 0.001)
+		r, _ := store.Get("p1")
+		assert.InDelta(changes["p1"].After, r.Rating, 0.001)
+		assert.InDelta(1500-eloK/2, changes["p2"].After, 0.001)
+	})
+}
+
+// racingRatingStore はレーティングを更新する前に一度だけraceを実行する
+type racingRatingStore struct {
+	ratingStoreInterface
+	race func()
+}
+
+func (s *racingRatingStore) Update(userID string, f func(*userRating)) (*userRating, error) {
+	if s.race != nil {
+		s.race()
+		s.race = nil
+	}
+	return s.ratingStoreInterface.Update(userID, f)
+}
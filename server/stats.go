@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// subcommandStats は参加者の成績を表示するサブコマンド
+	subcommandStats = "stats"
+)
+
+var (
+	statsTitle = &i18n.Message{
+		ID:    "statsTitle",
+		Other: "Janken stats of @{{.Username}}",
+	}
+	statsSummaryHeader = &i18n.Message{
+		ID: "statsSummaryHeader",
+		Other: `| Games | Average rank | Win rate | Most played hand | Current streak | Longest winning streak | Longest losing streak |
+| --- | --- | --- | --- | --- | --- | --- |`,
+	}
+	statsWinningStreak = &i18n.Message{
+		ID:    "statsWinningStreak",
+		Other: "{{.Count}} wins",
+	}
+	statsLosingStreak = &i18n.Message{
+		ID:    "statsLosingStreak",
+		Other: "{{.Count}} losses",
+	}
+	statsRoundHandsTitle = &i18n.Message{
+		ID:    "statsRoundHandsTitle",
+		Other: "Win rate of each hand by round",
+	}
+	statsRoundLabel = &i18n.Message{
+		ID:    "statsRoundLabel",
+		Other: "Round",
+	}
+	statsEmptyMessage = &i18n.Message{
+		ID:    "statsEmptyMessage",
+		Other: "@{{.Username}} has not played any janken games yet.",
+	}
+)
+
+/*
+userStats は参加者の成績の集計．
+ゲームの結果を表示するたびに更新する
+*/
+type userStats struct {
+	UserID string `json:"user_id"`
+	// 参加したゲームの数
+	Games int `json:"games"`
+	// 順位がついたゲームの数と順位の合計
+	RankedGames int `json:"ranked_games"`
+	RankSum     int `json:"rank_sum"`
+	// 1位になったゲームの数
+	Wins int `json:"wins"`
+	// 最下位になったゲームの数
+	Losses int `json:"losses"`
+	// 現在の連勝数(正)または連敗数(負)
+	Streak int `json:"streak"`
+	// 最長の連勝数と連敗数
+	LongestWinningStreak int `json:"longest_winning_streak"`
+	LongestLosingStreak  int `json:"longest_losing_streak"`
+	// 何回目のジャンケンで出した手の成績．Rounds[何回目][手]
+	Rounds []map[string]*handStats `json:"rounds"`
+	// 最後に参加したゲームの結果を表示した日時
+	LastPlayedAt int64 `json:"last_played_at"`
+}
+
+// handStats は手を出した回数と勝った回数
+type handStats struct {
+	Played int `json:"played"`
+	Won    int `json:"won"`
+}
+
+// playedRound は参加者が1回のジャンケンで出した手と勝敗
+type playedRound struct {
+	Round int
+	Hand  string
+	Won   bool
+}
+
+func newUserStats(userID string) *userStats {
+	return &userStats{
+		UserID: userID,
+		Rounds: []map[string]*handStats{},
+	}
+}
+
+/*
+playedRounds は参加者が出した手と勝敗をジャンケンの順に返す．
+チーム戦ではチームのジャンケンで参加者自身が出した手とチームの勝敗を返す
+*/
+func (r *gameRecord) playedRounds(userID string) []*playedRound {
+	var rp *recordParticipant
+	for _, p := range r.Participants {
+		if p.UserID == userID {
+			rp = p
+		}
+	}
+	if rp == nil {
+		return nil
+	}
+	member := rp.UserID
+	if r.GameType == "gameImplTeam" && rp.Team != "" {
+		member = rp.Team
+	}
+
+	rounds := []*playedRound{}
+	for _, event := range r.Rounds {
+		for i, m := range event.Members {
+			if m != member {
+				continue
+			}
+			hand := event.Hands[i]
+			if member != rp.UserID {
+				if event.Round >= len(rp.Hands) || rp.Hands[event.Round] == "" {
+					continue
+				}
+				hand = rp.Hands[event.Round]
+			}
+			won := false
+			for _, w := range event.Winners {
+				won = won || w == member
+			}
+			rounds = append(rounds, &playedRound{Round: event.Round, Hand: hand, Won: won})
+		}
+	}
+	return rounds
+}
+
+// add はゲームの結果を成績に加える
+func (s *userStats) add(record *gameRecord) {
+	var rp *recordParticipant
+	for _, p := range record.Participants {
+		if p.UserID == s.UserID {
+			rp = p
+		}
+	}
+	if rp == nil {
+		return
+	}
+
+	s.Games++
+	if rp.Rank > 0 {
+		s.RankedGames++
+		s.RankSum += rp.Rank
+	}
+
+	won := rp.Rank == 1
+	lost := false
+	for _, id := range record.losers() {
+		lost = lost || id == s.UserID
+	}
+	switch {
+	case won:
+		s.Wins++
+		if s.Streak < 0 {
+			s.Streak = 0
+		}
+		s.Streak++
+	case lost:
+		s.Losses++
+		if s.Streak > 0 {
+			s.Streak = 0
+		}
+		s.Streak--
+	default:
+		s.Streak = 0
+	}
+	if s.Streak > s.LongestWinningStreak {
+		s.LongestWinningStreak = s.Streak
+	}
+	if -s.Streak > s.LongestLosingStreak {
+		s.LongestLosingStreak = -s.Streak
+	}
+
+	for _, pr := range record.playedRounds(s.UserID) {
+		for len(s.Rounds) <= pr.Round {
+			s.Rounds = append(s.Rounds, map[string]*handStats{})
+		}
+		hs := s.Rounds[pr.Round][pr.Hand]
+		if hs == nil {
+			hs = &handStats{}
+			s.Rounds[pr.Round][pr.Hand] = hs
+		}
+		hs.Played++
+		if pr.Won {
+			hs.Won++
+		}
+	}
+
+	if record.FinishedAt > s.LastPlayedAt {
+		s.LastPlayedAt = record.FinishedAt
+	}
+}
+
+// averageRank は順位がついたゲームの平均順位を返す．順位がついたゲームがない場合は0
+func (s *userStats) averageRank() float64 {
+	if s.RankedGames == 0 {
+		return 0
+	}
+	return float64(s.RankSum) / float64(s.RankedGames)
+}
+
+// winRate は1位になったゲームの割合を返す
+func (s *userStats) winRate() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Games)
+}
+
+// handTotals は手ごとに何回目かに関係なく合計した成績を返す
+func (s *userStats) handTotals() map[string]*handStats {
+	totals := map[string]*handStats{}
+	for _, round := range s.Rounds {
+		for h, hs := range round {
+			if totals[h] == nil {
+				totals[h] = &handStats{}
+			}
+			totals[h].Played += hs.Played
+			totals[h].Won += hs.Won
+		}
+	}
+	return totals
+}
+
+/*
+mostPlayedHand は最も多く出した手を返す．
+同じ回数の手がある場合は手の表示順で先の手を返す．手を出していない場合は空文字
+*/
+func (s *userStats) mostPlayedHand() string {
+	totals := s.handTotals()
+	most := ""
+	for _, h := range getHandSet("rpsls").Hands {
+		if totals[h] != nil && (most == "" || totals[h].Played > totals[most].Played) {
+			most = h
+		}
+	}
+	return most
+}
+
+// updateUserStats はゲームの参加者全員の成績に結果を加える
+func (p *Plugin) updateUserStats(record *gameRecord) {
+	for _, rp := range record.Participants {
+		_, err := p.store.statsStore.Update(rp.UserID, func(s *userStats) {
+			s.add(record)
+		})
+		if err != nil {
+			p.API.LogError("failed to update the stats", "user_id", rp.UserID, "error", err.Error())
+		}
+	}
+}
+
+/*
+executeStatsCommand は参加者の成績を表示する．
+引数で@usernameを指定しない場合はコマンドを実行したユーザーの成績を表示する
+*/
+func (p *Plugin) executeStatsCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+
+	user, response := p.getCommandTargetUser(siteURL, args)
+	if response != nil {
+		return response
+	}
+
+	stats, err := p.store.statsStore.Get(user.Id)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get the stats.: %s", err.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+	if stats.Games == 0 {
+		message := Localize(l, statsEmptyMessage, map[string]interface{}{
+			"Username": user.Username,
+		})
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	message := p.getStatsMessage(l, user.Username, stats)
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+// getStatsMessage は成績の表を返す
+func (p *Plugin) getStatsMessage(l *i18n.Localizer, username string, stats *userStats) string {
+	hs := getHandSet("rpsls")
+
+	averageRank := "-"
+	if stats.RankedGames > 0 {
+		averageRank = fmt.Sprintf("%.2f", stats.averageRank())
+	}
+	mostPlayedHand := "-"
+	if h := stats.mostPlayedHand(); h != "" {
+		mostPlayedHand = hs.icon(h)
+	}
+	streak := "-"
+	if stats.Streak > 0 {
+		streak = Localize(l, statsWinningStreak, map[string]interface{}{"Count": stats.Streak})
+	} else if stats.Streak < 0 {
+		streak = Localize(l, statsLosingStreak, map[string]interface{}{"Count": -stats.Streak})
+	}
+
+	lines := []string{
+		fmt.Sprintf("#### %s", Localize(l, statsTitle, map[string]interface{}{"Username": username})),
+		Localize(l, statsSummaryHeader, nil),
+		fmt.Sprintf("| %d | %s | %.0f%% | %s | %s | %d | %d |",
+			stats.Games,
+			averageRank,
+			stats.winRate()*100,
+			mostPlayedHand,
+			streak,
+			stats.LongestWinningStreak,
+			stats.LongestLosingStreak,
+		),
+	}
+
+	// 出したことがある手だけを列にする
+	totals := stats.handTotals()
+	hands := []string{}
+	for _, h := range hs.Hands {
+		if totals[h] != nil {
+			hands = append(hands, h)
+		}
+	}
+	if len(hands) == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	header := "| " + Localize(l, statsRoundLabel, nil) + " |"
+	separator := "| --- |"
+	for _, h := range hands {
+		header += " " + hs.icon(h) + " |"
+		separator += " --- |"
+	}
+	lines = append(lines, "", fmt.Sprintf("##### %s", Localize(l, statsRoundHandsTitle, nil)), header, separator)
+	for i, round := range stats.Rounds {
+		if len(round) == 0 {
+			continue
+		}
+		line := fmt.Sprintf("| %d |", i+1)
+		for _, h := range hands {
+			cell := "-"
+			if r := round[h]; r != nil && r.Played > 0 {
+				cell = fmt.Sprintf("%.0f%% (%d/%d)", float64(r.Won)/float64(r.Played)*100, r.Won, r.Played)
+			}
+			line += " " + cell + " |"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStats(t *testing.T) {
+	newRecord := func(ranks map[string]int) *gameRecord {
+		r := &gameRecord{GameType: "gameImpl1", FinishedAt: 1}
+		for _, id := range []string{"p1", "p2", "p3"} {
+			if rank, ok := ranks[id]; ok {
+				r.Participants = append(r.Participants, &recordParticipant{UserID: id, Rank: rank})
+			}
+		}
+		return r
+	}
+
+	t.Run("playedRounds", func(t *testing.T) {
+		assert := assert.New(t)
+		g := newGame(&gameImpl1{})
+		g.UpdateHands("p1", []string{"rock", "rock"})
+		g.UpdateHands("p2", []string{"rock", "scissors"})
+		g.UpdateHands("p3", []string{"paper"})
+		r := newGameRecord(g, g.getResult())
+
+		assert.Equal([]*playedRound{
+			{Round: 0, Hand: "rock", Won: false},
+			{Round: 1, Hand: "rock", Won: true},
+		}, r.playedRounds("p1"))
+		assert.Equal([]*playedRound{{Round: 0, Hand: "paper", Won: true}}, r.playedRounds("p3"))
+		assert.Nil(r.playedRounds("unknown"))
+	})
+
+	t.Run("playedRounds of team janken", func(t *testing.T) {
+		assert := assert.New(t)
+		g := newGame(&gameImplTeam{})
+		g.UpdateHands("p1", []string{"rock"})
+		g.UpdateTeam("p1", "red")
+		g.UpdateHands("p2", []string{"paper"})
+		g.UpdateTeam("p2", "red")
+		g.UpdateHands("p3", []string{"rock"})
+		g.UpdateTeam("p3", "red")
+		g.UpdateHands("p4", []string{"scissors"})
+		r := newGameRecord(g, g.getResult())
+
+		// チームの手はグーなのでチームの勝ちになり，パーを出したp2の成績にはパーで勝ったと記録する
+		assert.Equal([]*playedRound{{Round: 0, Hand: "paper", Won: true}}, r.playedRounds("p2"))
+		assert.Equal([]*playedRound{{Round: 0, Hand: "scissors", Won: false}}, r.playedRounds("p4"))
+	})
+
+	t.Run("add", func(t *testing.T) {
+		assert := assert.New(t)
+		s := newUserStats("p1")
+
+		s.add(newRecord(map[string]int{"p1": 1, "p2": 2}))
+		s.add(newRecord(map[string]int{"p1": 1, "p2": 2}))
+		assert.Equal(2, s.Streak)
+		assert.Equal(2, s.LongestWinningStreak)
+
+		s.add(newRecord(map[string]int{"p1": 3, "p2": 1, "p3": 2}))
+		s.add(newRecord(map[string]int{"p1": 2, "p2": 1}))
+		assert.Equal(-2, s.Streak)
+		assert.Equal(2, s.LongestLosingStreak)
+
+		// 1位でも最下位でもない場合は連勝も連敗も途切れる
+		s.add(newRecord(map[string]int{"p1": 2, "p2": 1, "p3": 3}))
+		assert.Equal(0, s.Streak)
+
+		// 順位なしのゲームは平均順位に含めない
+		s.add(newRecord(map[string]int{"p1": 0, "p2": 1}))
+		// 参加していないゲームは数えない
+		s.add(newRecord(map[string]int{"p2": 1, "p3": 2}))
+
+		assert.Equal(6, s.Games)
+		assert.Equal(5, s.RankedGames)
+		assert.Equal(2, s.Wins)
+		assert.Equal(2, s.Losses)
+		assert.InDelta(1.8, s.averageRank(), 0.001)
+		assert.InDelta(2.0/6.0, s.winRate(), 0.001)
+		assert.Equal(2, s.LongestWinningStreak)
+		assert.Equal(2, s.LongestLosingStreak)
+		assert.Equal(int64(1), s.LastPlayedAt)
+	})
+
+	t.Run("add hands", func(t *testing.T) {
+		assert := assert.New(t)
+		s := newUserStats("p1")
+		r := newRecord(map[string]int{"p1": 1, "p2": 2})
+		r.Rounds = []*roundEvent{
+			{Round: 0, Members: []string{"p1", "p2"}, Hands: []string{"rock", "rock"}, Draw: true},
+			{Round: 1, Members: []string{"p1", "p2"}, Hands: []string{"paper", "rock"}, Winners: []string{"p1"}, Losers: []string{"p2"}},
+		}
+
+		s.add(r)
+		s.add(r)
+
+		assert.Equal([]map[string]*handStats{
+			{"rock": {Played: 2, Won: 0}},
+			{"paper": {Played: 2, Won: 2}},
+		}, s.Rounds)
+		assert.Equal(map[string]*handStats{
+			"rock":  {Played: 2, Won: 0},
+			"paper": {Played: 2, Won: 2},
+		}, s.handTotals())
+		// 同じ回数の場合は表示順で先の手
+		assert.Equal("rock", s.mostPlayedHand())
+		assert.Equal("", newUserStats("p2").mostPlayedHand())
+	})
+}
+
+func TestStatsStore(t *testing.T) {
+	assert := assert.New(t)
+	api, _ := newAtomicKVAPI()
+	s := statsStore{API: api}
+
+	stats, err := s.Get("p1")
+	assert.Nil(err)
+	assert.Equal(newUserStats("p1"), stats)
+
+	for i := 0; i < 2; i++ {
+		_, err = s.Update("p1", func(stats *userStats) {
+			stats.Games++
+		})
+		assert.Nil(err)
+	}
+
+	stats, err = s.Get("p1")
+	assert.Nil(err)
+	assert.Equal(2, stats.Games)
+}
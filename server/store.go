@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
@@ -13,12 +16,61 @@ const (
 
 	// keyPrefix is store key prefix
 	keyPrefix string = "janken_"
+
+	// channelIndexKeyPrefix is store key prefix of the index of open games in a channel
+	channelIndexKeyPrefix string = "janken_channel_"
+
+	// deadlineIndexKey is store key of the index of open games that have a deadline
+	deadlineIndexKey string = "janken_deadlines"
+
+	// liveIndexKey is store key of the index of live games that are being played
+	liveIndexKey string = "janken_live"
+
+	// historyKeyPrefix is store key prefix of finished games
+	historyKeyPrefix string = "janken_history_"
+
+	// historyChannelIndexKeyPrefix is store key prefix of the index of finished games in a channel
+	historyChannelIndexKeyPrefix string = "janken_history_channel_"
+
+	// statsKeyPrefix is store key prefix of the stats of users
+	statsKeyPrefix string = "janken_stats_"
+
+	// leaderboardKeyPrefix is store key prefix of the leaderboards of channels and teams
+	leaderboardKeyPrefix string = "janken_board_"
+
+	// ratingKeyPrefix is store key prefix of the ratings of users
+	ratingKeyPrefix string = "janken_rating_"
+
+	// notificationOptOutKeyPrefix is store key prefix of the users who don't receive direct messages
+	notificationOptOutKeyPrefix string = "janken_dm_optout_"
+
+	// maxUpdateAttempts is the number of attempts to update a game modified concurrently.
+	maxUpdateAttempts = 10
+
+	// maxHistoryIndexSize is the number of finished games kept in the history of a channel.
+	maxHistoryIndexSize = 500
+	// maxHistoryPruneCount is the number of the oldest finished games checked for expiry each time a game is saved.
+	maxHistoryPruneCount = 10
+	// maxHistorySearchCount is the number of the latest finished games checked by a search.
+	maxHistorySearchCount = 200
+)
+
+var (
+	// errGameNotFound is returned when a janken game doesn't exist in the KV store.
+	errGameNotFound = errors.New("janken game not found")
+	// errUpdateConflict is returned when a janken game keeps being modified concurrently.
+	errUpdateConflict = errors.New("janken game was modified concurrently too many times")
 )
 
 // Store is an interface to interact with the KV store.
 type Store struct {
-	API         plugin.API
-	jankenStore jankenStoreInterface
+	API               plugin.API
+	jankenStore       jankenStoreInterface
+	historyStore      historyStoreInterface
+	statsStore        statsStoreInterface
+	leaderboardStore  leaderboardStoreInterface
+	ratingStore       ratingStoreInterface
+	notificationStore notificationStoreInterface
 }
 
 // NewStore returns the new Store
@@ -28,6 +80,21 @@ func NewStore(api plugin.API) *Store {
 		jankenStore: jankenStore{
 			API: api,
 		},
+		historyStore: historyStore{
+			API: api,
+		},
+		statsStore: statsStore{
+			API: api,
+		},
+		leaderboardStore: leaderboardStore{
+			API: api,
+		},
+		ratingStore: ratingStore{
+			API: api,
+		},
+		notificationStore: notificationStore{
+			API: api,
+		},
 	}
 	return &store
 }
@@ -36,7 +103,12 @@ func NewStore(api plugin.API) *Store {
 type jankenStoreInterface interface {
 	Get(string) (*game, error)
 	Save(*game) error
+	Update(string, func(*game) error) (*game, error)
 	Delete(string) error
+	Claim(string, func(*game) error) (*game, error)
+	ListByChannel(string) ([]*game, error)
+	ListDue(int64) ([]*game, error)
+	ListLive() ([]*game, error)
 }
 
 // jankenStore allows to access janken games in the KV store.
@@ -66,15 +138,676 @@ func (s jankenStore) Save(game *game) error {
 	if err != nil {
 		return err
 	}
-	appErr := s.API.KVSetWithExpiry(keyPrefix+gameID, b, expireInSeconds);
+	appErr := s.API.KVSetWithExpiry(keyPrefix+gameID, b, expireInSeconds)
 	if appErr != nil {
 		return errors.New(appErr.DetailedError)
 	}
+	if game.Deadline > 0 {
+		if err := addToIndex(s.API, deadlineIndexKey, gameID); err != nil {
+			return err
+		}
+	}
+	if game.ChannelID != "" {
+		return addToIndex(s.API, channelIndexKeyPrefix+game.ChannelID, gameID)
+	}
 	return nil
 }
 
-// Delete deletes a janken game from the KV store.
+/*
+Update applies a given function to the latest janken game and saves it only if nobody else has saved the game in the meantime.
+When the game was modified concurrently, the function is applied again to the newer game, so concurrent updates are merged without losing any of them.
+An error returned by the function aborts the update and is returned as is.
+The game is added to the index of deadlines when the function sets a deadline, and to the index of live games when the function starts a live game.
+*/
+func (s jankenStore) Update(id string, f func(*game) error) (*game, error) {
+	var updated *game
+	err := compareAndSet(s.API, keyPrefix+id, expireInSeconds, func(oldValue []byte) ([]byte, error) {
+		if oldValue == nil {
+			return nil, errGameNotFound
+		}
+		game, err := gameFromBytes(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		if err = f(game); err != nil {
+			return nil, err
+		}
+		updated = game
+		return game.ToBytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if updated.Deadline > 0 {
+		if err := addToIndex(s.API, deadlineIndexKey, id); err != nil {
+			return nil, err
+		}
+	}
+	if isPlayingLive(updated) {
+		if err := addToIndex(s.API, liveIndexKey, id); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// Delete deletes a janken game from the KV store and from the indexes of its channel and deadline.
 func (s jankenStore) Delete(id string) error {
 	s.API.LogDebug("Delete", "id", id)
+	if b, appErr := s.API.KVGet(keyPrefix + id); appErr == nil && b != nil {
+		if game, err := gameFromBytes(b); err == nil {
+			s.removeFromIndexes(game)
+		}
+	}
 	return s.API.KVDelete(keyPrefix + id)
 }
+
+/*
+Claim deletes the latest janken game only if nobody else has modified or deleted it in the meantime, and returns the deleted game.
+Only one of the callers racing to claim the same game gets it, so the result of a game is shown only once.
+When the game was modified concurrently, the newer game is checked and claimed again.
+An error returned by the check function aborts the claim and is returned as is. The function may be nil.
+Returns errGameNotFound if the game doesn't exist or somebody else has claimed it.
+*/
+func (s jankenStore) Claim(id string, check func(*game) error) (*game, error) {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		b, appErr := s.API.KVGet(keyPrefix + id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if b == nil {
+			return nil, errGameNotFound
+		}
+		game, err := gameFromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		if check != nil {
+			if err := check(game); err != nil {
+				return nil, err
+			}
+		}
+
+		ok, appErr := s.API.KVSetWithOptions(keyPrefix+id, nil, model.PluginKVSetOptions{
+			Atomic:   true,
+			OldValue: b,
+		})
+		if appErr != nil {
+			return nil, errors.New(appErr.DetailedError)
+		}
+		if ok {
+			s.API.LogDebug("Claim", "id", id)
+			s.removeFromIndexes(game)
+			return game, nil
+		}
+		s.API.LogDebug("Update conflict", "key", keyPrefix+id, "attempt", i+1)
+	}
+	return nil, errUpdateConflict
+}
+
+// removeFromIndexes removes a janken game from the indexes of its channel, deadline and live games.
+func (s jankenStore) removeFromIndexes(game *game) {
+	if _, ok := game.Impl.(*gameImplLive); ok {
+		if err := removeFromIndex(s.API, liveIndexKey, game.ID); err != nil {
+			s.API.LogWarn("failed to remove the game from the live index", "id", game.ID, "error", err.Error())
+		}
+	}
+	if game.ChannelID != "" {
+		if err := removeFromIndex(s.API, channelIndexKeyPrefix+game.ChannelID, game.ID); err != nil {
+			s.API.LogWarn("failed to remove the game from the channel index", "id", game.ID, "error", err.Error())
+		}
+	}
+	if game.Deadline > 0 {
+		if err := removeFromIndex(s.API, deadlineIndexKey, game.ID); err != nil {
+			s.API.LogWarn("failed to remove the game from the deadline index", "id", game.ID, "error", err.Error())
+		}
+	}
+}
+
+/*
+ListByChannel returns the open janken games in a given channel ordered by creation time.
+Games that have expired are removed from the index of the channel.
+*/
+func (s jankenStore) ListByChannel(channelID string) ([]*game, error) {
+	ids, err := getIndex(s.API, channelIndexKeyPrefix+channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []*game{}
+	for _, id := range ids {
+		b, appErr := s.API.KVGet(keyPrefix + id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if b == nil {
+			// the game has expired
+			if err := removeFromIndex(s.API, channelIndexKeyPrefix+channelID, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		game, err := gameFromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].CreatedAt < games[j].CreatedAt
+	})
+	return games, nil
+}
+
+/*
+ListDue returns the open janken games whose deadline is at or before a given time in milliseconds, ordered by deadline.
+Games that have expired or no longer have a deadline are removed from the index of deadlines.
+*/
+func (s jankenStore) ListDue(now int64) ([]*game, error) {
+	ids, err := getIndex(s.API, deadlineIndexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []*game{}
+	for _, id := range ids {
+		b, appErr := s.API.KVGet(keyPrefix + id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		var game *game
+		if b != nil {
+			if game, err = gameFromBytes(b); err != nil {
+				return nil, err
+			}
+		}
+		if game == nil || game.Deadline == 0 {
+			// the game has expired or its deadline was removed
+			if err := removeFromIndex(s.API, deadlineIndexKey, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if game.Deadline <= now {
+			games = append(games, game)
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].Deadline < games[j].Deadline
+	})
+	return games, nil
+}
+
+/*
+ListLive returns the live janken games that are being played.
+Games that have expired or are no longer played are removed from the index of live games.
+*/
+func (s jankenStore) ListLive() ([]*game, error) {
+	ids, err := getIndex(s.API, liveIndexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []*game{}
+	for _, id := range ids {
+		b, appErr := s.API.KVGet(keyPrefix + id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		var game *game
+		if b != nil {
+			if game, err = gameFromBytes(b); err != nil {
+				return nil, err
+			}
+		}
+		if game == nil || !isPlayingLive(game) {
+			// the game has expired or finished
+			if err := removeFromIndex(s.API, liveIndexKey, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// isPlayingLive returns true if a given game is a live game being played.
+func isPlayingLive(game *game) bool {
+	live, ok := game.Impl.(*gameImplLive)
+	return ok && live.isPlaying()
+}
+
+// historyStoreInterface allows to access finished janken games in the KV store.
+type historyStoreInterface interface {
+	Save(*gameRecord, int64) error
+	Get(string) (*gameRecord, error)
+	ListByChannel(string, int) ([]*gameRecord, error)
+	SearchByChannel(string, string, int) ([]*gameRecord, error)
+}
+
+// historyStore allows to access finished janken games in the KV store.
+type historyStore struct {
+	API plugin.API
+}
+
+/*
+Save stores a finished janken game and adds it to the history of its channel.
+The record expires after a given number of seconds. It never expires if the number is 0.
+The history of a channel keeps the latest maxHistoryIndexSize games, and the older records are deleted.
+*/
+func (s historyStore) Save(record *gameRecord, expireInSeconds int64) error {
+	s.API.LogDebug("Save history", "id", record.ID)
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if appErr := s.API.KVSetWithExpiry(historyKeyPrefix+record.ID, b, expireInSeconds); appErr != nil {
+		return errors.New(appErr.DetailedError)
+	}
+	if record.ChannelID != "" {
+		return s.addToChannelIndex(record.ChannelID, record.ID)
+	}
+	return nil
+}
+
+/*
+addToChannelIndex appends an id to the history of a channel.
+Expired ids at the oldest end are removed, and the oldest records are deleted when the history exceeds maxHistoryIndexSize.
+*/
+func (s historyStore) addToChannelIndex(channelID, id string) error {
+	key := historyChannelIndexKeyPrefix + channelID
+	ids, err := getIndex(s.API, key)
+	if err != nil {
+		return err
+	}
+
+	// records expire from the oldest, so only the oldest end needs to be checked
+	expired := map[string]bool{}
+	for i := 0; i < len(ids) && i < maxHistoryPruneCount; i++ {
+		b, appErr := s.API.KVGet(historyKeyPrefix + ids[i])
+		if appErr != nil {
+			return appErr
+		}
+		if b != nil {
+			break
+		}
+		expired[ids[i]] = true
+	}
+
+	var dropped []string
+	err = updateIndex(s.API, key, func(ids []string) []string {
+		kept := make([]string, 0, len(ids)+1)
+		for _, i := range ids {
+			if !expired[i] && i != id {
+				kept = append(kept, i)
+			}
+		}
+		kept = append(kept, id)
+		dropped = nil
+		if over := len(kept) - maxHistoryIndexSize; over > 0 {
+			dropped = append([]string{}, kept[:over]...)
+			kept = kept[over:]
+		}
+		return kept
+	})
+	if err != nil {
+		return err
+	}
+	for _, d := range dropped {
+		if appErr := s.API.KVDelete(historyKeyPrefix + d); appErr != nil {
+			s.API.LogWarn("failed to delete the old history", "id", d, "error", appErr.Error())
+		}
+	}
+	return nil
+}
+
+// Get returns the finished janken game for a given id. Returns nil if the record doesn't exist or has expired.
+func (s historyStore) Get(id string) (*gameRecord, error) {
+	b, appErr := s.API.KVGet(historyKeyPrefix + id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if b == nil {
+		return nil, nil
+	}
+	record := &gameRecord{}
+	if err := json.Unmarshal(b, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+/*
+ListByChannel returns at most a given number of the latest finished janken games in a channel, newest first.
+All the records are returned if the number is 0. Records that have expired are removed from the history of the channel.
+*/
+func (s historyStore) ListByChannel(channelID string, limit int) ([]*gameRecord, error) {
+	return s.listByChannel(channelID, limit, 0, func(*gameRecord) bool { return true })
+}
+
+/*
+SearchByChannel returns at most a given number of the latest finished janken games in a channel
+whose title or purpose contains a given query, newest first. The query is case-insensitive.
+Only the latest maxHistorySearchCount games are searched.
+*/
+func (s historyStore) SearchByChannel(channelID, query string, limit int) ([]*gameRecord, error) {
+	return s.listByChannel(channelID, limit, maxHistorySearchCount, func(r *gameRecord) bool { return r.matches(query) })
+}
+
+/*
+listByChannel returns at most a given number of the latest finished janken games in a channel that match a given function.
+At most maxScan ids of the index are read. All the ids are read if maxScan is 0.
+*/
+func (s historyStore) listByChannel(channelID string, limit, maxScan int, match func(*gameRecord) bool) ([]*gameRecord, error) {
+	key := historyChannelIndexKeyPrefix + channelID
+	ids, err := getIndex(s.API, key)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*gameRecord{}
+	for i, scanned := len(ids)-1, 0; i >= 0; i, scanned = i-1, scanned+1 {
+		if (limit > 0 && len(records) >= limit) || (maxScan > 0 && scanned >= maxScan) {
+			break
+		}
+		record, err := s.Get(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			if err := removeFromIndex(s.API, key, ids[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if match(record) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// statsStoreInterface allows to access the stats of users in the KV store.
+type statsStoreInterface interface {
+	Get(string) (*userStats, error)
+	Update(string, func(*userStats)) (*userStats, error)
+}
+
+// statsStore allows to access the stats of users in the KV store.
+type statsStore struct {
+	API plugin.API
+}
+
+// Get returns the stats of a given user. Returns empty stats if the user has never played.
+func (s statsStore) Get(userID string) (*userStats, error) {
+	b, appErr := s.API.KVGet(statsKeyPrefix + userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return statsFromBytes(userID, b)
+}
+
+// Update applies a given function to the latest stats of a user and saves them with compare-and-set.
+func (s statsStore) Update(userID string, f func(*userStats)) (*userStats, error) {
+	var updated *userStats
+	err := compareAndSet(s.API, statsKeyPrefix+userID, 0, func(oldValue []byte) ([]byte, error) {
+		stats, err := statsFromBytes(userID, oldValue)
+		if err != nil {
+			return nil, err
+		}
+		f(stats)
+		updated = stats
+		return json.Marshal(stats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// statsFromBytes returns the stats of a user stored in the KV store.
+func statsFromBytes(userID string, b []byte) (*userStats, error) {
+	stats := newUserStats(userID)
+	if b == nil {
+		return stats, nil
+	}
+	if err := json.Unmarshal(b, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// leaderboardStoreInterface allows to access the leaderboards in the KV store.
+type leaderboardStoreInterface interface {
+	Get(string, string, string) (*leaderboard, error)
+	Update(string, string, string, int64, func(*leaderboard)) error
+}
+
+// leaderboardStore allows to access the leaderboards in the KV store.
+type leaderboardStore struct {
+	API plugin.API
+}
+
+// leaderboardKey returns the key of the leaderboard of a channel or a team for a period.
+func leaderboardKey(scope, scopeID, bucket string) string {
+	return fmt.Sprintf("%s%s_%s_%s", leaderboardKeyPrefix, scope, scopeID, bucket)
+}
+
+// Get returns the leaderboard of a channel or a team for a period. Returns an empty leaderboard if no game has finished.
+func (s leaderboardStore) Get(scope, scopeID, bucket string) (*leaderboard, error) {
+	b, appErr := s.API.KVGet(leaderboardKey(scope, scopeID, bucket))
+	if appErr != nil {
+		return nil, appErr
+	}
+	return leaderboardFromBytes(b)
+}
+
+/*
+Update applies a given function to the latest leaderboard of a channel or a team for a period and saves it with compare-and-set.
+The leaderboard expires after a given number of seconds from the last update. It never expires if the number is 0.
+*/
+func (s leaderboardStore) Update(scope, scopeID, bucket string, expireInSeconds int64, f func(*leaderboard)) error {
+	return compareAndSet(s.API, leaderboardKey(scope, scopeID, bucket), expireInSeconds, func(oldValue []byte) ([]byte, error) {
+		board, err := leaderboardFromBytes(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		f(board)
+		return json.Marshal(board)
+	})
+}
+
+// leaderboardFromBytes returns the leaderboard stored in the KV store.
+func leaderboardFromBytes(b []byte) (*leaderboard, error) {
+	board := newLeaderboard()
+	if b == nil {
+		return board, nil
+	}
+	if err := json.Unmarshal(b, board); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// ratingStoreInterface allows to access the ratings of users in the KV store.
+type ratingStoreInterface interface {
+	Get(string) (*userRating, error)
+	Update(string, func(*userRating)) (*userRating, error)
+}
+
+// ratingStore allows to access the ratings of users in the KV store.
+type ratingStore struct {
+	API plugin.API
+}
+
+// Get returns the rating of a given user. Returns the initial rating if the user has never been rated.
+func (s ratingStore) Get(userID string) (*userRating, error) {
+	b, appErr := s.API.KVGet(ratingKeyPrefix + userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return ratingFromBytes(userID, b)
+}
+
+// Update applies a given function to the latest rating of a user and saves it with compare-and-set.
+func (s ratingStore) Update(userID string, f func(*userRating)) (*userRating, error) {
+	var updated *userRating
+	err := compareAndSet(s.API, ratingKeyPrefix+userID, 0, func(oldValue []byte) ([]byte, error) {
+		rating, err := ratingFromBytes(userID, oldValue)
+		if err != nil {
+			return nil, err
+		}
+		f(rating)
+		updated = rating
+		return json.Marshal(rating)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ratingFromBytes returns the rating of a user stored in the KV store.
+func ratingFromBytes(userID string, b []byte) (*userRating, error) {
+	rating := newUserRating(userID)
+	if b == nil {
+		return rating, nil
+	}
+	if err := json.Unmarshal(b, rating); err != nil {
+		return nil, err
+	}
+	return rating, nil
+}
+
+// notificationStoreInterface allows to access the notification settings of users in the KV store.
+type notificationStoreInterface interface {
+	IsDisabled(string) (bool, error)
+	SetDisabled(string, bool) error
+}
+
+// notificationStore allows to access the notification settings of users in the KV store.
+type notificationStore struct {
+	API plugin.API
+}
+
+// IsDisabled returns true if a given user has turned off the direct messages of the results.
+func (s notificationStore) IsDisabled(userID string) (bool, error) {
+	b, appErr := s.API.KVGet(notificationOptOutKeyPrefix + userID)
+	if appErr != nil {
+		return false, appErr
+	}
+	return b != nil, nil
+}
+
+// SetDisabled turns off or on the direct messages of the results for a given user.
+func (s notificationStore) SetDisabled(userID string, disabled bool) error {
+	key := notificationOptOutKeyPrefix + userID
+	if !disabled {
+		if appErr := s.API.KVDelete(key); appErr != nil {
+			return errors.New(appErr.DetailedError)
+		}
+		return nil
+	}
+	if appErr := s.API.KVSetWithExpiry(key, []byte("true"), 0); appErr != nil {
+		return errors.New(appErr.DetailedError)
+	}
+	return nil
+}
+
+// getIndex returns the ids stored in an index.
+func getIndex(api plugin.API, key string) ([]string, error) {
+	b, appErr := api.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	ids := []string{}
+	if b == nil {
+		return ids, nil
+	}
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// addToIndex appends an id to an index unless the index already contains it.
+func addToIndex(api plugin.API, key, id string) error {
+	return updateIndex(api, key, func(ids []string) []string {
+		for _, i := range ids {
+			if i == id {
+				return ids
+			}
+		}
+		return append(ids, id)
+	})
+}
+
+// removeFromIndex removes an id from an index.
+func removeFromIndex(api plugin.API, key, id string) error {
+	return updateIndex(api, key, func(ids []string) []string {
+		removed := []string{}
+		for _, i := range ids {
+			if i != id {
+				removed = append(removed, i)
+			}
+		}
+		return removed
+	})
+}
+
+/*
+updateIndex applies a given function to the ids stored in an index with compare-and-set.
+The index is deleted when it becomes empty.
+*/
+func updateIndex(api plugin.API, key string, f func([]string) []string) error {
+	return compareAndSet(api, key, 0, func(oldValue []byte) ([]byte, error) {
+		ids := []string{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &ids); err != nil {
+				return nil, err
+			}
+		}
+		if ids = f(ids); len(ids) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(ids)
+	})
+}
+
+/*
+compareAndSet replaces the value of a key with the value returned by a given function only if nobody else has changed the value in the meantime.
+When the value was changed concurrently, the function is applied again to the newer value.
+The function receives nil if the key doesn't exist, and the key is deleted if the function returns nil.
+An error returned by the function aborts the update and is returned as is.
+*/
+func compareAndSet(api plugin.API, key string, expireInSeconds int64, f func([]byte) ([]byte, error)) error {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		oldValue, appErr := api.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+		newValue, err := f(oldValue)
+		if err != nil {
+			return err
+		}
+		if oldValue == nil && newValue == nil {
+			return nil
+		}
+
+		// KVCompareAndSet clears the expiry, so KVSetWithOptions is used instead
+		ok, appErr := api.KVSetWithOptions(key, newValue, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        oldValue,
+			ExpireInSeconds: expireInSeconds,
+		})
+		if appErr != nil {
+			return errors.New(appErr.DetailedError)
+		}
+		if ok {
+			return nil
+		}
+		api.LogDebug("Update conflict", "key", key, "attempt", i+1)
+	}
+	return errUpdateConflict
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"bou.ke/monkey"
@@ -25,11 +29,11 @@ func TestStore(t *testing.T) {
 func TestJankenStore(t *testing.T) {
 	t.Run("Get", func(t *testing.T) {
 		for name, test := range map[string]struct {
-			ID                  string
-			SetupAPI            func() *plugintest.API
+			ID            string
+			SetupAPI      func() *plugintest.API
 			gameFromBytes func([]byte) (*game, error)
-			ExpectedGame        *game
-			ShouldError         bool
+			ExpectedGame  *game
+			ShouldError   bool
 		}{
 			"successfully": {
 				ID: "testId",
@@ -159,6 +163,318 @@ func TestJankenStore(t *testing.T) {
 		}
 	})
 
+	t.Run("Update", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			Stored      *game
+			Conflicts   int
+			Mutate      func(*game) error
+			ExpectedErr error
+		}{
+			"successfully": {
+				Stored: newGame(&gameImpl1{}),
+				Mutate: func(g *game) error {
+					g.UpdateHands("p1", []string{"rock"})
+					return nil
+				},
+			},
+			"successfully after conflicts": {
+				Stored:    newGame(&gameImpl1{}),
+				Conflicts: 2,
+				Mutate: func(g *game) error {
+					g.UpdateHands("p1", []string{"rock"})
+					return nil
+				},
+			},
+			"failed because the game doesn't exist": {
+				Mutate:      func(g *game) error { return nil },
+				ExpectedErr: errGameNotFound,
+			},
+			"failed because the function returns an error": {
+				Stored:      newGame(&gameImpl1{}),
+				Mutate:      func(g *game) error { return errPermission },
+				ExpectedErr: errPermission,
+			},
+			"failed because of too many conflicts": {
+				Stored:      newGame(&gameImpl1{}),
+				Conflicts:   maxUpdateAttempts,
+				Mutate:      func(g *game) error { return nil },
+				ExpectedErr: errUpdateConflict,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				api, kv := newAtomicKVAPI()
+				s := jankenStore{API: api}
+				id := "testId"
+				if test.Stored != nil {
+					test.Stored.ID = id
+					kv.values[keyPrefix+id], _ = test.Stored.ToBytes()
+				}
+				kv.conflicts = test.Conflicts
+
+				g, err := s.Update(id, test.Mutate)
+
+				assert.Equal(test.ExpectedErr, err)
+				if test.ExpectedErr != nil {
+					assert.Nil(g)
+					return
+				}
+				stored, _ := gameFromBytes(kv.values[keyPrefix+id])
+				assert.Equal(g.Participants, stored.Participants)
+				assert.Len(stored.Participants, 1)
+				assert.Equal(expireInSeconds, kv.expiries[keyPrefix+id])
+			})
+		}
+	})
+
+	t.Run("Update merges concurrent updates", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		g := newGame(&gameImpl1{})
+		kv.values[keyPrefix+g.ID], _ = g.ToBytes()
+
+		n := maxUpdateAttempts - 1
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(userID string) {
+				defer wg.Done()
+				_, err := s.Update(g.ID, func(g *game) error {
+					g.UpdateHands(userID, []string{"rock"})
+					return nil
+				})
+				assert.Nil(err)
+			}(fmt.Sprintf("p%d", i))
+		}
+		wg.Wait()
+
+		stored, _ := gameFromBytes(kv.values[keyPrefix+g.ID])
+		assert.Len(stored.Participants, n)
+	})
+
+	t.Run("Update keeps the update made while applying the function", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		g := newGame(&gameImpl1{})
+		kv.values[keyPrefix+g.ID], _ = g.ToBytes()
+
+		attempts := 0
+		_, err := s.Update(g.ID, func(g *game) error {
+			attempts++
+			if attempts == 1 {
+				// 別のリクエストが先に参加者を追加する
+				s.Update(g.ID, func(g *game) error {
+					g.UpdateHands("p2", []string{"paper"})
+					return nil
+				})
+			}
+			g.UpdateHands("p1", []string{"rock"})
+			return nil
+		})
+
+		assert.Nil(err)
+		assert.Equal(2, attempts)
+		stored, _ := gameFromBytes(kv.values[keyPrefix+g.ID])
+		assert.NotNil(stored.GetParticipant("p1"))
+		assert.NotNil(stored.GetParticipant("p2"))
+	})
+
+	t.Run("Claim returns the game only once", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		g := newGame(&gameImpl1{})
+		g.ChannelID = "c1"
+		g.Deadline = 1
+		assert.Nil(s.Save(g))
+
+		var claimed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.Claim(g.ID, nil); err == nil {
+					atomic.AddInt32(&claimed, 1)
+				} else {
+					assert.Equal(errGameNotFound, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(int32(1), claimed)
+		assert.Nil(kv.values[keyPrefix+g.ID])
+		ids, _ := getIndex(api, channelIndexKeyPrefix+"c1")
+		assert.Empty(ids)
+		ids, _ = getIndex(api, deadlineIndexKey)
+		assert.Empty(ids)
+	})
+
+	t.Run("Claim returns the latest game", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		g := newGame(&gameImpl1{})
+		kv.values[keyPrefix+g.ID], _ = g.ToBytes()
+
+		attempts := 0
+		claimed, err := s.Claim(g.ID, func(g *game) error {
+			attempts++
+			if attempts == 1 {
+				// 別のリクエストが先に参加者を追加する
+				s.Update(g.ID, func(g *game) error {
+					g.UpdateHands("p1", []string{"rock"})
+					return nil
+				})
+			}
+			return nil
+		})
+
+		assert.Nil(err)
+		assert.Equal(2, attempts)
+		assert.NotNil(claimed.GetParticipant("p1"))
+		assert.Nil(kv.values[keyPrefix+g.ID])
+	})
+
+	t.Run("Claim keeps the game when the check fails", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		g := newGame(&gameImpl1{})
+		kv.values[keyPrefix+g.ID], _ = g.ToBytes()
+
+		claimed, err := s.Claim(g.ID, checkEnoughParticipants)
+
+		assert.Equal(errNotEnoughParticipants, err)
+		assert.Nil(claimed)
+		assert.NotNil(kv.values[keyPrefix+g.ID])
+	})
+
+	t.Run("live index", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		waiting, _ := newLiveTestGame("p1", "p2")
+		playing, _ := newLiveTestGame("p1", "p2")
+		for _, g := range []*game{waiting, playing} {
+			assert.Nil(s.Save(g))
+		}
+		_, err := s.Update(playing.ID, func(g *game) error {
+			return g.Impl.(*gameImplLive).start(g)
+		})
+		assert.Nil(err)
+
+		games, err := s.ListLive()
+		assert.Nil(err)
+		assert.Len(games, 1)
+		assert.Equal(playing.ID, games[0].ID)
+
+		// 期限切れのゲームは一覧から消える
+		delete(kv.values, keyPrefix+playing.ID)
+		games, _ = s.ListLive()
+		assert.Empty(games)
+		ids, _ := getIndex(api, liveIndexKey)
+		assert.Empty(ids)
+	})
+
+	t.Run("channel index", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		newChannelGame := func(channelID string, createdAt int64) *game {
+			g := newGame(&gameImpl1{})
+			g.ChannelID = channelID
+			g.CreatedAt = createdAt
+			assert.Nil(s.Save(g))
+			return g
+		}
+		g1 := newChannelGame("c1", 2)
+		g2 := newChannelGame("c1", 1)
+		g3 := newChannelGame("c2", 3)
+		// 保存し直しても重複しない
+		assert.Nil(s.Save(g1))
+
+		games, err := s.ListByChannel("c1")
+		assert.Nil(err)
+		assert.Equal([]string{g2.ID, g1.ID}, []string{games[0].ID, games[1].ID})
+
+		// 削除したゲームは一覧から消える
+		assert.Nil(s.Delete(g2.ID))
+		games, _ = s.ListByChannel("c1")
+		assert.Len(games, 1)
+		assert.Equal(g1.ID, games[0].ID)
+
+		// 期限切れのゲームは一覧を取得するときに消える
+		delete(kv.values, keyPrefix+g1.ID)
+		games, err = s.ListByChannel("c1")
+		assert.Nil(err)
+		assert.Empty(games)
+		assert.NotContains(kv.values, channelIndexKeyPrefix+"c1")
+
+		games, _ = s.ListByChannel("c2")
+		assert.Len(games, 1)
+		assert.Equal(g3.ID, games[0].ID)
+
+		games, err = s.ListByChannel("unknown")
+		assert.Nil(err)
+		assert.Empty(games)
+	})
+
+	t.Run("deadline index", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := jankenStore{API: api}
+		newDeadlineGame := func(deadline int64) *game {
+			g := newGame(&gameImpl1{})
+			g.Deadline = deadline
+			assert.Nil(s.Save(g))
+			return g
+		}
+		g1 := newDeadlineGame(20)
+		g2 := newDeadlineGame(10)
+		g3 := newDeadlineGame(30)
+		newDeadlineGame(0)
+
+		games, err := s.ListDue(20)
+		assert.Nil(err)
+		assert.Len(games, 2)
+		assert.Equal([]string{g2.ID, g1.ID}, []string{games[0].ID, games[1].ID})
+
+		// 締め切りを設定したゲームは一覧に加わる
+		g4 := newGame(&gameImpl1{})
+		assert.Nil(s.Save(g4))
+		_, err = s.Update(g4.ID, func(g *game) error {
+			g.Deadline = 5
+			return nil
+		})
+		assert.Nil(err)
+		games, _ = s.ListDue(20)
+		assert.Len(games, 3)
+		assert.Equal(g4.ID, games[0].ID)
+
+		// 削除したゲームと締め切りを外したゲームは一覧から消える
+		assert.Nil(s.Delete(g2.ID))
+		_, err = s.Update(g4.ID, func(g *game) error {
+			g.Deadline = 0
+			return nil
+		})
+		assert.Nil(err)
+		games, _ = s.ListDue(20)
+		assert.Len(games, 1)
+		assert.Equal(g1.ID, games[0].ID)
+
+		// 期限切れのゲームは一覧を取得するときに消える
+		delete(kv.values, keyPrefix+g1.ID)
+		delete(kv.values, keyPrefix+g3.ID)
+		games, err = s.ListDue(100)
+		assert.Nil(err)
+		assert.Empty(games)
+		assert.NotContains(kv.values, deadlineIndexKey)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		for name, test := range map[string]struct {
 			ID          string
@@ -170,6 +486,7 @@ func TestJankenStore(t *testing.T) {
 				SetupAPI: func() *plugintest.API {
 					api := &plugintest.API{}
 					api.On("LogDebug", "Delete", "id", mock.AnythingOfType("string"))
+					api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
 					api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
 					return api
 				},
@@ -180,6 +497,7 @@ func TestJankenStore(t *testing.T) {
 				SetupAPI: func() *plugintest.API {
 					api := &plugintest.API{}
 					api.On("LogDebug", "Delete", "id", mock.AnythingOfType("string"))
+					api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
 					api.On("KVDelete", mock.AnythingOfType("string")).Return(&model.AppError{})
 					return api
 				},
@@ -202,3 +520,233 @@ func TestJankenStore(t *testing.T) {
 		}
 	})
 }
+
+func TestHistoryStore(t *testing.T) {
+	newRecord := func(channelID string) *gameRecord {
+		return &gameRecord{
+			ID:           model.NewId(),
+			ChannelID:    channelID,
+			Participants: []*recordParticipant{{UserID: "p1", Rank: 1, Hands: []string{"rock"}}},
+		}
+	}
+
+	t.Run("Save and Get", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := historyStore{API: api}
+		r := newRecord("c1")
+
+		assert.Nil(s.Save(r, 60))
+
+		stored, err := s.Get(r.ID)
+		assert.Nil(err)
+		assert.Equal(r, stored)
+		assert.Equal(int64(60), kv.expiries[historyKeyPrefix+r.ID])
+
+		stored, err = s.Get("unknown")
+		assert.Nil(err)
+		assert.Nil(stored)
+	})
+
+	t.Run("ListByChannel", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := historyStore{API: api}
+		r1 := newRecord("c1")
+		r2 := newRecord("c1")
+		r3 := newRecord("c1")
+		for _, r := range []*gameRecord{r1, r2, r3, newRecord("c2")} {
+			assert.Nil(s.Save(r, 0))
+		}
+
+		records, err := s.ListByChannel("c1", 0)
+		assert.Nil(err)
+		assert.Equal([]*gameRecord{r3, r2, r1}, records)
+
+		records, _ = s.ListByChannel("c1", 2)
+		assert.Equal([]*gameRecord{r3, r2}, records)
+
+		// 期限切れの記録は一覧から消える
+		delete(kv.values, historyKeyPrefix+r3.ID)
+		records, _ = s.ListByChannel("c1", 2)
+		assert.Equal([]*gameRecord{r2, r1}, records)
+		ids, _ := getIndex(api, historyChannelIndexKeyPrefix+"c1")
+		assert.Equal([]string{r1.ID, r2.ID}, ids)
+	})
+
+	t.Run("Save caps the history and prunes expired records", func(t *testing.T) {
+		assert := assert.New(t)
+		api, kv := newAtomicKVAPI()
+		s := historyStore{API: api}
+		records := []*gameRecord{}
+		for i := 0; i < maxHistoryIndexSize+2; i++ {
+			r := newRecord("c1")
+			records = append(records, r)
+			assert.Nil(s.Save(r, 0))
+		}
+
+		// 上限を超えた古い記録は削除する
+		ids, _ := getIndex(api, historyChannelIndexKeyPrefix+"c1")
+		assert.Len(ids, maxHistoryIndexSize)
+		assert.Equal(records[2].ID, ids[0])
+		assert.Nil(kv.values[historyKeyPrefix+records[0].ID])
+		assert.Nil(kv.values[historyKeyPrefix+records[1].ID])
+		assert.NotNil(kv.values[historyKeyPrefix+records[2].ID])
+
+		// 期限切れの古い記録は次に保存したときに索引から消える
+		delete(kv.values, historyKeyPrefix+records[2].ID)
+		delete(kv.values, historyKeyPrefix+records[3].ID)
+		assert.Nil(s.Save(newRecord("c1"), 0))
+		ids, _ = getIndex(api, historyChannelIndexKeyPrefix+"c1")
+		assert.Len(ids, maxHistoryIndexSize-1)
+		assert.Equal(records[4].ID, ids[0])
+	})
+
+	t.Run("SearchByChannel", func(t *testing.T) {
+		assert := assert.New(t)
+		api, _ := newAtomicKVAPI()
+		s := historyStore{API: api}
+		r1 := newRecord("c1")
+		r1.Purpose = "Who presents at Friday demo?"
+		r2 := newRecord("c1")
+		r2.Title = "Lunch"
+		r3 := newRecord("c1")
+		r3.Title = "Demo day"
+		r4 := newRecord("c2")
+		r4.Title = "Demo"
+		for _, r := range []*gameRecord{r1, r2, r3, r4} {
+			assert.Nil(s.Save(r, 0))
+		}
+
+		records, err := s.SearchByChannel("c1", "demo", 0)
+		assert.Nil(err)
+		assert.Equal([]*gameRecord{r3, r1}, records)
+
+		records, _ = s.SearchByChannel("c1", "demo", 1)
+		assert.Equal([]*gameRecord{r3}, records)
+
+		// 古いゲームは検索しない
+		for i := 0; i < maxHistorySearchCount; i++ {
+			assert.Nil(s.Save(newRecord("c1"), 0))
+		}
+		records, _ = s.SearchByChannel("c1", "demo", 0)
+		assert.Empty(records)
+	})
+}
+
+func TestLeaderboardStore(t *testing.T) {
+	assert := assert.New(t)
+	api, kv := newAtomicKVAPI()
+	s := leaderboardStore{API: api}
+
+	b, err := s.Get(leaderboardScopeChannel, "c1", "all")
+	assert.Nil(err)
+	assert.Empty(b.Entries)
+
+	for i := 0; i < 2; i++ {
+		err = s.Update(leaderboardScopeChannel, "c1", "2021-01", 60, func(b *leaderboard) {
+			b.entry("p1").Wins++
+		})
+		assert.Nil(err)
+	}
+
+	b, err = s.Get(leaderboardScopeChannel, "c1", "2021-01")
+	assert.Nil(err)
+	assert.Equal(2, b.Entries["p1"].Wins)
+	assert.Equal(int64(60), kv.expiries[leaderboardKey(leaderboardScopeChannel, "c1", "2021-01")])
+
+	// 範囲と期間ごとに別々に集計する
+	b, _ = s.Get(leaderboardScopeTeam, "c1", "2021-01")
+	assert.Empty(b.Entries)
+}
+
+func TestNotificationStore(t *testing.T) {
+	assert := assert.New(t)
+	api, kv := newAtomicKVAPI()
+	s := notificationStore{API: api}
+
+	disabled, err := s.IsDisabled("p1")
+	assert.Nil(err)
+	assert.False(disabled)
+
+	assert.Nil(s.SetDisabled("p1", true))
+	disabled, _ = s.IsDisabled("p1")
+	assert.True(disabled)
+	disabled, _ = s.IsDisabled("p2")
+	assert.False(disabled)
+
+	assert.Nil(s.SetDisabled("p1", false))
+	disabled, _ = s.IsDisabled("p1")
+	assert.False(disabled)
+	assert.Empty(kv.values)
+}
+
+// atomicKV はKVSetWithOptionsのcompare-and-setを再現するKVストア
+type atomicKV struct {
+	mutex    sync.Mutex
+	values   map[string][]byte
+	expiries map[string]int64
+	// conflicts は他のリクエストが先に保存したことにして失敗させる回数
+	conflicts int
+}
+
+func newAtomicKVAPI() (*plugintest.API, *atomicKV) {
+	kv := &atomicKV{values: map[string][]byte{}, expiries: map[string]int64{}}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte {
+			kv.mutex.Lock()
+			defer kv.mutex.Unlock()
+			return kv.values[key]
+		},
+		nil)
+	api.On("KVSetWithOptions",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			kv.mutex.Lock()
+			defer kv.mutex.Unlock()
+			if kv.conflicts > 0 {
+				kv.conflicts--
+				return false
+			}
+			if !options.Atomic || !bytes.Equal(kv.values[key], options.OldValue) {
+				return false
+			}
+			if value == nil {
+				delete(kv.values, key)
+			} else {
+				kv.values[key] = value
+				kv.expiries[key] = options.ExpireInSeconds
+			}
+			return true
+		},
+		nil)
+	api.On("KVSetWithExpiry",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("int64")).Return(
+		func(key string, value []byte, expireInSeconds int64) *model.AppError {
+			kv.mutex.Lock()
+			defer kv.mutex.Unlock()
+			kv.values[key] = value
+			kv.expiries[key] = expireInSeconds
+			return nil
+		})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(
+		func(key string) *model.AppError {
+			kv.mutex.Lock()
+			defer kv.mutex.Unlock()
+			delete(kv.values, key)
+			return nil
+		})
+	api.On("LogDebug", "Save", "id", mock.Anything, "game", mock.Anything).Return()
+	api.On("LogDebug", "Delete", "id", mock.Anything).Return()
+	api.On("LogDebug", "Claim", "id", mock.Anything).Return()
+	api.On("LogWarn", "the lock has expired before unlocking", "key", mock.Anything).Return()
+	api.On("LogDebug", "The game was not claimed", "id", mock.Anything, "error", mock.Anything).Return()
+	api.On("LogDebug", "Save history", "id", mock.Anything).Return()
+	api.On("LogDebug", "Update conflict", "key", mock.Anything, "attempt", mock.Anything).Return()
+	return api, kv
+}
@@ -0,0 +1,645 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+const (
+	// subcommandCreate はゲームを作成するサブコマンド．サブコマンドを省略した場合もゲームを作成する
+	subcommandCreate = "create"
+	// subcommandResult は受付中のゲームの結果を表示するサブコマンド
+	subcommandResult = "result"
+	// subcommandCancel は受付中のゲームを削除するサブコマンド
+	subcommandCancel = "cancel"
+	// subcommandConfig は受付中のゲームの設定ダイアログを開くサブコマンド
+	subcommandConfig = "config"
+	// subcommandHelp は使い方を表示するサブコマンド
+	subcommandHelp = "help"
+
+	// autocompleteGamesURL はオートコンプリートでチャンネルの受付中のゲームのIDを返すAPIのURL．
+	// サーバーは"plugins/<プラグインID>"で始まるURLだけをプラグインに転送する
+	autocompleteGamesURL = "plugins/" + PluginID + "/api/v1/janken/autocomplete/games"
+)
+
+var (
+	usageTitle = &i18n.Message{
+		ID:    "usageTitle",
+		Other: "Usage of /{{.Trigger}}",
+	}
+	usageCommandHeader = &i18n.Message{
+		ID: "usageCommandHeader",
+		Other: `| Command | Description |
+| --- | --- |`,
+	}
+	usageOptionsTitle = &i18n.Message{
+		ID:    "usageOptionsTitle",
+		Other: "Options of {{.Subcommand}}",
+	}
+	usageOptionHeader = &i18n.Message{
+		ID: "usageOptionHeader",
+		Other: `| Option | Description |
+| --- | --- |`,
+	}
+	usageParseErrorMessage = &i18n.Message{
+		ID:    "usageParseErrorMessage",
+		Other: "Failed to parse arguments.: {{.Error}}",
+	}
+	usageUnknownSubcommandErrorMessage = &i18n.Message{
+		ID:    "usageUnknownSubcommandErrorMessage",
+		Other: "Unknown subcommand: {{.Subcommand}}",
+	}
+	usageUnknownOptionErrorMessage = &i18n.Message{
+		ID:    "usageUnknownOptionErrorMessage",
+		Other: "Unknown option: -{{.Option}}",
+	}
+	usageMissingValueErrorMessage = &i18n.Message{
+		ID:    "usageMissingValueErrorMessage",
+		Other: "Option -{{.Option}} requires a value.",
+	}
+	usageInvalidValueErrorMessage = &i18n.Message{
+		ID:    "usageInvalidValueErrorMessage",
+		Other: `Invalid value "{{.Value}}" for option -{{.Option}}.`,
+	}
+	usageInvalidChoiceErrorMessage = &i18n.Message{
+		ID:    "usageInvalidChoiceErrorMessage",
+		Other: `Invalid value "{{.Value}}" for option -{{.Option}}. Available values are {{.Values}}.`,
+	}
+	usageUnexpectedArgumentsErrorMessage = &i18n.Message{
+		ID:    "usageUnexpectedArgumentsErrorMessage",
+		Other: "Unexpected arguments: {{.Arguments}}",
+	}
+	commandAutocompleteDescription = &i18n.Message{
+		ID:    "commandAutocompleteDescription",
+		Other: "Play janken",
+	}
+	commandGameIDArgumentHelp = &i18n.Message{
+		ID:    "commandGameIDArgumentHelp",
+		Other: "ID of an open janken game in this channel",
+	}
+	commandGameIDItemHelp = &i18n.Message{
+		ID:    "commandGameIDItemHelp",
+		Other: "Created by @{{.Username}}, {{.Count}} participants",
+	}
+	commandGameIDRequiredErrorMessage = &i18n.Message{
+		ID:    "commandGameIDRequiredErrorMessage",
+		Other: "Specify the ID of a game.",
+	}
+	commandGameAmbiguousErrorMessage = &i18n.Message{
+		ID:    "commandGameAmbiguousErrorMessage",
+		Other: "More than one janken game in this channel starts with {{.ID}}. Specify a longer ID. `/{{.Trigger}} list` shows the open games.",
+	}
+	commandGameNotFoundErrorMessage = &i18n.Message{
+		ID:    "commandGameNotFoundErrorMessage",
+		Other: "Janken game {{.ID}} is not found in this channel. `/{{.Trigger}} list` shows the open games.",
+	}
+	commandCancelPermissionErrorMessage = &i18n.Message{
+		ID:    "commandCancelPermissionErrorMessage",
+		Other: "Failed to cancel the janken game. The creator of this game or the administrator can cancel the game.",
+	}
+
+	subcommandCreateDescription = &i18n.Message{
+		ID:    "subcommandCreateDescription",
+		Other: "Create a janken game. Only the mentioned users and you can join when users are mentioned. `create` can be omitted.",
+	}
+	subcommandListDescription = &i18n.Message{
+		ID:    "subcommandListDescription",
+		Other: "Show the open games in this channel",
+	}
+	subcommandResultDescription = &i18n.Message{
+		ID:    "subcommandResultDescription",
+		Other: "Show the result of an open game",
+	}
+	subcommandCancelDescription = &i18n.Message{
+		ID:    "subcommandCancelDescription",
+		Other: "Cancel an open game",
+	}
+	subcommandConfigDescription = &i18n.Message{
+		ID:    "subcommandConfigDescription",
+		Other: "Open the config dialog of an open game",
+	}
+	subcommandHistoryDescription = &i18n.Message{
+		ID:    "subcommandHistoryDescription",
+		Other: "Show the last N (default 5) finished games in this channel. With a keyword, only the games whose title or purpose contains it",
+	}
+	subcommandStatsDescription = &i18n.Message{
+		ID:    "subcommandStatsDescription",
+		Other: "Show the stats of a user (default to yourself)",
+	}
+	subcommandLeaderboardDescription = &i18n.Message{
+		ID:    "subcommandLeaderboardDescription",
+		Other: "Rank the users in this channel (default) or team by the score set in the system console",
+	}
+	subcommandRatingDescription = &i18n.Message{
+		ID:    "subcommandRatingDescription",
+		Other: "Show the Elo rating of a user (default to yourself) and its recent changes",
+	}
+	subcommandHelpDescription = &i18n.Message{
+		ID:    "subcommandHelpDescription",
+		Other: "Show this usage",
+	}
+
+	createLanguageFlagDescription = &i18n.Message{
+		ID:    "createLanguageFlagDescription",
+		Other: "Language",
+	}
+	createHandsFlagDescription = &i18n.Message{
+		ID:    "createHandsFlagDescription",
+		Other: "Hands (rock-paper-scissors or rock-paper-scissors-lizard-Spock)",
+	}
+	createTypeFlagDescription = &i18n.Message{
+		ID:    "createTypeFlagDescription",
+		Other: "Game type (ranking, tournament bracket, round-robin league, find the one loser, pick winners, team janken or live)",
+	}
+	createRoundsFlagDescription = &i18n.Message{
+		ID:    "createRoundsFlagDescription",
+		Other: "Max number of janken in a match (default 5)",
+	}
+	createTitleFlagDescription = &i18n.Message{
+		ID:    "createTitleFlagDescription",
+		Other: "Title of the game",
+	}
+	createAnonymousFlagDescription = &i18n.Message{
+		ID:    "createAnonymousFlagDescription",
+		Other: "Hide the participants until the result is shown",
+	}
+	createPublishFlagDescription = &i18n.Message{
+		ID:    "createPublishFlagDescription",
+		Other: "Add the result to the game post, post it as a new message or reply in the thread (default to the plugin setting)",
+	}
+	createWinnersFlagDescription = &i18n.Message{
+		ID:    "createWinnersFlagDescription",
+		Other: "Pick N winners instead of ranking all participants",
+	}
+	createMinFlagDescription = &i18n.Message{
+		ID:    "createMinFlagDescription",
+		Other: "Require N users (default 2) to show the result",
+	}
+	createMaxFlagDescription = &i18n.Message{
+		ID:    "createMaxFlagDescription",
+		Other: "Allow up to N users to join",
+	}
+	createAutoFlagDescription = &i18n.Message{
+		ID:    "createAutoFlagDescription",
+		Other: "Show the result automatically when the max participants have joined",
+	}
+	createDeadlineFlagDescription = &i18n.Message{
+		ID:    "createDeadlineFlagDescription",
+		Other: "Show the result automatically after the duration",
+	}
+	createAtFlagDescription = &i18n.Message{
+		ID:    "createAtFlagDescription",
+		Other: "Show the result automatically at the time in your timezone",
+	}
+	historyCountFlagDescription = &i18n.Message{
+		ID:    "historyCountFlagDescription",
+		Other: "Number of games to show, from 1 to 50 (default 5)",
+	}
+	leaderboardChannelFlagDescription = &i18n.Message{
+		ID:    "leaderboardChannelFlagDescription",
+		Other: "Rank the users in this channel",
+	}
+	leaderboardTeamFlagDescription = &i18n.Message{
+		ID:    "leaderboardTeamFlagDescription",
+		Other: "Rank the users in this team",
+	}
+	leaderboardPeriodFlagDescription = &i18n.Message{
+		ID:    "leaderboardPeriodFlagDescription",
+		Other: "Count the games finished this week, this month or all time (default)",
+	}
+)
+
+/*
+subcommand はスラッシュコマンドのサブコマンド．
+使い方とオートコンプリートは同じ定義から作る
+*/
+type subcommand struct {
+	Name string
+	// 引数の書式
+	Hint        string
+	Description *i18n.Message
+	// 受付中のゲームのIDを引数にとる
+	GameIDArgument bool
+	// 引数の候補を返す．候補がないサブコマンドはnil
+	Values func(p *Plugin) []string
+	// オプション
+	Flags []*commandFlag
+	// サブコマンドを実行する
+	Execute func(p *Plugin, siteURL string, args *model.CommandArgs) *model.CommandResponse
+}
+
+// commandFlag はサブコマンドのオプション
+type commandFlag struct {
+	Name string
+	// 値の書式．値をとらないオプションは空文字
+	Hint        string
+	Description *i18n.Message
+	// 値の候補を返す．候補がないオプションはnil
+	Values func(p *Plugin) []string
+}
+
+// getSubcommands はサブコマンドを使い方の表示順に返す
+func getSubcommands() []*subcommand {
+	return []*subcommand{
+		{
+			Name:        subcommandCreate,
+			Hint:        "[options] [@user ...]",
+			Description: subcommandCreateDescription,
+			Flags: []*commandFlag{
+				{Name: "l", Hint: "en|ja", Description: createLanguageFlagDescription, Values: (*Plugin).getLanguages},
+				{Name: "hands", Hint: strings.Join(handSetNames, "|"), Description: createHandsFlagDescription, Values: func(*Plugin) []string { return handSetNames }},
+				{Name: "type", Hint: strings.Join(gameTypeOptionNames, "|"), Description: createTypeFlagDescription, Values: func(*Plugin) []string { return gameTypeOptionNames }},
+				{Name: "rounds", Hint: "N", Description: createRoundsFlagDescription},
+				{Name: "winners", Hint: "N", Description: createWinnersFlagDescription},
+				{Name: "min", Hint: "N", Description: createMinFlagDescription},
+				{Name: "max", Hint: "N", Description: createMaxFlagDescription},
+				{Name: "auto", Description: createAutoFlagDescription},
+				{Name: "deadline", Hint: "15m", Description: createDeadlineFlagDescription},
+				{Name: "at", Hint: "17:00", Description: createAtFlagDescription},
+				{Name: "title", Hint: `"..."`, Description: createTitleFlagDescription},
+				{Name: "anonymous", Description: createAnonymousFlagDescription},
+				{Name: "publish", Hint: strings.Join(resultPublishModes, "|"), Description: createPublishFlagDescription, Values: func(*Plugin) []string { return resultPublishModes }},
+			},
+			Execute: (*Plugin).executeCreateCommand,
+		},
+		{
+			Name:        subcommandList,
+			Description: subcommandListDescription,
+			Execute:     (*Plugin).executeListCommand,
+		},
+		{
+			Name:           subcommandResult,
+			Hint:           "<ID>",
+			Description:    subcommandResultDescription,
+			GameIDArgument: true,
+			Execute:        (*Plugin).executeResultCommand,
+		},
+		{
+			Name:           subcommandCancel,
+			Hint:           "<ID>",
+			Description:    subcommandCancelDescription,
+			GameIDArgument: true,
+			Execute:        (*Plugin).executeCancelCommand,
+		},
+		{
+			Name:           subcommandConfig,
+			Hint:           "<ID>",
+			Description:    subcommandConfigDescription,
+			GameIDArgument: true,
+			Execute:        (*Plugin).executeConfigCommand,
+		},
+		{
+			Name:        subcommandHistory,
+			Hint:        "[-n N] [keyword]",
+			Description: subcommandHistoryDescription,
+			Flags: []*commandFlag{
+				{Name: "n", Hint: "N", Description: historyCountFlagDescription},
+			},
+			Execute: (*Plugin).executeHistoryCommand,
+		},
+		{
+			Name:        subcommandStats,
+			Hint:        "[@user]",
+			Description: subcommandStatsDescription,
+			Execute:     (*Plugin).executeStatsCommand,
+		},
+		{
+			Name:        subcommandLeaderboard,
+			Hint:        "[options]",
+			Description: subcommandLeaderboardDescription,
+			Flags: []*commandFlag{
+				{Name: "channel", Description: leaderboardChannelFlagDescription},
+				{Name: "team", Description: leaderboardTeamFlagDescription},
+				{Name: "period", Hint: strings.Join(leaderboardPeriods, "|"), Description: leaderboardPeriodFlagDescription, Values: func(*Plugin) []string { return leaderboardPeriods }},
+			},
+			Execute: (*Plugin).executeLeaderboardCommand,
+		},
+		{
+			Name:        subcommandRating,
+			Hint:        "[@user]",
+			Description: subcommandRatingDescription,
+			Execute:     (*Plugin).executeRatingCommand,
+		},
+		{
+			Name:        subcommandNotify,
+			Hint:        strings.Join(notifyValues, "|"),
+			Description: subcommandNotifyDescription,
+			Values:      func(*Plugin) []string { return notifyValues },
+			Execute:     (*Plugin).executeNotifyCommand,
+		},
+		{
+			Name:        subcommandHelp,
+			Description: subcommandHelpDescription,
+			Execute:     (*Plugin).executeHelpCommand,
+		},
+	}
+}
+
+// findSubcommand は指定した名前のサブコマンドを返す．見つからない場合はnil
+func findSubcommand(name string) *subcommand {
+	for _, sc := range getSubcommands() {
+		if sc.Name == name {
+			return sc
+		}
+	}
+	return nil
+}
+
+// getLanguages は利用できる言語を返す
+func (p *Plugin) getLanguages() []string {
+	languages := []string{}
+	for _, t := range p.bundle.LanguageTags() {
+		languages = append(languages, t.String())
+	}
+	return languages
+}
+
+// getUsage はサブコマンドとオプションの一覧を返す
+func (p *Plugin) getUsage(l *i18n.Localizer) string {
+	trigger := p.configuration.Trigger
+	lines := []string{
+		fmt.Sprintf("#### %s", Localize(l, usageTitle, map[string]interface{}{"Trigger": trigger})),
+		Localize(l, usageCommandHeader, nil),
+	}
+	for _, sc := range getSubcommands() {
+		command := strings.TrimSpace(fmt.Sprintf("/%s %s %s", trigger, sc.Name, sc.Hint))
+		lines = append(lines, fmt.Sprintf("| `%s` | %s |", escapeTableCell(command), Localize(l, sc.Description, nil)))
+	}
+
+	for _, sc := range getSubcommands() {
+		if len(sc.Flags) == 0 {
+			continue
+		}
+		lines = append(lines,
+			"",
+			fmt.Sprintf("##### %s", Localize(l, usageOptionsTitle, map[string]interface{}{"Subcommand": sc.Name})),
+			Localize(l, usageOptionHeader, nil),
+		)
+		for _, f := range sc.Flags {
+			option := strings.TrimSpace(fmt.Sprintf("-%s %s", f.Name, f.Hint))
+			lines = append(lines, fmt.Sprintf("| `%s` | %s |", escapeTableCell(option), Localize(l, f.Description, nil)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeTableCell はMarkdownの表のセルで区切りとして扱われないように|をエスケープする
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// usageError は使い方と一緒に翻訳して表示する引数の誤り
+type usageError struct {
+	message *i18n.Message
+	data    map[string]interface{}
+}
+
+func newUsageError(message *i18n.Message, data map[string]interface{}) error {
+	return &usageError{message: message, data: data}
+}
+
+// Error は英語のメッセージを返す
+func (e *usageError) Error() string {
+	return Localize(i18n.NewLocalizer(i18n.NewBundle(language.English)), e.message, e.data)
+}
+
+// localize は指定した言語のメッセージを返す
+func (e *usageError) localize(l *i18n.Localizer) string {
+	return Localize(l, e.message, e.data)
+}
+
+/*
+newFlagError はflagパッケージのエラーを翻訳できる引数の誤りに変換する．
+-hが指定された場合のflag.ErrHelpはそのまま返す
+*/
+func newFlagError(err error) error {
+	message := err.Error()
+	switch {
+	case err == flag.ErrHelp:
+		return err
+	case strings.HasPrefix(message, "flag provided but not defined: -"):
+		return newUsageError(usageUnknownOptionErrorMessage, map[string]interface{}{
+			"Option": strings.TrimPrefix(message, "flag provided but not defined: -"),
+		})
+	case strings.HasPrefix(message, "flag needs an argument: -"):
+		return newUsageError(usageMissingValueErrorMessage, map[string]interface{}{
+			"Option": strings.TrimPrefix(message, "flag needs an argument: -"),
+		})
+	}
+	if m := invalidFlagValuePattern.FindStringSubmatch(message); m != nil {
+		return newUsageError(usageInvalidValueErrorMessage, map[string]interface{}{
+			"Value":  m[1],
+			"Option": m[2],
+		})
+	}
+	return err
+}
+
+// invalidFlagValuePattern はflagパッケージの不正な値のエラーから値とオプションの名前を取り出す
+var invalidFlagValuePattern = regexp.MustCompile(`^invalid (?:boolean )?value "(.*)" for (?:flag )?-([^:]+):`)
+
+/*
+newUsageErrorResponse は引数の誤りと使い方を返す．
+エラーのメッセージが空の場合(-hが指定された場合)は使い方だけを返す
+*/
+func (p *Plugin) newUsageErrorResponse(siteURL string, err error) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+	message := p.getUsage(l)
+	detail := err.Error()
+	var ue *usageError
+	if errors.As(err, &ue) {
+		detail = ue.localize(l)
+	}
+	if detail != "" {
+		message = fmt.Sprintf("%s\n\n%s", message, Localize(l, usageParseErrorMessage, map[string]interface{}{
+			"Error": detail,
+		}))
+	}
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+/*
+getAutocompleteData はサブコマンドとオプションのオートコンプリートを返す．
+値をとらないオプションはオートコンプリートでは"--name value"の形で入力できないので含めない
+*/
+func (p *Plugin) getAutocompleteData(l *i18n.Localizer, trigger string) *model.AutocompleteData {
+	root := model.NewAutocompleteData(strings.ToLower(trigger), "[command]", Localize(l, commandAutocompleteDescription, nil))
+	for _, sc := range getSubcommands() {
+		data := model.NewAutocompleteData(sc.Name, sc.Hint, Localize(l, sc.Description, nil))
+		if sc.GameIDArgument {
+			data.AddDynamicListArgument(Localize(l, commandGameIDArgumentHelp, nil), autocompleteGamesURL, true)
+		}
+		if sc.Values != nil {
+			items := []model.AutocompleteListItem{}
+			for _, v := range sc.Values(p) {
+				items = append(items, model.AutocompleteListItem{Item: v})
+			}
+			data.AddStaticListArgument(Localize(l, sc.Description, nil), false, items)
+		}
+		for _, f := range sc.Flags {
+			switch {
+			case f.Hint == "":
+				continue
+			case f.Values != nil:
+				items := []model.AutocompleteListItem{}
+				for _, v := range f.Values(p) {
+					items = append(items, model.AutocompleteListItem{Item: v})
+				}
+				data.AddNamedStaticListArgument(f.Name, Localize(l, f.Description, nil), false, items)
+			default:
+				data.AddNamedTextArgument(f.Name, Localize(l, f.Description, nil), f.Hint, "", false)
+			}
+		}
+		root.AddCommand(data)
+	}
+	return root
+}
+
+/*
+getCommandTargetGame はサブコマンドの引数のIDのチャンネルの受付中のゲームを返す．
+IDはゲームの一覧に表示する短いIDでも完全なIDでもよい．IDに一致するゲームが複数ある場合はゲームを返さない
+Returns:
+    *game: ゲーム
+    *model.CommandResponse: 引数が不正な場合やゲームが見つからない場合に返す応答
+*/
+func (p *Plugin) getCommandTargetGame(siteURL string, args *model.CommandArgs) (*game, *model.CommandResponse) {
+	fields, err := shellquote.Split(args.Command)
+	if err != nil {
+		return nil, p.newUsageErrorResponse(siteURL, err)
+	}
+	if len(fields) < 3 {
+		return nil, p.newUsageErrorResponse(siteURL, newUsageError(commandGameIDRequiredErrorMessage, nil))
+	}
+	if len(fields) > 3 {
+		return nil, p.newUsageErrorResponse(siteURL, newUsageError(usageUnexpectedArgumentsErrorMessage, map[string]interface{}{
+			"Arguments": strings.Join(fields[3:], " "),
+		}))
+	}
+	id := fields[2]
+
+	games, err := p.store.jankenStore.ListByChannel(args.ChannelId)
+	if err != nil {
+		errmsg := fmt.Sprintf("Failed to get games.: %s", err.Error())
+		return nil, newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+	found := []*game{}
+	for _, g := range games {
+		if len(id) >= len(g.getShortID()) && strings.HasPrefix(g.ID, id) {
+			found = append(found, g)
+		}
+	}
+	if len(found) != 1 {
+		l := p.getLocalizer(p.configuration.DefaultLanguage)
+		message := commandGameNotFoundErrorMessage
+		if len(found) > 1 {
+			message = commandGameAmbiguousErrorMessage
+		}
+		text := Localize(l, message, map[string]interface{}{
+			"ID":      id,
+			"Trigger": p.configuration.Trigger,
+		})
+		return nil, newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, text, nil)
+	}
+	return found[0], nil
+}
+
+// executeResultCommand は受付中のゲームの結果を表示する
+func (p *Plugin) executeResultCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	game, response := p.getCommandTargetGame(siteURL, args)
+	if response != nil {
+		return response
+	}
+
+	l := p.getLocalizer(game.Language)
+	if message := p.validateResult(l, game, args.UserId); message != "" {
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	post, appErr := p.API.GetPost(game.PostID)
+	if appErr != nil {
+		errmsg := fmt.Sprintf("Failed to get the post.: %s", appErr.Error())
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, errmsg, nil)
+	}
+
+	// 他の操作が先に結果を表示した場合は見つからないゲームとして扱う
+	id := game.getShortID()
+	if game = p.claimGame(game.ID, checkEnoughParticipants); game == nil {
+		l := p.getLocalizer(p.configuration.DefaultLanguage)
+		message := Localize(l, commandGameNotFoundErrorMessage, map[string]interface{}{
+			"ID":      id,
+			"Trigger": p.configuration.Trigger,
+		})
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+	p.finishGame(game, post)
+	p.API.UpdatePost(post)
+	return &model.CommandResponse{}
+}
+
+/*
+executeCancelCommand は受付中のゲームを削除する．
+postが削除されている場合はゲームだけを削除する
+*/
+func (p *Plugin) executeCancelCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	game, response := p.getCommandTargetGame(siteURL, args)
+	if response != nil {
+		return response
+	}
+
+	l := p.getLocalizer(game.Language)
+	if permission, _ := p.HasPermission(game, args.UserId); !permission {
+		message := Localize(l, commandCancelPermissionErrorMessage, nil)
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	post, appErr := p.API.GetPost(game.PostID)
+	if appErr != nil {
+		if p.claimGame(game.ID, nil) != nil {
+			message := Localize(l, jankenGameDestroyedMessage, map[string]interface{}{
+				"Username": p.getUsername(args.UserId),
+			})
+			return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+		}
+	} else if p.destroyGame(game.ID, post, args.UserId) {
+		return &model.CommandResponse{}
+	}
+
+	// 他の操作が先に結果を表示したり削除したりした場合は見つからないゲームとして扱う
+	message := Localize(l, commandGameNotFoundErrorMessage, map[string]interface{}{
+		"ID":      game.getShortID(),
+		"Trigger": p.configuration.Trigger,
+	})
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+}
+
+// executeConfigCommand は受付中のゲームの設定ダイアログを開く
+func (p *Plugin) executeConfigCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	game, response := p.getCommandTargetGame(siteURL, args)
+	if response != nil {
+		return response
+	}
+
+	if permission, _ := p.HasPermission(game, args.UserId); !permission {
+		l := p.getLocalizer(game.Language)
+		message := Localize(l, configPermissionErrorMessage, nil)
+		return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, message, nil)
+	}
+
+	d := newConfigDialog(p.API, siteURL, PluginID, p)
+	d.Open(args.TriggerId, game.PostID, args.UserId, game)
+	return &model.CommandResponse{}
+}
+
+// executeHelpCommand は使い方を表示する
+func (p *Plugin) executeHelpCommand(siteURL string, args *model.CommandArgs) *model.CommandResponse {
+	l := p.getLocalizer(p.configuration.DefaultLanguage)
+	return newCommandResponse(siteURL, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, p.getUsage(l), nil)
+}
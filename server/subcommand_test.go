@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestFindSubcommand(t *testing.T) {
+	for name, test := range map[string]struct {
+		Name     string
+		Expected bool
+	}{
+		"create":  {Name: "create", Expected: true},
+		"result":  {Name: "result", Expected: true},
+		"rating":  {Name: "rating", Expected: true},
+		"notify":  {Name: "notify", Expected: true},
+		"unknown": {Name: "unknown", Expected: false},
+		"empty":   {Name: "", Expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			sc := findSubcommand(test.Name)
+			if !test.Expected {
+				assert.Nil(sc)
+				return
+			}
+			assert.Equal(test.Name, sc.Name)
+			assert.NotNil(sc.Execute)
+		})
+	}
+}
+
+func TestNewFlagError(t *testing.T) {
+	for name, test := range map[string]struct {
+		Args            []string
+		ExpectedMessage string
+	}{
+		"unknown option":     {Args: []string{"-foo"}, ExpectedMessage: "Unknown option: -foo"},
+		"missing value":      {Args: []string{"-max"}, ExpectedMessage: "Option -max requires a value."},
+		"invalid number":     {Args: []string{"-max", "three"}, ExpectedMessage: `Invalid value "three" for option -max.`},
+		"invalid bool value": {Args: []string{"-auto=yes"}, ExpectedMessage: `Invalid value "yes" for option -auto.`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(&strings.Builder{})
+			fs.Int("max", 0, "")
+			fs.Bool("auto", false, "")
+			err := newFlagError(fs.Parse(test.Args))
+
+			assert.IsType(&usageError{}, err)
+			assert.Equal(test.ExpectedMessage, err.Error())
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	p := &Plugin{
+		configuration: &pluginConfig{Trigger: "janken", DefaultLanguage: "en"},
+		bundle:        i18n.NewBundle(language.English),
+	}
+	l := p.getLocalizer("en")
+
+	t.Run("getUsage", func(t *testing.T) {
+		assert := assert.New(t)
+		usage := p.getUsage(l)
+		for _, sc := range getSubcommands() {
+			assert.Contains(usage, "/janken "+sc.Name)
+		}
+		assert.Contains(usage, "`-hands rps\\|rpsls`")
+		// 表の区切り以外の|はエスケープされる
+		for _, line := range strings.Split(usage, "\n") {
+			if strings.HasPrefix(line, "|") {
+				assert.Equal(3, strings.Count(line, "|")-strings.Count(line, `\|`), line)
+			}
+		}
+	})
+
+	t.Run("getAutocompleteData", func(t *testing.T) {
+		assert := assert.New(t)
+		data := p.getAutocompleteData(l, "Janken")
+		assert.Nil(data.IsValid())
+		assert.Equal("janken", data.Trigger)
+		assert.Len(data.SubCommands, len(getSubcommands()))
+		for _, sub := range data.SubCommands {
+			switch sub.Trigger {
+			case subcommandResult:
+				assert.Len(sub.Arguments, 1)
+				fetchURL := sub.Arguments[0].Data.(*model.AutocompleteDynamicListArg).FetchURL
+				assert.Equal("plugins/"+PluginID+"/api/v1/janken/autocomplete/games", fetchURL)
+				// プラグインのルーターはURLからプラグインのパスを除いたパスを受け取る
+				path := strings.TrimPrefix(fetchURL, "plugins/"+PluginID)
+				assert.True(p.initAPI().Match(httptest.NewRequest(http.MethodGet, path, nil), &mux.RouteMatch{}), path)
+			case subcommandNotify:
+				assert.Len(sub.Arguments, 1)
+				items := sub.Arguments[0].Data.(*model.AutocompleteStaticListArg).PossibleArguments
+				assert.Equal([]model.AutocompleteListItem{{Item: notifyOn}, {Item: notifyOff}}, items)
+			}
+		}
+	})
+}
+
+func TestGetCommandTargetGame(t *testing.T) {
+	api, _ := newAtomicKVAPI()
+	p := &Plugin{
+		configuration: &pluginConfig{Trigger: "janken", DefaultLanguage: "en"},
+		bundle:        i18n.NewBundle(language.English),
+	}
+	p.SetAPI(api)
+	p.store = NewStore(api)
+	// 短いIDが同じゲーム
+	ids := []string{"abcdefg1xxxxxxxxxxxxxxxxxx", "abcdefg2xxxxxxxxxxxxxxxxxx"}
+	for _, id := range ids {
+		g := newGame(&gameImpl1{})
+		g.ID = id
+		g.ChannelID = "channel"
+		assert.Nil(t, p.store.jankenStore.Save(g))
+	}
+
+	for name, test := range map[string]struct {
+		Command         string
+		ExpectedID      string
+		ExpectedMessage string
+	}{
+		"full ID":        {Command: "/janken result " + ids[1], ExpectedID: ids[1]},
+		"unique prefix":  {Command: "/janken result abcdefg1", ExpectedID: ids[0]},
+		"ambiguous ID":   {Command: "/janken result abcdefg", ExpectedMessage: "More than one janken game in this channel starts with abcdefg."},
+		"unknown ID":     {Command: "/janken result zzzzzzz", ExpectedMessage: "Janken game zzzzzzz is not found in this channel."},
+		"too short ID":   {Command: "/janken result abc", ExpectedMessage: "Janken game abc is not found in this channel."},
+		"no ID":          {Command: "/janken result", ExpectedMessage: "Specify the ID of a game."},
+		"extra argument": {Command: "/janken result abcdefg1 now", ExpectedMessage: "Unexpected arguments: now"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g, response := p.getCommandTargetGame(dummySiteURL, &model.CommandArgs{Command: test.Command, ChannelId: "channel"})
+
+			if test.ExpectedMessage != "" {
+				assert.Nil(g)
+				assert.Contains(response.Text, test.ExpectedMessage)
+				return
+			}
+			assert.Nil(response)
+			assert.Equal(test.ExpectedID, g.ID)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 )
@@ -15,8 +16,46 @@ func (p *Plugin) sendEphemeralPost(channelID, userID, message string) *model.Pos
 	return p.API.SendEphemeralPost(userID, post)
 }
 
+// replyToPost はpostのスレッドに返信する
+func (p *Plugin) replyToPost(post *model.Post, message string) {
+	reply := &model.Post{
+		UserId:    post.UserId,
+		ChannelId: post.ChannelId,
+		RootId:    post.Id,
+		Message:   message,
+	}
+	if _, appErr := p.API.CreatePost(reply); appErr != nil {
+		p.API.LogError("failed to reply to the post", "post_id", post.Id, "error", appErr.Error())
+	}
+}
+
 func appendMessage(post *model.Post, format string, args ...interface{}) *model.Post {
 	message := fmt.Sprintf(format, args...)
 	post.Message = fmt.Sprintf("%s\n%s", post.Message, message)
 	return post
 }
+
+/*
+submissionToInt はダイアログの入力値を数値に変換する．
+数値の入力欄の値は文字列または数値で送られてくる
+*/
+func submissionToInt(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.Atoi(v)
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("invalid number: %v", v)
+	}
+}
+
+// containsString はスライスに文字列が含まれているかを返す
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
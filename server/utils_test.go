@@ -104,3 +104,45 @@ func TestAppendMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestSubmissionToInt(t *testing.T) {
+	for name, test := range map[string]struct {
+		Value         interface{}
+		ExpectedValue int
+		ShouldError   bool
+	}{
+		"string":         {Value: "3", ExpectedValue: 3, ShouldError: false},
+		"float64":        {Value: float64(3), ExpectedValue: 3, ShouldError: false},
+		"invalid string": {Value: "three", ExpectedValue: 0, ShouldError: true},
+		"nil":            {Value: nil, ExpectedValue: 0, ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			v, err := submissionToInt(test.Value)
+
+			assert.Equal(test.ExpectedValue, v)
+			if test.ShouldError {
+				assert.NotNil(err)
+			} else {
+				assert.Nil(err)
+			}
+		})
+	}
+}
+
+func TestContainsString(t *testing.T) {
+	for name, test := range map[string]struct {
+		Slice    []string
+		Value    string
+		Expected bool
+	}{
+		"contained":     {Slice: []string{"a", "b"}, Value: "b", Expected: true},
+		"not contained": {Slice: []string{"a", "b"}, Value: "c", Expected: false},
+		"nil":           {Slice: nil, Value: "a", Expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, containsString(test.Slice, test.Value))
+		})
+	}
+}